@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/prompts"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runAccount implements `account recover`, the break-glass path for an
+// administrator who has lost their password or MFA device.
+func runAccount(args []string) error {
+	if len(args) == 0 || args[0] != "recover" {
+		return fmt.Errorf("usage: account recover --email EMAIL [--reset-password] [--disable-mfa | --recovery-codes] [--activate] [--revoke-sessions]")
+	}
+
+	fs := flag.NewFlagSet("account recover", flag.ExitOnError)
+	t := addTargetFlags(fs)
+	s := steps.RecoverAccount{}
+	resetPassword := fs.Bool("reset-password", false, "set a new password (prompted for unless --password is given)")
+	fs.StringVar(&s.Email, "email", "", "login email of the account to recover")
+	fs.StringVar(&s.Password, "password", "", "new password (implies --reset-password)")
+	fs.BoolVar(&s.DisableMFA, "disable-mfa", false, "turn off MFA and remove the TOTP secret and recovery codes")
+	fs.BoolVar(&s.NewRecoveryCodes, "recovery-codes", false, "replace the MFA recovery codes and print the new ones")
+	fs.BoolVar(&s.Activate, "activate", false, "set the account status back to active")
+	fs.BoolVar(&s.RevokeSessions, "revoke-sessions", false, "delete the account's API tokens and sessions")
+	fs.StringVar(&s.AuditLog, "audit-log", steps.DefaultAuditLog(), "JSON-lines file the actions are recorded in")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if s.Email == "" {
+		return fmt.Errorf("--email is required")
+	}
+	if *resetPassword && s.Password == "" {
+		p, err := prompts.AskPassword("New password for " + s.Email)
+		if err != nil {
+			return err
+		}
+		s.Password = p
+	}
+
+	ctx, err := t.context()
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		var actions []string
+		if s.Password != "" {
+			actions = append(actions, "reset the password")
+		}
+		if s.DisableMFA {
+			actions = append(actions, "disable MFA")
+		}
+		if s.NewRecoveryCodes {
+			actions = append(actions, "reissue recovery codes")
+		}
+		if s.Activate {
+			actions = append(actions, "activate the account")
+		}
+		if s.RevokeSessions {
+			actions = append(actions, "sign out all sessions and tokens")
+		}
+		if len(actions) > 0 {
+			ok, err := prompts.Confirm(fmt.Sprintf("In database %s, %s for %s?", ctx.DatabaseName, strings.Join(actions, ", "), s.Email), false)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("cancelled")
+			}
+		}
+	}
+	return installer.NewRunner([]installer.Step{s}).Run(ctx)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/sanitize"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runDump implements `dump sanitize`, which exports an anonymized copy of a
+// CRM database, and `dump rules`, which writes the default rules file for
+// editing.
+func runDump(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: dump sanitize --out FILE [--rules FILE] [--salt TEXT] | dump rules [--out FILE]")
+	}
+	action, args := args[0], args[1:]
+
+	switch action {
+	case "rules":
+		fs := flag.NewFlagSet("dump rules", flag.ExitOnError)
+		out := fs.String("out", "", "file to write (default: standard output)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *out == "" {
+			_, err := os.Stdout.WriteString(sanitize.DefaultRules)
+			return err
+		}
+		return os.WriteFile(*out, []byte(sanitize.DefaultRules), 0o644)
+
+	case "sanitize":
+		fs := flag.NewFlagSet("dump sanitize", flag.ExitOnError)
+		t := addTargetFlags(fs)
+		s := steps.SanitizeDump{}
+		database := fs.String("database", "", "source database (default: DB_DATABASE from backend\\.env)")
+		fs.StringVar(&s.Output, "out", "", "file to write the sanitized dump to")
+		fs.StringVar(&s.RulesPath, "rules", "", "rules file (default: built-in rules, see `dump rules`)")
+		fs.StringVar(&s.Salt, "salt", "", "secret that makes fake values repeatable across exports (default: random per export)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if s.Output == "" {
+			return fmt.Errorf("--out is required")
+		}
+		ctx, err := t.context()
+		if err != nil {
+			return err
+		}
+		if *database != "" {
+			ctx.DatabaseName = *database
+		}
+		return installer.NewRunner([]installer.Step{s}).Run(ctx)
+	}
+	return fmt.Errorf("unknown dump action %q (expected sanitize or rules)", action)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/instances"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runInstances manages named installs: list, create, upgrade and remove.
+func runInstances(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: instances list|create|upgrade|remove [name]")
+	}
+	action, args := args[0], args[1:]
+
+	if action == "list" {
+		return listInstances()
+	}
+
+	fs := flag.NewFlagSet("instances "+action, flag.ExitOnError)
+	source := fs.String("source", "", "CRM_Source directory to upgrade from (default: next to the installer)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: instances %s <name>", action)
+	}
+	name := fs.Arg(0)
+	if err := instances.ValidateName(name); err != nil {
+		return err
+	}
+
+	if action == "create" {
+		registry, err := instances.Load()
+		if err != nil {
+			return err
+		}
+		if _, err := registry.Get(name); err == nil {
+			return fmt.Errorf("instance %s already exists; use instances upgrade", name)
+		}
+		runner := installer.NewRunner(steps.All())
+		if err := steps.AddHooks(runner, nil); err != nil {
+			return err
+		}
+		runInstall(&installer.Context{InstanceName: name}, runner, false)
+		return nil
+	}
+
+	registry, err := instances.Load()
+	if err != nil {
+		return err
+	}
+	inst, err := registry.Get(name)
+	if err != nil {
+		return err
+	}
+	ctx := &installer.Context{}
+	steps.ApplyInstance(ctx, inst)
+
+	switch action {
+	case "upgrade":
+		ctx.CRMSourceDir = *source
+		if ctx.CRMSourceDir == "" {
+			exePath, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("determine executable path: %w", err)
+			}
+			ctx.CRMSourceDir = filepath.Join(filepath.Dir(exePath), "CRM_Source")
+		}
+		return installer.NewRunner(steps.UpgradeInstance()).Run(ctx)
+	case "remove":
+		return installer.NewRunner(steps.RemoveInstance()).Run(ctx)
+	default:
+		return fmt.Errorf("unknown instances action %q (expected list, create, upgrade or remove)", action)
+	}
+}
+
+func listInstances() error {
+	registry, err := instances.Load()
+	if err != nil {
+		return err
+	}
+	list := registry.List()
+	if len(list) == 0 {
+		fmt.Println("No instances registered.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSITE\tBINDING\tDATABASE\tRUNTIME\tUPDATED")
+	for _, inst := range list {
+		host := inst.HostName
+		if host == "" {
+			host = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s:%d\t%s\t%s\t%s\n", inst.Name, inst.SiteName, host, inst.Port, inst.DatabaseName, inst.RuntimeDir, inst.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
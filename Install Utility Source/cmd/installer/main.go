@@ -1,17 +1,236 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
 
+	"yachtcrm-installer/internal/detectors"
 	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/instances"
+	"yachtcrm-installer/internal/scheduler"
 	"yachtcrm-installer/internal/steps"
+	"yachtcrm-installer/internal/tui"
+	"yachtcrm-installer/internal/worker"
 )
 
 func main() {
+	command, args := "install", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "install":
+		if err := runInstallCommand(args); err != nil {
+			log.Fatalf("Installation failed: %v", err)
+		}
+	case "account":
+		if err := runAccount(args); err != nil {
+			log.Fatalf("Account command failed: %v", err)
+		}
+	case "create-user":
+		if err := runCreateUser(args); err != nil {
+			log.Fatalf("Create user failed: %v", err)
+		}
+	case "dump":
+		if err := runDump(args); err != nil {
+			log.Fatalf("Dump command failed: %v", err)
+		}
+	case "health":
+		if err := runHealth(args); err != nil {
+			log.Fatalf("Health check failed: %v", err)
+		}
+	case "modules":
+		if err := runModules(args); err != nil {
+			log.Fatalf("Modules command failed: %v", err)
+		}
+	case "reconfigure-host":
+		if err := runReconfigureHost(args); err != nil {
+			log.Fatalf("Reconfigure failed: %v", err)
+		}
+	case "instances":
+		if err := runInstances(args); err != nil {
+			log.Fatalf("Instances command failed: %v", err)
+		}
+	case "migrations":
+		if err := runMigrations(args); err != nil {
+			log.Fatalf("Migrations command failed: %v", err)
+		}
+	case "steps":
+		if err := runSteps(args); err != nil {
+			log.Fatalf("Steps command failed: %v", err)
+		}
+	case "serve":
+		if err := runServe(args); err != nil {
+			log.Fatalf("Serve failed: %v", err)
+		}
+	case "seed":
+		if err := runSeed(args); err != nil {
+			log.Fatalf("Seed command failed: %v", err)
+		}
+	case "verify":
+		if err := runVerify(args); err != nil {
+			log.Fatalf("Verify failed: %v", err)
+		}
+	case "worker":
+		if err := runWorker(args); err != nil {
+			log.Fatalf("Worker failed: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown command %q (expected install, account, create-user, dump, health, instances, migrations, modules, reconfigure-host, seed, serve, steps, verify or worker)", command)
+	}
+}
+
+func runInstallCommand(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	answers := fs.String("answers", "", "JSON answer file with settings to use instead of prompting")
+	fullScreen := fs.Bool("tui", false, "show progress in a full-screen terminal UI (plain output when stdout is not a terminal)")
+	parallel := fs.Int("parallel", installer.DefaultParallel, "maximum number of independent steps to run at once")
+	only := fs.String("only", "", "comma-separated step IDs to run (see installer steps)")
+	skip := fs.String("skip", "", "comma-separated step IDs to leave out")
+	from := fs.String("from", "", "first step ID to run")
+	to := fs.String("to", "", "last step ID to run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
 	ctx := &installer.Context{}
+	if *answers != "" {
+		a, err := installer.LoadAnswers(*answers)
+		if err != nil {
+			return err
+		}
+		ctx.Answers = a
+	}
 	runner := installer.NewRunner(steps.All())
+	runner.Parallel = *parallel
+	if err := steps.AddHooks(runner, ctx.Answers); err != nil {
+		return err
+	}
+	sel := installer.Selection{Only: splitIDs(*only), Skip: splitIDs(*skip), From: *from, To: *to}
+	if err := runner.Select(sel); err != nil {
+		return err
+	}
+	runInstall(ctx, runner, *fullScreen)
+	return nil
+}
 
-	if err := runner.Run(ctx); err != nil {
+func splitIDs(list string) []string {
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func runInstall(ctx *installer.Context, runner *installer.Runner, fullScreen bool) {
+	var err error
+	if fullScreen {
+		err = tui.Run(ctx, runner)
+	} else {
+		err = runner.Run(ctx)
+	}
+	if err != nil {
 		log.Fatalf("Installation failed: %v", err)
 	}
 }
+
+func runHealth(args []string) error {
+	fs := flag.NewFlagSet("health", flag.ExitOnError)
+	task := fs.String("scheduler-task", scheduler.DefaultTaskName, "name of the Laravel scheduler task")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range detectors.HealthChecks(*task) {
+		fmt.Printf("[%-7s] %-28s %s\n", result.Status, result.Name, result.Details)
+		if result.Status != detectors.StatusOK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) not OK", failed)
+	}
+	return nil
+}
+
+// runReconfigureHost moves an installed site to a new public URL.
+func runReconfigureHost(args []string) error {
+	fs := flag.NewFlagSet("reconfigure-host", flag.ExitOnError)
+	ctx := &installer.Context{}
+	instanceName := fs.String("instance", "", "registered instance to reconfigure (overrides --runtime, --site, --php and --node)")
+	fs.StringVar(&ctx.AppURL, "url", "", "new public URL, e.g. https://crm.example.com")
+	fs.StringVar(&ctx.RuntimeDir, "runtime", detectors.DefaultInstallPath(), "YachtCRM-DMS runtime directory")
+	fs.StringVar(&ctx.SiteName, "site", steps.DefaultSiteName, "IIS site name")
+	fs.StringVar(&ctx.PhpExePath, "php", `C:\PHP\php.exe`, "path to php.exe")
+	fs.StringVar(&ctx.NodeBinDir, "node", `C:\nodejs`, "Node.js installation directory")
+	fs.StringVar(&ctx.TLSCertThumbprint, "cert-thumbprint", "", "certificate for the HTTPS binding (default: newest matching certificate in LocalMachine\\My)")
+	fs.BoolVar(&ctx.AddHostsEntry, "hosts-entry", false, "map the host name to 127.0.0.1 in the hosts file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if ctx.AppURL == "" {
+		return fmt.Errorf("--url is required")
+	}
+	if *instanceName != "" {
+		registry, err := instances.Load()
+		if err != nil {
+			return err
+		}
+		inst, err := registry.Get(*instanceName)
+		if err != nil {
+			return err
+		}
+		appURL := ctx.AppURL
+		steps.ApplyInstance(ctx, inst)
+		ctx.AppURL = appURL
+	}
+
+	if err := installer.NewRunner(steps.ReconfigureHost()).Run(ctx); err != nil {
+		return err
+	}
+	if *instanceName != "" {
+		return installer.NewRunner([]installer.Step{steps.RegisterInstance{}}).Run(ctx)
+	}
+	return nil
+}
+
+// runWorker supervises `artisan queue:work`. `worker restart` instead asks
+// running workers to exit gracefully so they pick up newly deployed code.
+func runWorker(args []string) error {
+	restart := len(args) > 0 && args[0] == "restart"
+	if restart {
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	cfg := worker.Config{}
+	fs.StringVar(&cfg.Name, "name", worker.DefaultTaskName, "worker service name")
+	fs.StringVar(&cfg.PhpExePath, "php", `C:\PHP\php.exe`, "path to php.exe")
+	fs.StringVar(&cfg.BackendDir, "backend", "", "path to the backend directory containing artisan")
+	fs.StringVar(&cfg.LogPath, "log", "", "worker log file (default storage/logs/queue-worker.log)")
+	fs.StringVar(&cfg.Queue, "queue", "default", "queues to process")
+	fs.IntVar(&cfg.MaxJobs, "max-jobs", 500, "recycle the worker after this many jobs")
+	fs.IntVar(&cfg.MaxMemoryMB, "max-memory", 256, "recycle the worker once it uses this many MB")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.BackendDir == "" {
+		return fmt.Errorf("--backend is required")
+	}
+
+	if restart {
+		return worker.GracefulRestart(cfg.PhpExePath, cfg.BackendDir)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return worker.Supervise(ctx, cfg)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runMigrations reports migration status or, with `migrations migrate`,
+// applies pending migrations after a backup.
+func runMigrations(args []string) error {
+	action := "status"
+	if len(args) > 0 && (args[0] == "status" || args[0] == "migrate") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("migrations "+action, flag.ExitOnError)
+	t := addTargetFlags(fs)
+	backupDir := fs.String("backup-dir", "", "directory for the pre-migration backup (default %ProgramData%\\YachtCRM-DMS\\backups)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx, err := t.context()
+	if err != nil {
+		return err
+	}
+
+	if action == "migrate" {
+		return installer.NewRunner([]installer.Step{steps.RunMigrations{BackupDir: *backupDir}}).Run(ctx)
+	}
+
+	status, err := steps.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Database %s on %s:%d\n\n", ctx.DatabaseName, ctx.DatabaseHost, ctx.DatabasePort)
+	fmt.Printf("Applied (%d):\n", len(status.Applied))
+	for _, a := range status.Applied {
+		fmt.Printf("  [batch %d] %s\n", a.Batch, a.Name)
+	}
+	fmt.Printf("\nPending (%d):\n", len(status.Pending))
+	for _, name := range status.Pending {
+		fmt.Printf("  %s\n", name)
+	}
+	fmt.Printf("\nUnknown to this code (%d):\n", len(status.Unknown))
+	for _, name := range status.Unknown {
+		fmt.Printf("  %s\n", name)
+	}
+	if len(status.Unknown) > 0 {
+		return fmt.Errorf("database has migrations the deployed code does not include")
+	}
+	return nil
+}
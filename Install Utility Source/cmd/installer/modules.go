@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/modules"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runModules lists the product modules of an installed site or enables or
+// disables them.
+func runModules(args []string) error {
+	action := "list"
+	if len(args) > 0 && (args[0] == "list" || args[0] == "enable" || args[0] == "disable") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("modules "+action, flag.ExitOnError)
+	t := addTargetFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx, err := t.context()
+	if err != nil {
+		return err
+	}
+
+	if action == "list" {
+		list, err := steps.ListModules(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tNAME\tENABLED")
+		for _, m := range list {
+			fmt.Fprintf(w, "%s\t%s\t%v\n", m.Key, m.Name, m.Enabled)
+		}
+		return w.Flush()
+	}
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: modules %s MODULE... (modules: %s)", action, strings.Join(modules.Keys(), ", "))
+	}
+	keys, err := modules.Parse(strings.Join(fs.Args(), ","))
+	if err != nil {
+		return err
+	}
+	return installer.NewRunner([]installer.Step{steps.SetModules{Keys: keys, Enabled: action == "enable"}}).Run(ctx)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"yachtcrm-installer/internal/demo"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runSeed implements `seed demo`, which loads or removes the demo dataset.
+func runSeed(args []string) error {
+	if len(args) == 0 || args[0] != "demo" {
+		return fmt.Errorf("usage: seed demo [--seed N] [--size N] [--as-of YYYY-MM-DD] [--remove]")
+	}
+
+	fs := flag.NewFlagSet("seed demo", flag.ExitOnError)
+	t := addTargetFlags(fs)
+	opts := demo.Options{}
+	fs.Int64Var(&opts.Seed, "seed", 1, "random seed; the same seed, size and date give the same data")
+	fs.IntVar(&opts.Size, "size", 25, "number of customers; other records scale with it")
+	asOf := fs.String("as-of", time.Now().Format("2006-01-02"), "date the data is anchored to")
+	remove := fs.Bool("remove", false, "remove previously loaded demo data instead")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	ctx, err := t.context()
+	if err != nil {
+		return err
+	}
+
+	if *remove {
+		return installer.NewRunner([]installer.Step{steps.RemoveDemo{}}).Run(ctx)
+	}
+	if opts.AsOf, err = time.ParseInLocation("2006-01-02", *asOf, time.Local); err != nil {
+		return fmt.Errorf("invalid --as-of %q: %w", *asOf, err)
+	}
+	return installer.NewRunner([]installer.Step{steps.SeedDemo{Options: opts}}).Run(ctx)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/powershell"
+	"yachtcrm-installer/internal/steps"
+	"yachtcrm-installer/internal/wizard"
+)
+
+// runServe implements `serve`, the browser-based install wizard.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:0", "loopback address to listen on (port 0 picks a free port)")
+	open := fs.Bool("open", true, "open the wizard in the default browser")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	runner := installer.NewRunner(steps.All())
+	if err := steps.AddHooks(runner, nil); err != nil {
+		return err
+	}
+	srv, err := wizard.New(runner)
+	if err != nil {
+		return err
+	}
+	ln, err := wizard.Listen(*listen)
+	if err != nil {
+		return err
+	}
+	url := srv.URL(ln)
+	fmt.Printf("Installer wizard running. Open this link on this server (it works once):\n\n  %s\n\nPress Ctrl+C to stop the wizard.\n\n", url)
+	if *open {
+		if res := powershell.Run("Start-Process " + powershell.Quote(url)); res.Err != nil {
+			fmt.Printf("Warning: could not open a browser: %v\n", res.Err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return srv.Serve(ctx, ln)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runSteps lists the install steps, including hooks, with the IDs install
+// --only, --skip, --from and --to accept, and the steps each one waits for.
+func runSteps(args []string) error {
+	fs := flag.NewFlagSet("steps", flag.ExitOnError)
+	answers := fs.String("answers", "", "JSON answer file whose hooks to list as well")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var a *installer.Answers
+	if *answers != "" {
+		var err error
+		if a, err = installer.LoadAnswers(*answers); err != nil {
+			return err
+		}
+	}
+	runner := installer.NewRunner(steps.All())
+	if err := steps.AddHooks(runner, a); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDESCRIPTION\tAFTER")
+	for i, step := range runner.Steps() {
+		description := step.Name()
+		if d, ok := step.(installer.Described); ok {
+			description = d.Description()
+		}
+		var after []string
+		if h, ok := step.(steps.HookStep); ok {
+			after = append(after, h.Hook.Placement())
+		} else if d, ok := step.(installer.Dependent); ok {
+			for _, dep := range d.DependsOn() {
+				after = append(after, installer.StepID(dep))
+			}
+		} else if i > 0 {
+			after = append(after, "all above")
+		}
+		if m, ok := step.(installer.Mandatory); ok && m.Mandatory() {
+			description += " (always runs)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", installer.StepID(step), description, strings.Join(after, ", "))
+	}
+	return w.Flush()
+}
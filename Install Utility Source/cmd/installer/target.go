@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+
+	"yachtcrm-installer/internal/detectors"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/instances"
+	"yachtcrm-installer/internal/steps"
+)
+
+// target selects the installed site a maintenance command works on, either
+// by instance name or by runtime directory and php.exe.
+type target struct {
+	instance   string
+	runtimeDir string
+	phpExePath string
+
+	// record is the registered instance settings loaded, if any.
+	record *instances.Instance
+}
+
+func addTargetFlags(fs *flag.FlagSet) *target {
+	t := &target{}
+	fs.StringVar(&t.instance, "instance", "", "registered instance to use (overrides --runtime and --php)")
+	fs.StringVar(&t.runtimeDir, "runtime", detectors.DefaultInstallPath(), "YachtCRM-DMS runtime directory")
+	fs.StringVar(&t.phpExePath, "php", `C:\PHP\php.exe`, "path to php.exe")
+	return t
+}
+
+// context builds an installer context for the target with the database
+// settings from its backend/.env.
+func (t *target) context() (*installer.Context, error) {
+	ctx, err := t.settings()
+	if err != nil {
+		return nil, err
+	}
+	if err := steps.LoadEnvDatabase(ctx); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// settings builds an installer context for the target from the flags or
+// the instance registry alone.
+func (t *target) settings() (*installer.Context, error) {
+	ctx := &installer.Context{RuntimeDir: t.runtimeDir, PhpExePath: t.phpExePath}
+	if t.instance != "" {
+		registry, err := instances.Load()
+		if err != nil {
+			return nil, err
+		}
+		inst, err := registry.Get(t.instance)
+		if err != nil {
+			return nil, err
+		}
+		steps.ApplyInstance(ctx, inst)
+		t.record = &inst
+	}
+	ctx.PhpInstallDir = filepath.Dir(ctx.PhpExePath)
+	return ctx, nil
+}
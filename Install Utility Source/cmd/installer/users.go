@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"yachtcrm-installer/internal/accounts"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/prompts"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runCreateUser creates or updates one CRM account on an installed site.
+func runCreateUser(args []string) error {
+	fs := flag.NewFlagSet("create-user", flag.ExitOnError)
+	t := addTargetFlags(fs)
+	name := fs.String("name", "", "display name")
+	email := fs.String("email", "", "login email (an existing account with this email is updated)")
+	role := fs.String("role", accounts.RoleAdmin, "role: admin, office_staff, accountant, employee or customer")
+	password := fs.String("password", "", "password (prompted for when omitted)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("--email is required")
+	}
+	if *name == "" {
+		*name = *email
+	}
+	if err := accounts.ValidateRole(*role); err != nil {
+		return err
+	}
+	if *password == "" {
+		p, err := prompts.AskPassword("Password for " + *email)
+		if err != nil {
+			return err
+		}
+		*password = p
+	}
+
+	ctx, err := t.context()
+	if err != nil {
+		return err
+	}
+	return installer.NewRunner([]installer.Step{steps.CreateUser{FullName: *name, Email: *email, Password: *password, Role: *role}}).Run(ctx)
+}
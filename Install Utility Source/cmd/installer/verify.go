@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/phpconfig"
+	"yachtcrm-installer/internal/steps"
+)
+
+// runVerify compares what the installer set up with the machine and, with
+// --fix, repairs what it can.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	t := addTargetFlags(fs)
+	site := fs.String("site", steps.DefaultSiteName, "IIS site name (ignored with --instance)")
+	answers := fs.String("answers", "", "answer file the site was installed with, for its php.ini settings")
+	profile := fs.String("php-profile", phpconfig.DefaultProfile, "php.ini profile the site was installed with (when neither the instance nor --answers records one)")
+	timezone := fs.String("timezone", "", "date.timezone the site was installed with; empty is UTC (when neither the instance nor --answers records one)")
+	fix := fs.Bool("fix", false, "repair the drifted items that can be repaired automatically")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx, err := t.settings()
+	if err != nil {
+		return err
+	}
+	if t.instance == "" {
+		ctx.SiteName = *site
+	}
+
+	// php.ini is compared with the settings the instance was registered
+	// with, else those of the answer file, else the flags. --fix only
+	// rewrites it to settings that were recorded or given explicitly.
+	phpKnown := t.record != nil && t.record.PHP != nil
+	if !phpKnown && *answers != "" {
+		a, err := installer.LoadAnswers(*answers)
+		if err != nil {
+			return err
+		}
+		if a.PHP != nil {
+			if err := a.PHP.Validate(); err != nil {
+				return fmt.Errorf("answer file %s: %w", *answers, err)
+			}
+			ctx.PHP, phpKnown = *a.PHP, true
+		}
+	}
+	if !phpKnown {
+		ctx.PHP = phpconfig.Settings{Profile: *profile, Timezone: *timezone}
+		if err := ctx.PHP.Validate(); err != nil {
+			return err
+		}
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "php-profile" || f.Name == "timezone" {
+				phpKnown = true
+			}
+		})
+	}
+
+	items := steps.Verify(ctx)
+	if !phpKnown {
+		items = steps.WithoutFix(items, "php.ini")
+	}
+	var fixErr error
+	if *fix {
+		items, fixErr = steps.FixDrift(ctx, items)
+		if !phpKnown {
+			items = steps.WithoutFix(items, "php.ini")
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tAREA\tITEM\tEXPECTED\tACTUAL")
+	failed, fixable := 0, 0
+	for _, item := range items {
+		status := string(item.Status)
+		if item.Fixable() {
+			status += " (fixable)"
+			fixable++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status, item.Area, item.Item, blankDash(item.Expected), blankDash(item.Actual))
+		if item.Status == steps.VerifyDrift || item.Status == steps.VerifyError {
+			failed++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if fixErr != nil {
+		return fixErr
+	}
+	if fixable > 0 && !*fix {
+		fmt.Printf("\n%d item(s) can be repaired with --fix.\n", fixable)
+	}
+	if !phpKnown && slices.ContainsFunc(items, func(item steps.VerifyItem) bool { return item.Area == "php.ini" && item.Status == steps.VerifyDrift }) {
+		fmt.Println("\nphp.ini was compared with the default production profile and UTC; give --answers, --php-profile or --timezone to let --fix repair it.")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d item(s) not OK", failed)
+	}
+	return nil
+}
+
+func blankDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -1,3 +1,12 @@
 module yachtcrm-installer
 
 go 1.25.3
+
+require (
+	github.com/go-sql-driver/mysql v1.9.3
+	golang.org/x/crypto v0.45.0
+	golang.org/x/sys v0.38.0
+	golang.org/x/term v0.37.0
+)
+
+require filippo.io/edwards25519 v1.1.0 // indirect
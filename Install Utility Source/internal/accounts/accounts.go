@@ -0,0 +1,139 @@
+// Package accounts manages CRM login accounts in the users and
+// mfa_settings tables.
+package accounts
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"yachtcrm-installer/internal/database"
+)
+
+// Roles are the values of users.role.
+const (
+	RoleAdmin       = "admin"
+	RoleOfficeStaff = "office_staff"
+	RoleAccountant  = "accountant"
+	RoleEmployee    = "employee"
+	RoleCustomer    = "customer"
+)
+
+var Roles = []string{RoleAdmin, RoleOfficeStaff, RoleAccountant, RoleEmployee, RoleCustomer}
+
+// ErrNotFound is returned when no user has the requested email.
+var ErrNotFound = errors.New("user not found")
+
+// User is a row of users joined with its MFA settings.
+type User struct {
+	ID              int64
+	Name            string
+	Email           string
+	Role            string
+	Status          string
+	EmailVerifiedAt *time.Time
+	PasswordHash    string
+	MFAEnabled      bool
+	MFAMethod       string
+}
+
+// ValidateRole reports an error unless role is one of Roles.
+func ValidateRole(role string) error {
+	for _, r := range Roles {
+		if role == r {
+			return nil
+		}
+	}
+	return fmt.Errorf("role must be one of %s", strings.Join(Roles, ", "))
+}
+
+// HashPassword returns a bcrypt hash in the $2y$ form PHP's password_hash
+// produces. Go writes $2a$, which is the same algorithm but is not
+// recognised by password_get_info, so Laravel's algorithm check would
+// reject it.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return "$2y$" + strings.TrimPrefix(string(hash), "$2a$"), nil
+}
+
+// CheckPassword reports whether password matches a $2y$ or $2a$ hash.
+func CheckPassword(hash, password string) bool {
+	if strings.HasPrefix(hash, "$2y$") {
+		hash = "$2a$" + strings.TrimPrefix(hash, "$2y$")
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// hasColumn reports whether the connected database's table has column.
+// Older dumps predate users.status.
+func hasColumn(db *database.DB, table, column string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+		[]any{table, column}, &count)
+	return count > 0, err
+}
+
+// Upsert creates the user or updates the name, password and role of the
+// existing user with the same email. The account is marked active and its
+// email verified. MFA settings are left as they are.
+func Upsert(db *database.DB, name, email, passwordHash, role string) error {
+	if err := ValidateRole(role); err != nil {
+		return err
+	}
+	withStatus, err := hasColumn(db, "users", "status")
+	if err != nil {
+		return err
+	}
+
+	columns := "name,email,password,role,email_verified_at,remember_token,created_at,updated_at"
+	values := "?,?,?,?,NOW(),NULL,NOW(),NOW()"
+	update := "name=VALUES(name), password=VALUES(password), role=VALUES(role), email_verified_at=COALESCE(email_verified_at, NOW()), updated_at=NOW()"
+	if withStatus {
+		columns += ",status"
+		values += ",'active'"
+		update += ", status='active'"
+	}
+	_, err = db.Exec("INSERT INTO users ("+columns+") VALUES ("+values+") ON DUPLICATE KEY UPDATE "+update, name, email, passwordHash, role)
+	return err
+}
+
+// Find loads the user with email.
+func Find(db *database.DB, email string) (User, error) {
+	withStatus, err := hasColumn(db, "users", "status")
+	if err != nil {
+		return User{}, err
+	}
+	status := "'active'"
+	if withStatus {
+		status = "u.status"
+	}
+
+	var u User
+	var verified sql.NullString
+	var mfaEnabled sql.NullBool
+	var mfaMethod sql.NullString
+	err = db.QueryRow("SELECT u.id, u.name, u.email, u.role, "+status+", u.email_verified_at, u.password, m.mfa_enabled, m.mfa_method "+
+		"FROM users u LEFT JOIN mfa_settings m ON m.user_id = u.id WHERE u.email = ? ORDER BY m.id DESC LIMIT 1",
+		[]any{email}, &u.ID, &u.Name, &u.Email, &u.Role, &u.Status, &verified, &u.PasswordHash, &mfaEnabled, &mfaMethod)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, fmt.Errorf("%w: %s", ErrNotFound, email)
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if verified.Valid {
+		if t, err := time.Parse(time.DateTime, verified.String); err == nil {
+			u.EmailVerifiedAt = &t
+		}
+	}
+	u.MFAEnabled = mfaEnabled.Valid && mfaEnabled.Bool
+	u.MFAMethod = mfaMethod.String
+	return u, nil
+}
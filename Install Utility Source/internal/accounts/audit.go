@@ -0,0 +1,51 @@
+package accounts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AuditEntry records one change made to an account outside the CRM.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Operator string    `json:"operator"`
+	Machine  string    `json:"machine"`
+	Database string    `json:"database"`
+	UserID   int64     `json:"user_id,omitempty"`
+	Email    string    `json:"email"`
+	Action   string    `json:"action"`
+	Detail   string    `json:"detail,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// AppendAudit adds entry to the JSON-lines audit log at path, filling in
+// the time, operator and machine when they are empty.
+func AppendAudit(path string, entry AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.Operator == "" {
+		entry.Operator = os.Getenv("USERDOMAIN") + `\` + os.Getenv("USERNAME")
+	}
+	if entry.Machine == "" {
+		entry.Machine, _ = os.Hostname()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
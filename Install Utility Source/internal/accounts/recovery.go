@@ -0,0 +1,103 @@
+package accounts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"yachtcrm-installer/internal/database"
+)
+
+// ErrNoMFA is returned when recovery codes are requested for a user
+// without MFA enabled.
+var ErrNoMFA = errors.New("MFA is not enabled for this user")
+
+// userTokenType is the tokenable_type Sanctum stores for tokens issued to
+// App\Models\User.
+const userTokenType = `App\Models\User`
+
+// recoveryCodeCount matches MfaController::generateRecoveryCodes.
+const recoveryCodeCount = 10
+
+func hasTable(db *database.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+		[]any{table}, &count)
+	return count > 0, err
+}
+
+// SetPassword replaces the user's password hash and clears the remember-me
+// token so existing "remember me" cookies stop working.
+func SetPassword(db *database.DB, userID int64, passwordHash string) error {
+	_, err := db.Exec("UPDATE users SET password = ?, remember_token = NULL, updated_at = NOW() WHERE id = ?", passwordHash, userID)
+	return err
+}
+
+// DisableMFA turns off TOTP and email codes and discards the secret and
+// recovery codes, the same as disabling MFA from the user admin screen.
+func DisableMFA(db *database.DB, userID int64) error {
+	_, err := db.Exec("UPDATE mfa_settings SET mfa_enabled = 0, email_2fa_enabled = 0, totp_secret = NULL, mfa_method = NULL, recovery_codes = NULL, updated_at = NOW() WHERE user_id = ?", userID)
+	return err
+}
+
+// NewRecoveryCodes replaces the user's MFA recovery codes and returns the
+// new ones. The codes have the same shape as those the CRM generates.
+func NewRecoveryCodes(db *database.DB, userID int64) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		codes[i] = strings.ToUpper(hex.EncodeToString(b))
+	}
+	encoded, err := json.Marshal(codes)
+	if err != nil {
+		return nil, err
+	}
+	res, err := db.Exec("UPDATE mfa_settings SET recovery_codes = ?, updated_at = NOW() WHERE user_id = ? AND mfa_enabled = 1", string(encoded), userID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNoMFA
+	}
+	return codes, nil
+}
+
+// Activate sets the user's status to active. It reports false when the
+// schema predates users.status, in which case every account can sign in.
+func Activate(db *database.DB, userID int64) (bool, error) {
+	withStatus, err := hasColumn(db, "users", "status")
+	if err != nil || !withStatus {
+		return false, err
+	}
+	_, err = db.Exec("UPDATE users SET status = 'active', updated_at = NOW() WHERE id = ?", userID)
+	return err == nil, err
+}
+
+// RevokeTokens deletes the user's Sanctum API tokens and returns how many
+// were removed.
+func RevokeTokens(db *database.DB, userID int64) (int64, error) {
+	return deleteRows(db, "personal_access_tokens", "DELETE FROM personal_access_tokens WHERE tokenable_type = ? AND tokenable_id = ?", userTokenType, userID)
+}
+
+// RevokeSessions deletes the user's database sessions and returns how many
+// were removed. Sites using file or cookie sessions have no sessions table.
+func RevokeSessions(db *database.DB, userID int64) (int64, error) {
+	return deleteRows(db, "sessions", "DELETE FROM sessions WHERE user_id = ?", userID)
+}
+
+func deleteRows(db *database.DB, table, query string, args ...any) (int64, error) {
+	exists, err := hasTable(db, table)
+	if err != nil || !exists {
+		return 0, err
+	}
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,232 @@
+// Package branding writes the CRM name, company profile and logos the CRM
+// reads from its settings table and public storage.
+package branding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/database"
+)
+
+// DefaultName is the CRM name shipped in .env.example.
+const DefaultName = "YachtCRM-DMS"
+
+// MaxLogoSize matches the 2048 KB limit of the branding settings form.
+const MaxLogoSize = 2048 * 1024
+
+// LogoFields are the settings columns holding logo paths.
+var LogoFields = []string{"logo_login", "logo_header", "logo_invoice"}
+
+// Profile is the branding record. Empty fields leave the stored value
+// unchanged. Logos maps a LogoFields entry to an image file to install.
+type Profile struct {
+	CRMName      string            `json:"crm_name"`
+	BusinessName string            `json:"business_name"`
+	LegalName    string            `json:"business_legal_name"`
+	Phone        string            `json:"business_phone"`
+	Email        string            `json:"business_email"`
+	Website      string            `json:"business_website"`
+	TaxID        string            `json:"business_tax_id"`
+	AddressLine1 string            `json:"business_address_line1"`
+	AddressLine2 string            `json:"business_address_line2"`
+	City         string            `json:"business_city"`
+	State        string            `json:"business_state"`
+	PostalCode   string            `json:"business_postal_code"`
+	Country      string            `json:"business_country"`
+	Logos        map[string]string `json:"logos"`
+}
+
+// column pairs a settings column with its value and the length limit the
+// settings form enforces.
+type column struct {
+	name  string
+	value string
+	max   int
+}
+
+func (p Profile) columns() []column {
+	return []column{
+		{"crm_name", p.CRMName, 255},
+		{"business_name", p.BusinessName, 255},
+		{"business_legal_name", p.LegalName, 255},
+		{"business_phone", p.Phone, 50},
+		{"business_email", p.Email, 255},
+		{"business_website", p.Website, 255},
+		{"business_tax_id", p.TaxID, 100},
+		{"business_address_line1", p.AddressLine1, 255},
+		{"business_address_line2", p.AddressLine2, 255},
+		{"business_city", p.City, 120},
+		{"business_state", p.State, 120},
+		{"business_postal_code", p.PostalCode, 30},
+		{"business_country", p.Country, 120},
+	}
+}
+
+// ProfileFromValues builds a profile from values keyed by settings column,
+// with logo files under their LogoFields key. Blank values are skipped.
+func ProfileFromValues(values map[string]string) (Profile, error) {
+	fields := map[string]any{}
+	logos := map[string]string{}
+	for key, value := range values {
+		switch {
+		case value == "":
+		case slices.Contains(LogoFields, key):
+			logos[key] = value
+		default:
+			fields[key] = value
+		}
+	}
+	if len(logos) > 0 {
+		fields["logos"] = logos
+	}
+
+	var p Profile
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return p, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return p, fmt.Errorf("branding: %w", err)
+	}
+	return p, nil
+}
+
+// Validate applies the branding form's rules to the profile and its logos.
+func (p Profile) Validate() error {
+	for _, c := range p.columns() {
+		if len([]rune(c.value)) > c.max {
+			return fmt.Errorf("%s must be at most %d characters", c.name, c.max)
+		}
+	}
+	if p.Email != "" {
+		if _, err := mail.ParseAddress(p.Email); err != nil {
+			return fmt.Errorf("business_email %q is not a valid email address", p.Email)
+		}
+	}
+	for field, path := range p.Logos {
+		if !slices.Contains(LogoFields, field) {
+			return fmt.Errorf("unknown logo %q (expected %s)", field, strings.Join(LogoFields, ", "))
+		}
+		if err := ValidateLogo(path); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// logoTypes maps accepted extensions to the content type the file must
+// actually have.
+var logoTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+}
+
+// ValidateLogo checks that path is a PNG, JPEG, GIF or SVG image of at most
+// MaxLogoSize bytes whose content matches its extension.
+func ValidateLogo(path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	want, ok := logoTypes[ext]
+	if !ok {
+		return fmt.Errorf("%s: logos must be PNG, JPEG, GIF or SVG files", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > MaxLogoSize {
+		return fmt.Errorf("%s is %d KB; logos must be at most %d KB", path, info.Size()/1024, MaxLogoSize/1024)
+	}
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	head = head[:n]
+
+	got := http.DetectContentType(head)
+	if want == "image/svg+xml" {
+		if !bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+			return fmt.Errorf("%s does not look like an SVG image", path)
+		}
+		return nil
+	}
+	if got != want {
+		return fmt.Errorf("%s has extension %s but contains %s", path, ext, got)
+	}
+	return nil
+}
+
+// InstallLogo copies a logo into the backend's public storage the way the
+// settings form stores uploads and returns the path to record in settings.
+// The site serves public/storage as a copy of storage/app/public, so the
+// file is written to both.
+func InstallLogo(backendDir, field, src string) (string, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s_%d%s", field, time.Now().Unix(), strings.ToLower(filepath.Ext(src)))
+	rel := "logos/" + name
+	for _, dir := range []string{
+		filepath.Join(backendDir, "storage", "app", "public", "logos"),
+		filepath.Join(backendDir, "public", "storage", "logos"),
+	} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return "", err
+		}
+	}
+	return rel, nil
+}
+
+// Save writes the profile's non-empty fields and the given logo paths to
+// the branding row of settings, creating it if needed.
+func Save(db *database.DB, p Profile, logoPaths map[string]string) error {
+	if _, err := db.Exec("INSERT IGNORE INTO settings (`key`, value, type, description, created_at, updated_at) VALUES ('crm_name', 'enabled', 'branding', 'CRM Branding Settings', NOW(), NOW())"); err != nil {
+		return err
+	}
+	var sets []string
+	var args []any
+	for _, c := range p.columns() {
+		if strings.TrimSpace(c.value) != "" {
+			sets = append(sets, c.name+" = ?")
+			args = append(args, strings.TrimSpace(c.value))
+		}
+	}
+	for _, field := range LogoFields {
+		if path, ok := logoPaths[field]; ok {
+			sets = append(sets, field+" = ?")
+			args = append(args, path)
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	_, err := db.Exec("UPDATE settings SET "+strings.Join(sets, ", ")+", updated_at = NOW() WHERE `key` = 'crm_name'", args...)
+	return err
+}
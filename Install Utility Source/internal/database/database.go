@@ -0,0 +1,319 @@
+// Package database talks to the CRM's MySQL or MariaDB server directly so
+// the installer does not depend on mysql.exe or on quoting values into SQL
+// text.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Errors returned by this package wrap one of these so callers can tell a
+// bad password from a missing grant with errors.Is. The driver error is
+// wrapped as well.
+var (
+	ErrConnection      = errors.New("cannot reach database server")
+	ErrAuthFailed      = errors.New("database authentication failed")
+	ErrAccessDenied    = errors.New("database access denied")
+	ErrUnknownDatabase = errors.New("unknown database")
+)
+
+// Server error numbers, see
+// https://mariadb.com/kb/en/mariadb-error-codes/.
+const (
+	erDBAccessDenied       = 1044
+	erAccessDenied         = 1045
+	erBadDB                = 1049
+	erTableAccessDenied    = 1142
+	erColumnAccessDenied   = 1143
+	erSpecificAccessDenied = 1227
+	erProcAccessDenied     = 1370
+	erCantCreateUser       = 1410
+)
+
+// Config describes how to reach the server. An empty Database connects
+// without a default schema.
+type Config struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+	// Timeout bounds connecting and each read or write. Zero means 10s.
+	Timeout time.Duration
+}
+
+// DB is a connection pool to one server.
+type DB struct {
+	conn *sql.DB
+}
+
+// Open connects to the server and verifies the credentials.
+func Open(cfg Config) (*DB, error) {
+	if cfg.Port == 0 {
+		cfg.Port = 3306
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	mc := mysql.NewConfig()
+	mc.User = cfg.User
+	mc.Passwd = cfg.Password
+	mc.Net = "tcp"
+	mc.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	mc.DBName = cfg.Database
+	mc.Collation = "utf8mb4_unicode_ci"
+	mc.Timeout = cfg.Timeout
+	mc.ReadTimeout = 5 * time.Minute
+	mc.WriteTimeout = 5 * time.Minute
+	// Account-management statements cannot be prepared server side, so
+	// arguments are escaped by the driver instead.
+	mc.InterpolateParams = true
+
+	connector, err := mysql.NewConnector(mc)
+	if err != nil {
+		return nil, fmt.Errorf("database configuration: %w", err)
+	}
+	db := &DB{conn: sql.OpenDB(connector)}
+
+	pingCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	if err := db.conn.PingContext(pingCtx); err != nil {
+		db.conn.Close()
+		return nil, wrap(fmt.Sprintf("connect to %s as %s", mc.Addr, cfg.User), err)
+	}
+	return db, nil
+}
+
+func (db *DB) Close() error {
+	return db.conn.Close()
+}
+
+// Exec runs a statement with ? placeholders for its arguments.
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	res, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return nil, wrap(summarize(query), err)
+	}
+	return res, nil
+}
+
+// Query runs a query with ? placeholders for its arguments.
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, wrap(summarize(query), err)
+	}
+	return rows, nil
+}
+
+// QueryRow runs a query expected to return one row and scans it into dest.
+// It returns sql.ErrNoRows when there is none.
+func (db *DB) QueryRow(query string, args []any, dest ...any) error {
+	err := db.conn.QueryRow(query, args...).Scan(dest...)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return wrap(summarize(query), err)
+	}
+	return err
+}
+
+// Begin starts a transaction.
+func (db *DB) Begin() (*sql.Tx, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, wrap("begin transaction", err)
+	}
+	return tx, nil
+}
+
+// Version returns the server's VERSION() string.
+func (db *DB) Version() (string, error) {
+	var version string
+	if err := db.QueryRow("SELECT VERSION()", nil, &version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// DatabaseExists reports whether the schema exists and is visible to the
+// current account.
+func (db *DB) DatabaseExists(name string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", []any{name}, &count)
+	return count > 0, err
+}
+
+// CreateDatabase creates the schema with the CRM's character set if it does
+// not exist yet.
+func (db *DB) CreateDatabase(name string) error {
+	_, err := db.Exec("CREATE DATABASE IF NOT EXISTS " + QuoteIdentifier(name) + " CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci")
+	return err
+}
+
+func (db *DB) DropDatabase(name string) error {
+	_, err := db.Exec("DROP DATABASE IF EXISTS " + QuoteIdentifier(name))
+	return err
+}
+
+// CreateUser creates user@host, or resets its password if it exists.
+func (db *DB) CreateUser(user, host, password string) error {
+	if _, err := db.Exec("CREATE USER IF NOT EXISTS ?@? IDENTIFIED BY ?", user, host, password); err != nil {
+		return err
+	}
+	_, err := db.Exec("ALTER USER ?@? IDENTIFIED BY ?", user, host, password)
+	return err
+}
+
+func (db *DB) DropUser(user, host string) error {
+	_, err := db.Exec("DROP USER IF EXISTS ?@?", user, host)
+	return err
+}
+
+// DropTables drops every table and view in the connected database so a
+// dump can be imported into an empty schema, and returns how many there
+// were. Foreign keys are not checked meanwhile, so the order is free.
+func (db *DB) DropTables() (int, error) {
+	ctx := context.Background()
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return 0, wrap("open connection", err)
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "SELECT TABLE_NAME, TABLE_TYPE FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()")
+	if err != nil {
+		return 0, wrap("list tables", err)
+	}
+	var drops []string
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			rows.Close()
+			return 0, wrap("list tables", err)
+		}
+		if kind == "VIEW" {
+			drops = append(drops, "DROP VIEW IF EXISTS "+QuoteIdentifier(name))
+		} else {
+			drops = append(drops, "DROP TABLE IF EXISTS "+QuoteIdentifier(name))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, wrap("list tables", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return 0, wrap("disable foreign key checks", err)
+	}
+	// The connection goes back to the pool afterwards.
+	defer conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1")
+	for i, stmt := range drops {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return i, wrap(summarize(stmt), err)
+		}
+	}
+	return len(drops), nil
+}
+
+// GrantAll gives user@host every privilege on database.
+func (db *DB) GrantAll(database, user, host string) error {
+	_, err := db.Exec("GRANT ALL PRIVILEGES ON "+QuoteIdentifier(database)+".* TO ?@?", user, host)
+	return err
+}
+
+// SupportsCollation reports whether the server has the named collation.
+func (db *DB) SupportsCollation(charset, collation string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.COLLATIONS WHERE CHARACTER_SET_NAME = ? AND COLLATION_NAME = ?",
+		[]any{charset, collation}, &count)
+	return count > 0, err
+}
+
+// Privileges lists the current account's global privileges, plus those on
+// schema when it is not empty. grantable is true if any of them carries
+// GRANT OPTION.
+func (db *DB) Privileges(schema string) (granted map[string]bool, grantable bool, err error) {
+	const grantee = "GRANTEE = CONCAT('''', SUBSTRING_INDEX(CURRENT_USER(), '@', 1), '''@''', SUBSTRING_INDEX(CURRENT_USER(), '@', -1), '''')"
+	granted = make(map[string]bool)
+	collect := func(query string, args ...any) error {
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var priv, isGrantable string
+			if err := rows.Scan(&priv, &isGrantable); err != nil {
+				return wrap("read privileges", err)
+			}
+			granted[strings.ToUpper(priv)] = true
+			if strings.EqualFold(isGrantable, "YES") {
+				grantable = true
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return wrap("read privileges", err)
+		}
+		return nil
+	}
+
+	if err := collect("SELECT PRIVILEGE_TYPE, IS_GRANTABLE FROM information_schema.USER_PRIVILEGES WHERE " + grantee); err != nil {
+		return nil, false, err
+	}
+	if schema != "" {
+		// Schema grants are LIKE patterns, so match the name against them.
+		if err := collect("SELECT PRIVILEGE_TYPE, IS_GRANTABLE FROM information_schema.SCHEMA_PRIVILEGES WHERE "+grantee+" AND ? LIKE TABLE_SCHEMA", schema); err != nil {
+			return nil, false, err
+		}
+	}
+	return granted, grantable, nil
+}
+
+// QuoteIdentifier quotes a database, table or column name. Identifiers
+// cannot be passed as placeholders.
+func QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// wrap attaches the operation and a classification to a driver error.
+func wrap(op string, err error) error {
+	var kind error
+	var myErr *mysql.MySQLError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &myErr):
+		switch myErr.Number {
+		case erAccessDenied:
+			kind = ErrAuthFailed
+		case erDBAccessDenied, erTableAccessDenied, erColumnAccessDenied, erSpecificAccessDenied, erProcAccessDenied, erCantCreateUser:
+			kind = ErrAccessDenied
+		case erBadDB:
+			kind = ErrUnknownDatabase
+		}
+	case errors.As(err, &netErr), errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn), errors.Is(err, context.DeadlineExceeded):
+		kind = ErrConnection
+	}
+	if kind == nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return fmt.Errorf("%s: %w: %w", op, kind, err)
+}
+
+// summarize shortens a statement for error messages.
+func summarize(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > 60 {
+		query = query[:57] + "..."
+	}
+	return query
+}
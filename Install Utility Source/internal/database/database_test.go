@@ -0,0 +1,389 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// scanAll reads every statement of script.
+func scanAll(t *testing.T, script string) []Statement {
+	t.Helper()
+	s := NewScanner(strings.NewReader(script), 1, ";", 0)
+	var stmts []Statement
+	for {
+		stmt, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			return stmts
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+		lines  []int
+	}{
+		{
+			name:   "statements",
+			script: "CREATE TABLE t (id INT);\nINSERT INTO t VALUES (1);\n",
+			want:   []string{"CREATE TABLE t (id INT)", "INSERT INTO t VALUES (1)"},
+			lines:  []int{1, 2},
+		},
+		{
+			name:   "several on a line",
+			script: "SELECT 1; SELECT 2;;\n",
+			want:   []string{"SELECT 1", "SELECT 2"},
+			lines:  []int{1, 1},
+		},
+		{
+			name:   "multi-line statement",
+			script: "\nINSERT INTO t\nVALUES (1),\n(2);\n",
+			want:   []string{"INSERT INTO t\nVALUES (1),\n(2)"},
+			lines:  []int{2},
+		},
+		{
+			name:   "quoted delimiters",
+			script: "INSERT INTO t VALUES ('a;b', \"c;d\");\nSELECT `e;f` FROM t;\n",
+			want:   []string{"INSERT INTO t VALUES ('a;b', \"c;d\")", "SELECT `e;f` FROM t"},
+			lines:  []int{1, 2},
+		},
+		{
+			name:   "escaped and doubled quotes",
+			script: "INSERT INTO t VALUES ('it\\'s;', 'it''s;', \"say \\\";\\\"\");\n",
+			want:   []string{"INSERT INTO t VALUES ('it\\'s;', 'it''s;', \"say \\\";\\\"\")"},
+			lines:  []int{1},
+		},
+		{
+			name:   "quoted value over several lines",
+			script: "INSERT INTO t VALUES ('one;\ntwo;\n');\nSELECT 1;\n",
+			want:   []string{"INSERT INTO t VALUES ('one;\ntwo;\n')", "SELECT 1"},
+			lines:  []int{1, 4},
+		},
+		{
+			name:   "line comments",
+			script: "-- a comment; not a statement\n# another;\nSELECT 1; -- trailing;\nSELECT 2--2;\n",
+			want:   []string{"SELECT 1", "SELECT 2--2"},
+			lines:  []int{3, 4},
+		},
+		{
+			name:   "block comments",
+			script: "/* a comment;\n still; */\nSELECT /* ; */ 1;\n",
+			want:   []string{"SELECT   1"},
+			lines:  []int{3},
+		},
+		{
+			name:   "versioned comments",
+			script: "/*!40101 SET NAMES utf8mb4 */;\n/*M!100100 SET @x = 1 */;\n",
+			want:   []string{"/*!40101 SET NAMES utf8mb4 */", "/*M!100100 SET @x = 1 */"},
+			lines:  []int{1, 2},
+		},
+		{
+			name:   "sandbox directive",
+			script: sandboxDirective + "\n-- MariaDB dump\nSELECT 1;\n",
+			want:   []string{"SELECT 1"},
+			lines:  []int{3},
+		},
+		{
+			name: "delimiter",
+			script: "DELIMITER ;;\n" +
+				"CREATE TRIGGER tr BEFORE INSERT ON t FOR EACH ROW BEGIN SET NEW.id = 1; END ;;\n" +
+				"DELIMITER ;\n" +
+				"SELECT 1;\n",
+			want:  []string{"CREATE TRIGGER tr BEFORE INSERT ON t FOR EACH ROW BEGIN SET NEW.id = 1; END", "SELECT 1"},
+			lines: []int{2, 4},
+		},
+		{
+			name:   "last statement without a delimiter",
+			script: "SELECT 1;\nSELECT 2",
+			want:   []string{"SELECT 1", "SELECT 2"},
+			lines:  []int{1, 2},
+		},
+		{
+			name:   "CRLF",
+			script: "SELECT 1;\r\nSELECT\r\n2;\r\n",
+			want:   []string{"SELECT 1", "SELECT\r\n2"},
+			lines:  []int{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		var sql []string
+		var lines []int
+		for _, stmt := range scanAll(t, tt.script) {
+			sql = append(sql, stmt.SQL)
+			lines = append(lines, stmt.Line)
+		}
+		if !slices.Equal(sql, tt.want) || !slices.Equal(lines, tt.lines) {
+			t.Errorf("%s: got %q at lines %v, want %q at lines %v", tt.name, sql, lines, tt.want, tt.lines)
+		}
+	}
+}
+
+func TestScannerUnterminated(t *testing.T) {
+	for _, script := range []string{"SELECT 'open;\n", "SELECT 1 /* open;\n"} {
+		s := NewScanner(strings.NewReader(script), 1, ";", 0)
+		if _, err := s.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Next(%q) error = %v, want io.ErrUnexpectedEOF", script, err)
+		}
+	}
+}
+
+// TestScannerResume restarts the scanner after every statement the way
+// Import resumes from a saved ImportState, and expects the same statements
+// at the same positions as an uninterrupted scan.
+func TestScannerResume(t *testing.T) {
+	script := sandboxDirective + "\n" +
+		"/*!40101 SET NAMES utf8mb4 */;\n" +
+		"CREATE TABLE t (id INT, note TEXT); INSERT INTO t VALUES (1, 'a;b');\n" +
+		"-- comment;\n" +
+		"DELIMITER $$\n" +
+		"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END $$\n" +
+		"DELIMITER ;\n" +
+		"INSERT INTO t VALUES\n(2, 'multi\nline;'),\n(3, NULL);\n" +
+		"SELECT 3"
+	full := scanAll(t, script)
+	if len(full) != 6 {
+		t.Fatalf("scanned %d statements, want 6", len(full))
+	}
+
+	s := NewScanner(strings.NewReader(script), 1, ";", 0)
+	for i := range full {
+		stmt, err := s.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := ImportState{Offset: stmt.EndOffset, Line: stmt.EndLine, Statements: i + 1, Delimiter: s.Delimiter()}
+		resumed := NewScanner(strings.NewReader(script[state.Offset:]), state.Line, state.Delimiter, state.Offset)
+		for j, want := range full[i+1:] {
+			got, err := resumed.Next()
+			if err != nil {
+				t.Fatalf("resumed after statement %d: %v", i+1, err)
+			}
+			if got != want {
+				t.Errorf("resumed after statement %d: statement %d = %+v, want %+v", i+1, i+j+2, got, want)
+			}
+		}
+		if _, err := resumed.Next(); !errors.Is(err, io.EOF) {
+			t.Errorf("resumed after statement %d: want io.EOF at the end, got %v", i+1, err)
+		}
+	}
+}
+
+func TestIsSessionStatement(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SET NAMES utf8mb4", true},
+		{"set @x = 1", true},
+		{"/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */", true},
+		{"SET GLOBAL max_allowed_packet = 1073741824", false},
+		{"/*!40101 SET @@global.time_zone = '+00:00' */", false},
+		{"INSERT INTO settings SET name = 'x'", false},
+		{"SELECT 1", false},
+	}
+	for _, tt := range tests {
+		if got := isSessionStatement(tt.sql); got != tt.want {
+			t.Errorf("isSessionStatement(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+// TestOpenConnectionRefused needs no server: nothing listens on the port.
+func TestOpenConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	_, err = Open(Config{Host: "127.0.0.1", Port: port, User: "root", Timeout: 2 * time.Second})
+	if !errors.Is(err, ErrConnection) {
+		t.Fatalf("Open error = %v, want ErrConnection", err)
+	}
+}
+
+// testConfig returns the server named by YACHTCRM_TEST_DSN, in the driver's
+// DSN format, or skips the test. The account must be able to create
+// databases and users.
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	dsn := os.Getenv("YACHTCRM_TEST_DSN")
+	if dsn == "" {
+		t.Skip("set YACHTCRM_TEST_DSN, e.g. root:secret@tcp(127.0.0.1:3306)/, to test against a server")
+	}
+	mc, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("YACHTCRM_TEST_DSN: %v", err)
+	}
+	host, port, err := net.SplitHostPort(mc.Addr)
+	if err != nil {
+		t.Fatalf("YACHTCRM_TEST_DSN: %v", err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("YACHTCRM_TEST_DSN: %v", err)
+	}
+	return Config{Host: host, Port: p, User: mc.User, Password: mc.Passwd, Timeout: 5 * time.Second}
+}
+
+// testName returns a database or user name unlikely to exist yet.
+func testName(prefix string) string {
+	return fmt.Sprintf("yachtcrm_test_%s_%d", prefix, time.Now().UnixNano()%1e9)
+}
+
+func openTest(t *testing.T, cfg Config) *DB {
+	t.Helper()
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestOpenAuthFailed(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.User, cfg.Password = testName("nobody"), "wrong password"
+	_, err := Open(cfg)
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("Open error = %v, want ErrAuthFailed", err)
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) || myErr.Number != erAccessDenied {
+		t.Errorf("Open error = %v, want the driver error wrapped as well", err)
+	}
+}
+
+func TestOpenUnknownDatabase(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Database = testName("missing")
+	_, err := Open(cfg)
+	if !errors.Is(err, ErrUnknownDatabase) {
+		t.Fatalf("Open error = %v, want ErrUnknownDatabase", err)
+	}
+}
+
+func TestAccessDenied(t *testing.T) {
+	cfg := testConfig(t)
+	admin := openTest(t, cfg)
+	user, password := testName("user"), "Test-password-1"
+	if err := admin.CreateUser(user, "%", password); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.DropUser(user, "%") })
+
+	limited := cfg
+	limited.User, limited.Password = user, password
+	db := openTest(t, limited)
+	err := db.CreateDatabase(testName("denied"))
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("CreateDatabase error = %v, want ErrAccessDenied", err)
+	}
+	if errors.Is(err, ErrAuthFailed) || errors.Is(err, ErrConnection) {
+		t.Errorf("CreateDatabase error = %v, want only ErrAccessDenied", err)
+	}
+}
+
+func TestImportResume(t *testing.T) {
+	cfg := testConfig(t)
+	admin := openTest(t, cfg)
+	name := testName("import")
+	if err := admin.CreateDatabase(name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.DropDatabase(name) })
+	cfg.Database = name
+	db := openTest(t, cfg)
+
+	script := "/*!40101 SET @marker = 'kept;' */;\n" +
+		"CREATE TABLE a (note TEXT);\n" +
+		"INSERT INTO a VALUES (@marker);\n" +
+		"INSERT INTO b VALUES (@marker);\n" +
+		"INSERT INTO a VALUES ('after');\n"
+
+	var progress []ImportState
+	state, err := db.Import(strings.NewReader(script), ImportOptions{Progress: func(s ImportState) { progress = append(progress, s) }})
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || importErr.Line != 4 {
+		t.Fatalf("Import error = %v, want an ImportError at line 4", err)
+	}
+	if state.Statements != 3 || len(progress) != 3 || len(state.Session) != 1 {
+		t.Fatalf("state = %+v after %d progress calls, want 3 statements and 1 session statement", state, len(progress))
+	}
+
+	// Fix the cause and resume on a new connection; the session variable
+	// must be replayed.
+	if _, err := db.Exec("CREATE TABLE b (note TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	state, err = db.Import(strings.NewReader(script[state.Offset:]), ImportOptions{Resume: &state})
+	if err != nil {
+		t.Fatalf("resumed Import: %v", err)
+	}
+	if state.Statements != 5 {
+		t.Errorf("resumed state = %+v, want 5 statements", state)
+	}
+	for table, want := range map[string]string{"a": "kept;,after", "b": "kept;"} {
+		var got string
+		if err := db.QueryRow("SELECT GROUP_CONCAT(note ORDER BY note DESC) FROM "+QuoteIdentifier(table), nil, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("table %s holds %q, want %q", table, got, want)
+		}
+	}
+}
+
+func TestDropTables(t *testing.T) {
+	cfg := testConfig(t)
+	admin := openTest(t, cfg)
+	name := testName("drop")
+	if err := admin.CreateDatabase(name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.DropDatabase(name) })
+	cfg.Database = name
+	db := openTest(t, cfg)
+
+	for _, stmt := range []string{
+		"CREATE TABLE parent (id INT PRIMARY KEY)",
+		"CREATE TABLE child (id INT PRIMARY KEY, parent_id INT, FOREIGN KEY (parent_id) REFERENCES parent (id))",
+		"CREATE VIEW children AS SELECT id FROM child",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dropped, err := db.DropTables()
+	if err != nil {
+		t.Fatalf("DropTables: %v", err)
+	}
+	if dropped != 3 {
+		t.Errorf("DropTables dropped %d, want 3", dropped)
+	}
+	if n, err := db.TableCount(); err != nil || n != 0 {
+		t.Errorf("TableCount = %d, %v after DropTables, want 0", n, err)
+	}
+	// Foreign key checks are back on for the pooled connections.
+	var checks int
+	if err := db.QueryRow("SELECT @@FOREIGN_KEY_CHECKS", nil, &checks); err != nil || checks != 1 {
+		t.Errorf("FOREIGN_KEY_CHECKS = %d, %v, want 1", checks, err)
+	}
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// dumpBatchRows is how many rows go into one INSERT statement.
+const dumpBatchRows = 500
+
+// DumpOptions adjusts what Dump writes.
+type DumpOptions struct {
+	// SchemaOnly lists tables whose rows are left out.
+	SchemaOnly map[string]bool
+	// Transform, if set, may rewrite each row before it is written. A nil
+	// value is written as NULL.
+	Transform func(table string, columns []string, values []sql.RawBytes) error
+}
+
+// Dump writes the connected database's tables, views and data to w as a SQL
+// script that Import can restore.
+func (db *DB) Dump(w io.Writer, opts DumpOptions) error {
+	out := bufio.NewWriterSize(w, 1<<20)
+	fmt.Fprintf(out, "-- YachtCRM-DMS database backup\n-- Created %s\n\n", time.Now().Format(time.RFC3339))
+	out.WriteString("/*!40101 SET NAMES utf8mb4 */;\n/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;\n/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;\n\n")
+
+	rows, err := db.Query("SELECT TABLE_NAME, TABLE_TYPE FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_TYPE, TABLE_NAME")
+	if err != nil {
+		return err
+	}
+	var tables, views []string
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			rows.Close()
+			return wrap("list tables", err)
+		}
+		if kind == "VIEW" {
+			views = append(views, name)
+		} else {
+			tables = append(tables, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return wrap("list tables", err)
+	}
+
+	for _, table := range tables {
+		var name, create string
+		if err := db.QueryRow("SHOW CREATE TABLE "+QuoteIdentifier(table), nil, &name, &create); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "DROP TABLE IF EXISTS %s;\n%s;\n\n", QuoteIdentifier(table), create)
+		if opts.SchemaOnly[table] {
+			continue
+		}
+		if err := db.dumpRows(out, table, opts.Transform); err != nil {
+			return err
+		}
+	}
+	for _, view := range views {
+		var name, create, charset, collation string
+		if err := db.QueryRow("SHOW CREATE VIEW "+QuoteIdentifier(view), nil, &name, &create, &charset, &collation); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "DROP VIEW IF EXISTS %s;\n%s;\n\n", QuoteIdentifier(view), create)
+	}
+
+	out.WriteString("/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;\n/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;\n")
+	return out.Flush()
+}
+
+func (db *DB) dumpRows(out *bufio.Writer, table string, transform func(string, []string, []sql.RawBytes) error) error {
+	rows, err := db.Query("SELECT * FROM " + QuoteIdentifier(table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return wrap("read columns of "+table, err)
+	}
+	names := make([]string, len(columns))
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		names[i] = columns[i].Name()
+		dest[i] = &values[i]
+	}
+
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return wrap("read "+table, err)
+		}
+		if transform != nil {
+			if err := transform(table, names, values); err != nil {
+				return fmt.Errorf("%s row %d: %w", table, n+1, err)
+			}
+		}
+		if n%dumpBatchRows == 0 {
+			if n > 0 {
+				out.WriteString(";\n")
+			}
+			fmt.Fprintf(out, "INSERT INTO %s VALUES ", QuoteIdentifier(table))
+		} else {
+			out.WriteString(",")
+		}
+		out.WriteString("(")
+		for i, v := range values {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			out.WriteString(sqlLiteral(v, columns[i].DatabaseTypeName()))
+		}
+		out.WriteString(")")
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return wrap("read "+table, err)
+	}
+	if n > 0 {
+		out.WriteString(";\n\n")
+	}
+	return nil
+}
+
+// sqlLiteral renders a value as the text protocol returned it. Binary
+// columns are written as hex so no byte sequence can break the quoting.
+func sqlLiteral(v sql.RawBytes, typeName string) string {
+	if v == nil {
+		return "NULL"
+	}
+	switch typeName {
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		if len(v) == 0 {
+			return "''"
+		}
+		return "0x" + hex.EncodeToString(v)
+	}
+	return "'" + escapeString(string(v)) + "'"
+}
+
+var stringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	"\x00", `\0`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x1a", `\Z`,
+)
+
+func escapeString(s string) string {
+	return stringEscaper.Replace(s)
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxSessionStatements bounds how many SET statements are remembered for
+// replay when an import resumes.
+const maxSessionStatements = 200
+
+// ImportState records how far an import got. It is returned after every
+// statement so callers can persist it and resume later.
+type ImportState struct {
+	Offset     int64  `json:"offset"`
+	Line       int    `json:"line"`
+	Statements int    `json:"statements"`
+	Delimiter  string `json:"delimiter"`
+	// Session holds the SET statements executed so far. A resumed import
+	// runs on a new connection, so they are replayed first.
+	Session []string `json:"session,omitempty"`
+}
+
+// ImportOptions controls Import. A nil Resume starts at the beginning.
+type ImportOptions struct {
+	Resume *ImportState
+	// Progress is called after each statement with the position reached.
+	Progress func(ImportState)
+}
+
+// ImportError identifies the statement an import stopped at.
+type ImportError struct {
+	Line      int
+	Statement string
+	Err       error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("statement at line %d failed: %v\n%s", e.Line, e.Err, e.Statement)
+}
+
+func (e *ImportError) Unwrap() error { return e.Err }
+
+var sessionStatement = regexp.MustCompile(`(?is)^(/\*!\d*\s*)?SET\s+`)
+
+// Import streams a SQL script from r into the database, one statement at a
+// time, on a single connection. r must be positioned at opts.Resume.Offset
+// when resuming. It returns the state after the last successful statement.
+func (db *DB) Import(r io.Reader, opts ImportOptions) (ImportState, error) {
+	state := ImportState{Line: 1, Delimiter: ";"}
+	if opts.Resume != nil {
+		state = *opts.Resume
+		state.Session = append([]string(nil), opts.Resume.Session...)
+	}
+
+	ctx := context.Background()
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return state, wrap("open connection", err)
+	}
+	defer conn.Close()
+
+	for _, stmt := range state.Session {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return state, wrap("restore session settings", err)
+		}
+	}
+
+	scanner := NewScanner(r, state.Line, state.Delimiter, state.Offset)
+	for {
+		stmt, err := scanner.Next()
+		if errors.Is(err, io.EOF) {
+			return state, nil
+		}
+		if err != nil {
+			return state, fmt.Errorf("read SQL near line %d: %w", state.Line, err)
+		}
+		if _, err := conn.ExecContext(ctx, stmt.SQL); err != nil {
+			return state, &ImportError{Line: stmt.Line, Statement: abbreviate(stmt.SQL, 2000), Err: wrap("execute", err)}
+		}
+
+		state.Offset = stmt.EndOffset
+		state.Line = stmt.EndLine
+		state.Statements++
+		state.Delimiter = scanner.Delimiter()
+		if isSessionStatement(stmt.SQL) && !slices.Contains(state.Session, stmt.SQL) && len(state.Session) < maxSessionStatements {
+			state.Session = append(state.Session, stmt.SQL)
+		}
+		if opts.Progress != nil {
+			opts.Progress(state)
+		}
+	}
+}
+
+// isSessionStatement reports whether sql changes connection state, such as
+// the SET statements at the top of a dump.
+func isSessionStatement(sql string) bool {
+	return sessionStatement.MatchString(sql) && !strings.Contains(strings.ToUpper(sql), "GLOBAL")
+}
+
+// TableCount returns how many tables the connected database holds.
+func (db *DB) TableCount() (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()", nil, &count)
+	return count, err
+}
+
+func abbreviate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + fmt.Sprintf("... (%d more bytes)", len(s)-max)
+}
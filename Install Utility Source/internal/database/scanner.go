@@ -0,0 +1,221 @@
+package database
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sandboxDirective is the first line of dumps written by mariadb-dump 11.
+// It is an instruction to the mariadb client, not SQL.
+const sandboxDirective = `/*M!999999\- enable the sandbox mode */`
+
+// Statement is one SQL statement read from a dump.
+type Statement struct {
+	SQL string
+	// Line is the line the statement starts on, counting from 1.
+	Line int
+	// EndLine is the line holding its delimiter and EndOffset the byte
+	// offset just past it. Reading can resume from there.
+	EndLine   int
+	EndOffset int64
+}
+
+// Scanner splits a SQL script into statements the way the mysql client
+// does: delimiters inside quotes and comments are ignored, DELIMITER lines
+// change the terminator, and ordinary comments are dropped. Versioned
+// comments such as /*!40101 ... */ are kept because the server executes
+// them.
+type Scanner struct {
+	r         *bufio.Reader
+	delimiter string
+
+	pending string // unprocessed rest of the current line
+	line    int
+	offset  int64 // stream offset of pending
+
+	buf       strings.Builder
+	startLine int
+	quote     byte
+	comment   bool // inside /* */
+	keep      bool // the open block comment is executable
+}
+
+// NewScanner reads statements from r. line and delimiter describe the
+// position r starts at; use 1 and ";" for the beginning of a file.
+func NewScanner(r io.Reader, line int, delimiter string, offset int64) *Scanner {
+	if delimiter == "" {
+		delimiter = ";"
+	}
+	return &Scanner{r: bufio.NewReaderSize(r, 1<<20), delimiter: delimiter, line: line - 1, offset: offset}
+}
+
+// Delimiter is the statement terminator currently in effect.
+func (s *Scanner) Delimiter() string { return s.delimiter }
+
+// Offset is the number of bytes consumed so far, including the starting
+// offset.
+func (s *Scanner) Offset() int64 { return s.offset }
+
+// Next returns the next statement, or io.EOF when the input is exhausted.
+// A final statement without a delimiter is returned as well.
+func (s *Scanner) Next() (Statement, error) {
+	for {
+		if s.pending == "" {
+			text, err := s.r.ReadString('\n')
+			if text == "" && err != nil {
+				if !errors.Is(err, io.EOF) {
+					return Statement{}, err
+				}
+				if s.quote != 0 || s.comment {
+					return Statement{}, io.ErrUnexpectedEOF
+				}
+				if sql := strings.TrimSpace(s.buf.String()); sql != "" {
+					stmt := Statement{SQL: sql, Line: s.startLine, EndLine: s.line, EndOffset: s.offset}
+					s.reset()
+					return stmt, nil
+				}
+				return Statement{}, io.EOF
+			}
+			s.pending = text
+			s.line++
+			if s.atStatementStart() && s.directive() {
+				continue
+			}
+		}
+		if stmt, ok := s.scanPending(); ok {
+			return stmt, nil
+		}
+	}
+}
+
+func (s *Scanner) atStatementStart() bool {
+	return s.quote == 0 && !s.comment && strings.TrimSpace(s.buf.String()) == ""
+}
+
+// directive handles client-only lines at the start of a statement. It
+// reports whether the pending line was consumed.
+func (s *Scanner) directive() bool {
+	trimmed := strings.TrimSpace(s.pending)
+	fields := strings.Fields(trimmed)
+	switch {
+	case len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER"):
+		s.delimiter = fields[1]
+	case strings.HasPrefix(trimmed, sandboxDirective):
+	default:
+		return false
+	}
+	s.offset += int64(len(s.pending))
+	s.pending = ""
+	s.buf.Reset()
+	return true
+}
+
+// scanPending consumes the pending line up to the end of a statement.
+func (s *Scanner) scanPending() (Statement, bool) {
+	p := s.pending
+	i := 0
+	for i < len(p) {
+		switch {
+		case s.comment:
+			end := strings.Index(p[i:], "*/")
+			if end == -1 {
+				if s.keep {
+					s.buf.WriteString(p[i:])
+				}
+				i = len(p)
+				continue
+			}
+			if s.keep {
+				s.buf.WriteString(p[i : i+end+2])
+			} else {
+				s.buf.WriteByte(' ')
+			}
+			s.comment = false
+			i += end + 2
+
+		case s.quote != 0:
+			c := p[i]
+			switch {
+			case c == '\\' && s.quote != '`' && i+1 < len(p):
+				s.buf.WriteString(p[i : i+2])
+				i += 2
+			case c == s.quote && i+1 < len(p) && p[i+1] == s.quote:
+				s.buf.WriteString(p[i : i+2])
+				i += 2
+			case c == s.quote:
+				s.buf.WriteByte(c)
+				s.quote = 0
+				i++
+			default:
+				s.buf.WriteByte(c)
+				i++
+			}
+
+		case strings.HasPrefix(p[i:], s.delimiter):
+			sql := strings.TrimSpace(s.buf.String())
+			stmt := Statement{SQL: sql, Line: s.startLine, EndLine: s.line, EndOffset: s.offset + int64(i+len(s.delimiter))}
+			s.offset += int64(i + len(s.delimiter))
+			s.pending = p[i+len(s.delimiter):]
+			s.reset()
+			if sql == "" {
+				return s.continueLine()
+			}
+			return stmt, true
+
+		default:
+			c := p[i]
+			switch {
+			case c == '\'' || c == '"' || c == '`':
+				s.markStart()
+				s.quote = c
+				s.buf.WriteByte(c)
+				i++
+			case c == '#' || strings.HasPrefix(p[i:], "--") && (i+2 == len(p) || strings.ContainsRune(" \t\r\n", rune(p[i+2]))):
+				// Line comment: drop it but keep the newline.
+				if strings.HasSuffix(p, "\n") {
+					s.buf.WriteByte('\n')
+				}
+				i = len(p)
+			case strings.HasPrefix(p[i:], "/*"):
+				s.comment = true
+				s.keep = strings.HasPrefix(p[i:], "/*!") || strings.HasPrefix(p[i:], "/*M!") || strings.HasPrefix(p[i:], "/*+")
+				if s.keep {
+					s.markStart()
+					s.buf.WriteString("/*")
+				}
+				i += 2
+			default:
+				if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+					s.markStart()
+				}
+				s.buf.WriteByte(c)
+				i++
+			}
+		}
+	}
+	s.offset += int64(len(p))
+	s.pending = ""
+	return Statement{}, false
+}
+
+// continueLine resumes scanning the rest of a line after an empty
+// statement such as a stray delimiter.
+func (s *Scanner) continueLine() (Statement, bool) {
+	if s.pending == "" {
+		return Statement{}, false
+	}
+	return s.scanPending()
+}
+
+func (s *Scanner) markStart() {
+	if s.startLine == 0 {
+		s.startLine = s.line
+	}
+}
+
+func (s *Scanner) reset() {
+	s.buf.Reset()
+	s.startLine = 0
+}
@@ -0,0 +1,96 @@
+package demo
+
+type serviceItem struct {
+	name     string
+	category string
+	rate     int64 // cents per hour
+	minutes  int
+}
+
+var serviceCatalog = []serviceItem{
+	{"Engine Service - Annual", "Engine", 14500, 240},
+	{"Outdrive Service", "Engine", 13500, 180},
+	{"Bottom Paint", "Hull", 9500, 480},
+	{"Hull Wax and Buff", "Detailing", 8500, 360},
+	{"Interior Detail", "Detailing", 7500, 240},
+	{"Winterization", "Seasonal", 12500, 180},
+	{"Spring Commissioning", "Seasonal", 12500, 240},
+	{"Electrical Diagnostics", "Electrical", 15500, 120},
+	{"Electronics Installation", "Electrical", 15500, 300},
+	{"Canvas Repair", "Canvas", 9000, 120},
+	{"Haul Out and Block", "Yard", 11000, 90},
+	{"RV Chassis Service", "Vehicle", 13000, 150},
+	{"Trailer Bearing Repack", "Vehicle", 9500, 90},
+}
+
+type partItem struct {
+	name     string
+	category string
+	cost     int64 // cents
+}
+
+var partCatalog = []partItem{
+	{"Oil Filter", "Engine", 1450},
+	{"Fuel Water Separator", "Engine", 3275},
+	{"Impeller Kit", "Engine", 5890},
+	{"Spark Plug Set", "Engine", 2640},
+	{"Gear Lube Kit", "Drive", 3120},
+	{"Zinc Anode Kit", "Hull", 4580},
+	{"Antifouling Paint (gal)", "Hull", 21900},
+	{"Bilge Pump 1100 GPH", "Plumbing", 6850},
+	{"Raw Water Strainer", "Plumbing", 8925},
+	{"Marine Battery Group 31", "Electrical", 23500},
+	{"Navigation Light Set", "Electrical", 7460},
+	{"Shore Power Cord 30A", "Electrical", 15900},
+	{"Dock Line 5/8 x 25ft", "Deck", 4250},
+	{"Fender 8 x 26", "Deck", 3690},
+	{"Bimini Top Canvas", "Canvas", 48900},
+	{"Trailer Bearing Kit", "Trailer", 2875},
+	{"Trailer Tire ST205", "Trailer", 11250},
+	{"RV Air Filter", "Vehicle", 3890},
+	{"RV Wiper Blade Pair", "Vehicle", 2480},
+	{"Antifreeze -50F (gal)", "Seasonal", 1190},
+}
+
+var firstNames = []string{
+	"Alex", "Avery", "Blake", "Cameron", "Casey", "Dana", "Drew", "Elliot",
+	"Emerson", "Finley", "Harper", "Hayden", "Jamie", "Jordan", "Kendall", "Logan",
+	"Morgan", "Parker", "Quinn", "Reese", "Riley", "Rowan", "Sawyer", "Taylor",
+}
+
+var lastNames = []string{
+	"Anchor", "Bayliss", "Brooks", "Carver", "Dalton", "Ellison", "Fairway", "Graves",
+	"Harbor", "Islay", "Jetty", "Keel", "Lowell", "Marsh", "Norland", "Oakes",
+	"Pierce", "Quay", "Rigby", "Shoal", "Tidewell", "Upton", "Vale", "Whitlock",
+}
+
+var streets = []string{"Harbor", "Marina", "Lighthouse", "Bayview", "Seaside", "Pelican", "Mariner", "Cove"}
+
+var cities = []string{"Port Ellis", "Bay Harbor", "Cedar Point", "Northshore", "Seabrook", "Westport"}
+
+var states = []string{"FL", "SC", "NC", "MD", "NJ", "RI"}
+
+var yachtNames = []string{
+	"Sea Breeze", "Knot Working", "Reel Time", "Aqua Vitae", "Serenity", "Blue Horizon",
+	"Wind Dancer", "Second Wind", "Salty Dog", "Island Time", "Liquid Asset", "Far Niente",
+}
+
+var yachtTypes = []string{"Sailboat", "Motor Yacht", "Center Console", "Trawler", "Catamaran", "Sportfish"}
+
+type vehicleModel struct {
+	kind  string
+	make  string
+	model string
+}
+
+var vehicleModels = []vehicleModel{
+	{"rv", "Winnebago", "View 24D"},
+	{"rv", "Thor", "Four Winds 28A"},
+	{"truck", "Ford", "F-250"},
+	{"truck", "Ram", "2500"},
+	{"suv", "Chevrolet", "Tahoe"},
+	{"trailer", "EZ Loader", "Tandem 25"},
+	{"van", "Mercedes-Benz", "Sprinter"},
+}
+
+var colors = []string{"White", "Silver", "Black", "Blue", "Red", "Gray"}
@@ -0,0 +1,638 @@
+// Package demo generates a linked sample dataset for sales and training
+// installs and removes it again.
+//
+// Every inserted row is recorded in the installer_demo_rows table, so
+// removal deletes exactly what was seeded and leaves real data alone.
+package demo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/database"
+)
+
+// manifestTable lists the rows the seed inserted.
+const manifestTable = "installer_demo_rows"
+
+// ErrAlreadySeeded is returned when demo data is already present.
+var ErrAlreadySeeded = errors.New("demo data is already loaded; remove it first")
+
+// removeOrder lists seeded tables with children before their parents.
+var removeOrder = []string{
+	"journal_entry_lines", "journal_entries", "time_entries", "work_orders", "appointments",
+	"payments", "invoice_items", "invoices", "quote_items", "quotes",
+	"yachts", "vehicles", "customers", "parts", "services", "users", "chart_of_accounts",
+}
+
+// keepIfUsed guards seeded rows that real data may have started to use.
+// Deleting a ledger account would cascade to journal lines posted to it
+// after the seed.
+var keepIfUsed = map[string]string{
+	"chart_of_accounts": " AND NOT EXISTS (SELECT 1 FROM journal_entry_lines l WHERE l.account_id = t.id)",
+}
+
+// Options control the generated dataset. The same seed, size and date
+// produce the same data.
+type Options struct {
+	Seed int64
+	// Size is the number of customers; everything else scales with it.
+	Size int
+	// AsOf anchors all dates. Past work is dated before it and upcoming
+	// appointments after it.
+	AsOf time.Time
+}
+
+// Summary counts the rows inserted per table.
+type Summary map[string]int
+
+// Loaded reports how many rows the current demo seed holds, or 0 if there
+// is none.
+func Loaded(db *database.DB) (int, error) {
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+		[]any{manifestTable}, &tables); err != nil || tables == 0 {
+		return 0, err
+	}
+	var rows int
+	err := db.QueryRow("SELECT COUNT(*) FROM "+manifestTable, nil, &rows)
+	return rows, err
+}
+
+// Seed inserts the demo dataset in one transaction.
+func Seed(db *database.DB, opts Options) (Summary, error) {
+	if opts.Size < 1 {
+		return nil, fmt.Errorf("size must be at least 1")
+	}
+	loaded, err := Loaded(db)
+	if err != nil {
+		return nil, err
+	}
+	if loaded > 0 {
+		return nil, ErrAlreadySeeded
+	}
+	// DDL commits implicitly, so the manifest is created before the
+	// transaction starts.
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + manifestTable + " (table_name varchar(64) NOT NULL, row_id bigint unsigned NOT NULL, PRIMARY KEY (table_name, row_id)) ENGINE=InnoDB"); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{
+		tx:      tx,
+		rng:     rand.New(rand.NewPCG(uint64(opts.Seed), 0x59616368)),
+		asOf:    time.Date(opts.AsOf.Year(), opts.AsOf.Month(), opts.AsOf.Day(), 0, 0, 0, 0, time.Local),
+		summary: Summary{},
+	}
+	if err := g.run(opts.Size); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return g.summary, nil
+}
+
+// Remove deletes every seeded row and the manifest. Rows users added that
+// reference demo rows are removed or detached by the schema's own ON DELETE
+// rules.
+func Remove(db *database.DB) (Summary, error) {
+	loaded, err := Loaded(db)
+	if err != nil {
+		return nil, err
+	}
+	summary := Summary{}
+	if loaded > 0 {
+		tx, err := db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range removeOrder {
+			res, err := tx.Exec("DELETE t FROM "+database.QuoteIdentifier(table)+" t JOIN "+manifestTable+" m ON m.table_name = ? AND m.row_id = t.id"+keepIfUsed[table], table)
+			if err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("remove demo rows from %s: %w", table, err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				summary[table] = int(n)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := db.Exec("DROP TABLE IF EXISTS " + manifestTable); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+type generator struct {
+	tx      *sql.Tx
+	rng     *rand.Rand
+	asOf    time.Time
+	summary Summary
+
+	staff    []int64
+	manager  int64
+	services []service
+	parts    []part
+	accounts map[string]int64
+	methods  []int64
+	journal  int
+}
+
+type service struct {
+	id   int64
+	item serviceItem
+}
+
+type part struct {
+	id    int64
+	name  string
+	price int64
+}
+
+type customer struct {
+	id       int64
+	yachts   []int64
+	vehicles []int64
+}
+
+type lineItem struct {
+	kind      string
+	partID    any
+	serviceID any
+	desc      string
+	qty       int64
+	unit      int64
+}
+
+func (l lineItem) total() int64 { return l.qty * l.unit }
+
+// insert adds a row and records it in the manifest.
+func (g *generator) insert(table string, columns []string, values ...any) (int64, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")
+	res, err := g.tx.Exec("INSERT INTO "+database.QuoteIdentifier(table)+" ("+strings.Join(columns, ",")+",created_at,updated_at) VALUES ("+placeholders+",NOW(),NOW())", values...)
+	if err != nil {
+		return 0, fmt.Errorf("insert into %s: %w", table, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := g.tx.Exec("INSERT INTO "+manifestTable+" (table_name, row_id) VALUES (?, ?)", table, id); err != nil {
+		return 0, err
+	}
+	g.summary[table]++
+	return id, nil
+}
+
+func (g *generator) chance(p float64) bool { return g.rng.Float64() < p }
+
+func (g *generator) between(lo, hi int) int { return lo + g.rng.IntN(hi-lo+1) }
+
+func (g *generator) pick(words []string) string { return words[g.rng.IntN(len(words))] }
+
+func (g *generator) daysAgo(lo, hi int) time.Time {
+	return g.asOf.AddDate(0, 0, -g.between(lo, hi))
+}
+
+func money(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+func date(t time.Time) string { return t.Format(time.DateOnly) }
+
+func datetime(t time.Time) string { return t.Format(time.DateTime) }
+
+func (g *generator) run(size int) error {
+	steps := []func(int) error{g.seedStaff, g.seedAccounts, g.seedCatalog}
+	for _, step := range steps {
+		if err := step(size); err != nil {
+			return err
+		}
+	}
+
+	customers, err := g.seedCustomers(size)
+	if err != nil {
+		return err
+	}
+	for i := range customers {
+		if err := g.seedCustomerActivity(&customers[i]); err != nil {
+			return err
+		}
+	}
+	// Unsold stock on the lot.
+	for i := 0; i < size/5; i++ {
+		if _, err := g.seedVehicle(nil, "inventory"); err != nil {
+			return err
+		}
+	}
+	return g.seedTimeEntries()
+}
+
+func (g *generator) seedStaff(size int) error {
+	columns := []string{"name", "email", "password", "role", "email_verified_at"}
+	// Demo staff cannot sign in; the password is not a valid bcrypt hash.
+	const noLogin = "$2y$10$demo.account.without.password.login.is.disabled......"
+	id, err := g.insert("users", columns, "Morgan Harbor (Demo)", "office.manager@demo.example", noLogin, "office_staff", datetime(g.asOf))
+	if err != nil {
+		return err
+	}
+	g.manager = id
+	for i := 0; i < 2+size/20; i++ {
+		name := g.pick(firstNames) + " " + g.pick(lastNames)
+		email := fmt.Sprintf("technician%d@demo.example", i+1)
+		id, err := g.insert("users", columns, name+" (Demo)", email, noLogin, "employee", datetime(g.asOf))
+		if err != nil {
+			return err
+		}
+		g.staff = append(g.staff, id)
+	}
+	return nil
+}
+
+// seedAccounts finds the ledger accounts the journal entries post to,
+// creating any the database lacks.
+func (g *generator) seedAccounts(int) error {
+	wanted := []struct{ number, name, kind, detail string }{
+		{"1000", "Cash and Bank Accounts", "asset", "bank"},
+		{"1100", "Accounts Receivable", "asset", "accounts_receivable"},
+		{"2200", "Sales Tax Payable", "liability", "other_current_liability"},
+		{"4000", "Service Revenue", "revenue", "income"},
+		{"4100", "Product Sales", "revenue", "income"},
+	}
+	g.accounts = map[string]int64{}
+	for _, a := range wanted {
+		var id int64
+		err := g.tx.QueryRow("SELECT id FROM chart_of_accounts WHERE account_number = ?", a.number).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			id, err = g.insert("chart_of_accounts", []string{"account_number", "account_name", "account_type", "detail_type"}, a.number, a.name, a.kind, a.detail)
+		}
+		if err != nil {
+			return fmt.Errorf("ledger account %s: %w", a.number, err)
+		}
+		g.accounts[a.number] = id
+	}
+
+	rows, err := g.tx.Query("SELECT id FROM payment_methods WHERE active = 1 ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		g.methods = append(g.methods, id)
+	}
+	return rows.Err()
+}
+
+func (g *generator) seedCatalog(size int) error {
+	for _, s := range serviceCatalog {
+		id, err := g.insert("services", []string{"name", "description", "category", "hourly_rate", "duration_minutes", "active"},
+			s.name, "Demo service", s.category, money(s.rate), s.minutes, 1)
+		if err != nil {
+			return err
+		}
+		g.services = append(g.services, service{id: id, item: s})
+	}
+	for i := 0; i < 20+size; i++ {
+		p := partCatalog[i%len(partCatalog)]
+		name := p.name
+		if i >= len(partCatalog) {
+			name = fmt.Sprintf("%s (Variant %d)", p.name, i/len(partCatalog)+1)
+		}
+		cost := p.cost + int64(g.between(-10, 10))*p.cost/100
+		price := cost * int64(g.between(135, 180)) / 100
+		id, err := g.insert("parts", []string{"sku", "name", "description", "category", "cost", "price", "stock_quantity", "min_stock_level", "location", "active"},
+			fmt.Sprintf("DEMO-P-%04d", i+1), name, "Demo part", p.category, money(cost), money(price),
+			g.between(0, 40), g.between(2, 6), fmt.Sprintf("Aisle %d, Bin %02d", g.between(1, 8), g.between(1, 30)), 1)
+		if err != nil {
+			return err
+		}
+		g.parts = append(g.parts, part{id: id, name: name, price: price})
+	}
+	return nil
+}
+
+func (g *generator) seedCustomers(size int) ([]customer, error) {
+	customers := make([]customer, 0, size)
+	for i := 0; i < size; i++ {
+		first, last := g.pick(firstNames), g.pick(lastNames)
+		id, err := g.insert("customers", []string{"name", "email", "phone", "address", "city", "state", "zip", "country", "notes"},
+			first+" "+last, fmt.Sprintf("%s.%s.%d@demo.example", strings.ToLower(first), strings.ToLower(last), i+1),
+			fmt.Sprintf("(%03d) 555-01%02d", g.between(200, 999), g.rng.IntN(100)),
+			fmt.Sprintf("%d %s Way", g.between(1, 9899), g.pick(streets)), g.pick(cities), g.pick(states),
+			fmt.Sprintf("%05d", g.between(10000, 99999)), "US", "Demo customer")
+		if err != nil {
+			return nil, err
+		}
+		c := customer{id: id}
+
+		yachts := 0
+		switch r := g.rng.Float64(); {
+		case r < 0.15:
+			yachts = 2
+		case r < 0.85:
+			yachts = 1
+		}
+		for y := 0; y < yachts; y++ {
+			n := g.summary["yachts"] + 1
+			length := int64(g.between(2200, 6500)) // hundredths of a foot
+			yid, err := g.insert("yachts", []string{"customer_id", "name", "type", "description", "hull_identification_number", "flag", "length", "beam", "draft", "build_year"},
+				id, g.pick(yachtNames), g.pick(yachtTypes), "Demo vessel", fmt.Sprintf("DMO%05dD%03d", n, g.rng.IntN(1000)),
+				"US", money(length), money(length/3), money(length/12), g.between(1995, g.asOf.Year()))
+			if err != nil {
+				return nil, err
+			}
+			c.yachts = append(c.yachts, yid)
+		}
+		if g.chance(0.35) {
+			vid, err := g.seedVehicle(&id, "service")
+			if err != nil {
+				return nil, err
+			}
+			c.vehicles = append(c.vehicles, vid)
+		}
+		customers = append(customers, c)
+	}
+	return customers, nil
+}
+
+func (g *generator) seedVehicle(customerID *int64, status string) (int64, error) {
+	m := vehicleModels[g.rng.IntN(len(vehicleModels))]
+	n := g.summary["vehicles"] + 1
+	var owner any
+	if customerID != nil {
+		owner = *customerID
+	}
+	price := int64(g.between(25, 180)) * 100000
+	return g.insert("vehicles", []string{"customer_id", "vehicle_type", "year", "make", "model", "vin", "license_plate", "color", "mileage", "purchase_price", "status", "stock_number", "notes"},
+		owner, m.kind, g.between(2012, g.asOf.Year()), m.make, m.model, fmt.Sprintf("1DEMO%012d", n),
+		fmt.Sprintf("DMO-%04d", n), g.pick(colors), g.between(1000, 90000), money(price), status,
+		fmt.Sprintf("DEMO-S-%04d", n), "Demo vehicle")
+}
+
+func (g *generator) lineItems(vehicle bool) []lineItem {
+	var items []lineItem
+	for i := g.between(1, 4); i > 0; i-- {
+		if g.chance(0.55) {
+			s := g.services[g.rng.IntN(len(g.services))]
+			if vehicle != (s.item.category == "Vehicle") && g.chance(0.8) {
+				continue
+			}
+			hours := int64(max(1, s.item.minutes/60))
+			items = append(items, lineItem{kind: "service", partID: nil, serviceID: s.id, desc: s.item.name, qty: hours, unit: s.item.rate})
+		} else {
+			p := g.parts[g.rng.IntN(len(g.parts))]
+			items = append(items, lineItem{kind: "part", partID: p.id, serviceID: nil, desc: p.name, qty: int64(g.between(1, 4)), unit: p.price})
+		}
+	}
+	if len(items) == 0 {
+		s := g.services[0]
+		items = append(items, lineItem{kind: "service", partID: nil, serviceID: s.id, desc: s.item.name, qty: 4, unit: s.item.rate})
+	}
+	return items
+}
+
+// taxRate is the demo sales tax in hundredths of a percent.
+const taxRate = 700
+
+func totals(items []lineItem) (subtotal, tax, total int64) {
+	for _, it := range items {
+		subtotal += it.total()
+	}
+	tax = (subtotal*taxRate + 5000) / 10000
+	return subtotal, tax, subtotal + tax
+}
+
+func (g *generator) insertItems(table, parentColumn string, parentID int64, items []lineItem) error {
+	for i, it := range items {
+		if _, err := g.insert(table, []string{parentColumn, "item_type", "part_id", "service_id", "description", "quantity", "unit_price", "discount", "total", "sort_order"},
+			parentID, it.kind, it.partID, it.serviceID, it.desc, it.qty, money(it.unit), "0.00", money(it.total()), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// asset picks the customer's yacht or vehicle a document is about.
+func (g *generator) asset(c *customer) (yacht, vehicle any) {
+	if len(c.yachts) > 0 && (len(c.vehicles) == 0 || g.chance(0.7)) {
+		return c.yachts[g.rng.IntN(len(c.yachts))], nil
+	}
+	if len(c.vehicles) > 0 {
+		return nil, c.vehicles[g.rng.IntN(len(c.vehicles))]
+	}
+	return nil, nil
+}
+
+func (g *generator) seedCustomerActivity(c *customer) error {
+	for q := g.between(1, 2); q > 0; q-- {
+		yacht, vehicle := g.asset(c)
+		items := g.lineItems(vehicle != nil)
+		subtotal, tax, total := totals(items)
+		created := g.daysAgo(10, 200)
+		status := []string{"draft", "sent", "accepted", "accepted", "rejected", "expired"}[g.rng.IntN(6)]
+		quoteID, err := g.insert("quotes", []string{"quote_number", "customer_id", "yacht_id", "vehicle_id", "status", "expiration_date", "subtotal", "tax_rate", "tax_amount", "tax_name", "total", "notes"},
+			fmt.Sprintf("Q-DEMO-%05d", g.summary["quotes"]+1), c.id, yacht, vehicle, status, date(created.AddDate(0, 0, 30)),
+			money(subtotal), money(taxRate), money(tax), "Sales Tax", money(total), "Demo quote")
+		if err != nil {
+			return err
+		}
+		if err := g.insertItems("quote_items", "quote_id", quoteID, items); err != nil {
+			return err
+		}
+		if status == "accepted" {
+			if err := g.seedInvoice(c, quoteID, yacht, vehicle, items, created.AddDate(0, 0, g.between(3, 10))); err != nil {
+				return err
+			}
+		}
+	}
+	for i := g.between(0, 2); i > 0; i-- {
+		yacht, vehicle := g.asset(c)
+		if err := g.seedInvoice(c, nil, yacht, vehicle, g.lineItems(vehicle != nil), g.daysAgo(0, 180)); err != nil {
+			return err
+		}
+	}
+
+	for _, y := range c.yachts {
+		for i := g.between(1, 3); i > 0; i-- {
+			s := g.services[g.rng.IntN(len(g.services))]
+			start := g.asOf.AddDate(0, 0, g.between(-30, 30)).Add(time.Duration(g.between(8, 15)) * time.Hour)
+			status := "scheduled"
+			if start.Before(g.asOf) {
+				status = "completed"
+			}
+			if _, err := g.insert("appointments", []string{"customer_id", "yacht_id", "staff_id", "service_id", "title", "description", "start_time", "end_time", "status"},
+				c.id, y, g.staff[g.rng.IntN(len(g.staff))], s.id, s.item.name, "Demo appointment",
+				datetime(start), datetime(start.Add(time.Duration(s.item.minutes)*time.Minute)), status); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, v := range c.vehicles {
+		s := g.services[g.rng.IntN(len(g.services))]
+		status := []string{"open", "in_progress", "completed", "on_hold"}[g.rng.IntN(4)]
+		estimate := int64(s.item.minutes) * 100 / 60
+		var actual any
+		if status == "completed" {
+			actual = money(estimate + int64(g.between(-50, 100)))
+		}
+		if _, err := g.insert("work_orders", []string{"work_order_number", "customer_id", "vehicle_id", "key_tag_number", "assigned_to", "status", "priority", "title", "description", "estimated_hours", "actual_hours", "due_date"},
+			fmt.Sprintf("WO-DEMO-%05d", g.summary["work_orders"]+1), c.id, v, fmt.Sprintf("K%03d", g.between(1, 250)),
+			g.staff[g.rng.IntN(len(g.staff))], status, []string{"low", "normal", "normal", "high"}[g.rng.IntN(4)],
+			s.item.name, "Demo work order", money(estimate), actual, date(g.asOf.AddDate(0, 0, g.between(-5, 21)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedInvoice adds an invoice, its items, any payment and the journal
+// entries that post them.
+func (g *generator) seedInvoice(c *customer, quoteID, yacht, vehicle any, items []lineItem, issued time.Time) error {
+	if issued.After(g.asOf) {
+		issued = g.asOf
+	}
+	due := issued.AddDate(0, 0, 30)
+	subtotal, tax, total := totals(items)
+
+	status, paid := "sent", int64(0)
+	switch r := g.rng.Float64(); {
+	case r < 0.1:
+		status = "draft"
+	case r < 0.6:
+		status, paid = "paid", total
+	case r < 0.75:
+		paid = total * int64(g.between(20, 80)) / 100
+	}
+	if status == "sent" && due.Before(g.asOf) {
+		status = "overdue"
+	}
+
+	number := fmt.Sprintf("INV-DEMO-%05d", g.summary["invoices"]+1)
+	invoiceID, err := g.insert("invoices", []string{"invoice_number", "quote_id", "customer_id", "yacht_id", "vehicle_id", "status", "issue_date", "due_date", "subtotal", "tax_rate", "tax_amount", "tax_name", "total", "paid_amount", "balance", "notes"},
+		number, quoteID, c.id, yacht, vehicle, status, date(issued), date(due),
+		money(subtotal), money(taxRate), money(tax), "Sales Tax", money(total), money(paid), money(total-paid), "Demo invoice")
+	if err != nil {
+		return err
+	}
+	if err := g.insertItems("invoice_items", "invoice_id", invoiceID, items); err != nil {
+		return err
+	}
+	if status == "draft" {
+		return nil
+	}
+
+	var services, products int64
+	for _, it := range items {
+		if it.kind == "service" {
+			services += it.total()
+		} else {
+			products += it.total()
+		}
+	}
+	if err := g.journalEntry(issued, "Invoice "+number, number, []journalLine{
+		{g.accounts["1100"], total, 0},
+		{g.accounts["4000"], 0, services},
+		{g.accounts["4100"], 0, products},
+		{g.accounts["2200"], 0, tax},
+	}); err != nil {
+		return err
+	}
+
+	if paid == 0 {
+		return nil
+	}
+	paidAt := issued.AddDate(0, 0, g.between(0, 25))
+	if paidAt.After(g.asOf) {
+		paidAt = g.asOf
+	}
+	var method any
+	if len(g.methods) > 0 {
+		method = g.methods[g.rng.IntN(len(g.methods))]
+	}
+	if _, err := g.insert("payments", []string{"invoice_id", "payment_method_id", "payment_provider", "amount", "status", "notes", "processed_at"},
+		invoiceID, method, "offline", money(paid), "completed", "Demo payment", datetime(paidAt.Add(11*time.Hour))); err != nil {
+		return err
+	}
+	return g.journalEntry(paidAt, "Payment on "+number, number, []journalLine{
+		{g.accounts["1000"], paid, 0},
+		{g.accounts["1100"], 0, paid},
+	})
+}
+
+type journalLine struct {
+	account       int64
+	debit, credit int64
+}
+
+// journalEntry posts a balanced entry, skipping zero lines.
+func (g *generator) journalEntry(on time.Time, description, reference string, lines []journalLine) error {
+	var debits, credits int64
+	for _, l := range lines {
+		debits += l.debit
+		credits += l.credit
+	}
+	if debits != credits {
+		return fmt.Errorf("journal entry %q does not balance: debits %s, credits %s", description, money(debits), money(credits))
+	}
+	g.journal++
+	entryID, err := g.insert("journal_entries", []string{"entry_number", "entry_date", "description", "status", "created_by", "approved_by", "approved_at"},
+		fmt.Sprintf("JE-DEMO-%06d", g.journal), date(on), description, "posted", g.manager, g.manager, datetime(on.Add(17*time.Hour)))
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if l.debit == 0 && l.credit == 0 {
+			continue
+		}
+		if _, err := g.insert("journal_entry_lines", []string{"journal_entry_id", "account_id", "debit", "credit", "description", "reference"},
+			entryID, l.account, money(l.debit), money(l.credit), description, reference); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedTimeEntries clocks each technician in on the weekdays of the last two
+// weeks. Entries older than a week are approved.
+func (g *generator) seedTimeEntries() error {
+	for _, user := range g.staff {
+		for d := 14; d >= 1; d-- {
+			day := g.asOf.AddDate(0, 0, -d)
+			if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+				continue
+			}
+			in := day.Add(7*time.Hour + time.Duration(g.between(30, 75))*time.Minute)
+			out := in.Add(8*time.Hour + time.Duration(g.between(0, 90))*time.Minute)
+			status, approver, approvedAt := "pending", any(nil), any(nil)
+			if d > 7 {
+				status, approver, approvedAt = "approved", g.manager, datetime(day.AddDate(0, 0, 7))
+			}
+			if _, err := g.insert("time_entries", []string{"user_id", "clock_in", "clock_out", "break_minutes", "notes", "status", "approved_by", "approved_at"},
+				user, datetime(in), datetime(out), 30, "Demo shift", status, approver, approvedAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -1,11 +1,13 @@
 package detectors
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
 
 	"yachtcrm-installer/internal/powershell"
+	"yachtcrm-installer/internal/scheduler"
 )
 
 type Status string
@@ -158,6 +160,21 @@ func CheckPhpExtensions() DetectionResult {
 	return DetectionResult{Name: "Required PHP Extensions", Status: StatusMissing, Details: fmt.Sprintf("Missing: %s", strings.Join(missing, ", "))}
 }
 
+func CheckSchedulerTask(name string) DetectionResult {
+	status, err := scheduler.Query(name)
+	if errors.Is(err, scheduler.ErrNotFound) {
+		return DetectionResult{Name: "Laravel Scheduler Task", Status: StatusMissing, Details: fmt.Sprintf("Task %q not registered", name)}
+	}
+	if err != nil {
+		return DetectionResult{Name: "Laravel Scheduler Task", Status: StatusError, Details: err.Error()}
+	}
+	details := fmt.Sprintf("%s as %s, last run %s, last result 0x%X", status.State, status.RunAsUser, status.LastRunTime, status.LastResult)
+	if !status.Healthy() {
+		return DetectionResult{Name: "Laravel Scheduler Task", Status: StatusError, Details: details}
+	}
+	return DetectionResult{Name: "Laravel Scheduler Task", Status: StatusOK, Details: details}
+}
+
 func AllDetections() []DetectionResult {
 	checks := []func() DetectionResult{
 		CheckIISInstalled,
@@ -178,6 +195,14 @@ func AllDetections() []DetectionResult {
 	return results
 }
 
+// HealthChecks extends the prerequisite detections with checks for the
+// services a running installation depends on.
+func HealthChecks(schedulerTask string) []DetectionResult {
+	results := AllDetections()
+	results = append(results, CheckSchedulerTask(schedulerTask))
+	return results
+}
+
 func stderrOrError(res powershell.Result) string {
 	if strings.TrimSpace(res.Stderr) != "" {
 		return res.Stderr
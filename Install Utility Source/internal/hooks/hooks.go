@@ -0,0 +1,245 @@
+// Package hooks describes site-specific programs that run as extra install
+// steps, before or after a named install step.
+//
+// A hook receives the install settings as an Input JSON document on stdin.
+// The document leaves out passwords. Every line the hook writes to stdout
+// or stderr goes into the install log, except these status lines:
+//
+//	::warning::<message>   logs a warning; the install goes on
+//	::error::<message>     gives the reason for a failure
+//
+// The hook succeeds when it exits with status 0. Any other status fails
+// the install, with the ::error:: messages as the reason, unless the hook
+// is optional; then the failure is logged as a warning.
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is how long a hook may run when it does not set a
+// timeout.
+const DefaultTimeout = 30 * time.Minute
+
+// Hook is an external program run as an install step.
+type Hook struct {
+	// ID selects the hook like a step ID. It defaults to the placement
+	// and the command's file name without its extension, such as
+	// after-register-notify.
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	// Command is the program or script to run. .ps1 scripts run with
+	// PowerShell, .sh scripts with bash and .bat and .cmd scripts with
+	// cmd.exe; anything else is run directly.
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// Dir is the working directory; empty is the installer's.
+	Dir string `json:"dir"`
+	// Before or After is the ID of the install step the hook runs
+	// before or after. Exactly one of them is set.
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// Optional hooks log a failure as a warning instead of stopping the
+	// install.
+	Optional bool `json:"optional"`
+	// Timeout is a duration such as "10m"; empty is DefaultTimeout.
+	Timeout string `json:"timeout"`
+}
+
+// Check fills in the ID and reports whether the hook is complete.
+func (h *Hook) Check() error {
+	if h.Command == "" {
+		return fmt.Errorf("hook %q has no command", h.ID)
+	}
+	if (h.Before == "") == (h.After == "") {
+		name := h.ID
+		if name == "" {
+			name = h.Command
+		}
+		return fmt.Errorf("hook %s must set exactly one of before and after", name)
+	}
+	if h.ID == "" {
+		// The placement keeps hooks with the same file name in different
+		// hooks directories apart.
+		base := filepath.Base(h.Command)
+		h.ID = strings.ReplaceAll(h.Placement(), " ", "-") + "-" + strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	if _, err := h.timeout(); err != nil {
+		return fmt.Errorf("hook %s: %w", h.ID, err)
+	}
+	return nil
+}
+
+// Target is the ID of the step the hook is placed by, and whether it runs
+// after that step rather than before it.
+func (h Hook) Target() (id string, after bool) {
+	if h.After != "" {
+		return h.After, true
+	}
+	return h.Before, false
+}
+
+// Placement describes where the hook runs, e.g. "after register".
+func (h Hook) Placement() string {
+	id, after := h.Target()
+	if after {
+		return "after " + id
+	}
+	return "before " + id
+}
+
+// TimeLimit is how long the hook may run.
+func (h Hook) TimeLimit() time.Duration {
+	d, _ := h.timeout()
+	return d
+}
+
+func (h Hook) timeout() (time.Duration, error) {
+	if h.Timeout == "" {
+		return DefaultTimeout, nil
+	}
+	d, err := time.ParseDuration(h.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q", h.Timeout)
+	}
+	return d, nil
+}
+
+// CommandLine returns the program to start and its arguments, adding the
+// interpreter for scripts.
+func (h Hook) CommandLine() (string, []string) {
+	switch strings.ToLower(filepath.Ext(h.Command)) {
+	case ".ps1":
+		return "powershell.exe", append([]string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", h.Command}, h.Args...)
+	case ".sh":
+		return "bash", append([]string{h.Command}, h.Args...)
+	case ".bat", ".cmd":
+		return "cmd.exe", append([]string{"/c", h.Command}, h.Args...)
+	}
+	return h.Command, h.Args
+}
+
+// runnable lists the extensions Load picks up from a hooks directory.
+var runnable = []string{".ps1", ".sh", ".bat", ".cmd", ".exe"}
+
+// Load reads the hooks in dir. Hooks live in subdirectories named for their
+// placement, such as before-iis or after-register, and run in file name
+// order; other files are ignored. A missing dir has no hooks.
+func Load(dir string) ([]Hook, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read hooks directory: %w", err)
+	}
+	var hooks []Hook
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		when, target, ok := strings.Cut(entry.Name(), "-")
+		if !ok || (when != "before" && when != "after") || target == "" {
+			return nil, fmt.Errorf("hooks directory %s: expected before-<step ID> or after-<step ID>", entry.Name())
+		}
+		sub := filepath.Join(dir, entry.Name())
+		files, err := os.ReadDir(sub)
+		if err != nil {
+			return nil, fmt.Errorf("read hooks directory: %w", err)
+		}
+		for _, file := range files {
+			if file.IsDir() || !slices.Contains(runnable, strings.ToLower(filepath.Ext(file.Name()))) {
+				continue
+			}
+			h := Hook{Command: filepath.Join(sub, file.Name()), Dir: sub}
+			if when == "before" {
+				h.Before = target
+			} else {
+				h.After = target
+			}
+			if err := h.Check(); err != nil {
+				return nil, err
+			}
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks, nil
+}
+
+// Input is the JSON document a hook reads from stdin: the install settings
+// known when it starts, without passwords, and the install log so far.
+type Input struct {
+	Hook      string `json:"hook"`
+	Placement string `json:"placement"`
+
+	InstanceName string `json:"instance_name"`
+	RuntimeDir   string `json:"runtime_dir"`
+	CRMSourceDir string `json:"crm_source_dir"`
+
+	ExternalDatabase bool   `json:"external_database"`
+	DatabaseHost     string `json:"database_host"`
+	DatabasePort     int    `json:"database_port"`
+	DatabaseName     string `json:"database_name"`
+	DatabaseUser     string `json:"database_user"`
+
+	AdminName  string `json:"admin_name"`
+	AdminEmail string `json:"admin_email"`
+
+	PhpInstallDir string `json:"php_install_dir"`
+	PhpIniPath    string `json:"php_ini_path"`
+	PhpExePath    string `json:"php_exe_path"`
+	ComposerPath  string `json:"composer_path"`
+	NodeBinDir    string `json:"node_bin_dir"`
+	MariaDBBinDir string `json:"mariadb_bin_dir"`
+	PhpMyAdminDir string `json:"phpmyadmin_dir"`
+
+	AppURL      string `json:"app_url"`
+	FrontendURL string `json:"frontend_url"`
+	SiteName    string `json:"site_name"`
+	AppPoolName string `json:"app_pool_name"`
+	HostHeader  string `json:"host_header"`
+	HTTPPort    int    `json:"http_port"`
+
+	SchedulerTaskName  string `json:"scheduler_task_name"`
+	SchedulerRunAsUser string `json:"scheduler_run_as_user"`
+	WorkerTaskName     string `json:"worker_task_name"`
+
+	EnabledModules []string `json:"enabled_modules"`
+	CRMName        string   `json:"crm_name"`
+
+	// Log is the install log up to the hook, e.g. for a report.
+	Log []string `json:"log"`
+}
+
+// Encode returns in as the hook's stdin.
+func (in Input) Encode() ([]byte, error) {
+	return json.MarshalIndent(in, "", "  ")
+}
+
+// LineKind says what a line of hook output is.
+type LineKind int
+
+const (
+	Log LineKind = iota
+	Warning
+	Error
+)
+
+// ParseLine splits a status line into its kind and message. Other lines are
+// Log lines and are returned unchanged.
+func ParseLine(line string) (LineKind, string) {
+	trimmed := strings.TrimSpace(line)
+	if msg, ok := strings.CutPrefix(trimmed, "::warning::"); ok {
+		return Warning, strings.TrimSpace(msg)
+	}
+	if msg, ok := strings.CutPrefix(trimmed, "::error::"); ok {
+		return Error, strings.TrimSpace(msg)
+	}
+	return Log, line
+}
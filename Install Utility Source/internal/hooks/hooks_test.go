@@ -0,0 +1,113 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		hook Hook
+		id   string
+		err  string
+	}{
+		{hook: Hook{Command: "hooks/after-register/notify.ps1", After: "register"}, id: "after-register-notify"},
+		{hook: Hook{Command: "/opt/hooks/scan.sh", Before: "iis"}, id: "before-iis-scan"},
+		{hook: Hook{ID: "monitoring", Command: "agent.exe", After: "queue-worker", Timeout: "5m"}, id: "monitoring"},
+		{hook: Hook{ID: "monitoring", After: "iis"}, err: `hook "monitoring" has no command`},
+		{hook: Hook{Command: "agent.exe"}, err: "hook agent.exe must set exactly one of before and after"},
+		{hook: Hook{ID: "both", Command: "agent.exe", Before: "iis", After: "iis"}, err: "hook both must set exactly one of before and after"},
+		{hook: Hook{Command: "agent.exe", After: "iis", Timeout: "soon"}, err: `hook after-iis-agent: invalid timeout "soon"`},
+		{hook: Hook{Command: "agent.exe", After: "iis", Timeout: "-1m"}, err: `hook after-iis-agent: invalid timeout "-1m"`},
+	}
+	for _, tt := range tests {
+		h := tt.hook
+		err := h.Check()
+		switch {
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("Check(%+v): error %v, want %q", tt.hook, err, tt.err)
+		case tt.err == "" && err != nil:
+			t.Errorf("Check(%+v): %v", tt.hook, err)
+		case tt.err == "" && h.ID != tt.id:
+			t.Errorf("Check(%+v): ID %q, want %q", tt.hook, h.ID, tt.id)
+		}
+	}
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"README.txt",
+		"after-register/notify.ps1",
+		"after-register/50-push-report.PS1",
+		"before-iis/20-scan.sh",
+		"before-iis/10-exclusions.bat",
+		"before-iis/notify.ps1",
+		"before-iis/notes.txt",
+		"before-iis/lib/",
+		"before-iis/tool.exe",
+		"before-iis/run.cmd",
+	)
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ id, placement, file string }{
+		{"after-register-50-push-report", "after register", "after-register/50-push-report.PS1"},
+		{"after-register-notify", "after register", "after-register/notify.ps1"},
+		{"before-iis-10-exclusions", "before iis", "before-iis/10-exclusions.bat"},
+		{"before-iis-20-scan", "before iis", "before-iis/20-scan.sh"},
+		{"before-iis-notify", "before iis", "before-iis/notify.ps1"},
+		{"before-iis-run", "before iis", "before-iis/run.cmd"},
+		{"before-iis-tool", "before iis", "before-iis/tool.exe"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("loaded %d hooks, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		h := got[i]
+		command := filepath.Join(dir, filepath.FromSlash(w.file))
+		if h.ID != w.id || h.Placement() != w.placement || h.Command != command || h.Dir != filepath.Dir(command) {
+			t.Errorf("hook %d = %s %s %s in %s, want %s %s %s", i, h.ID, h.Placement(), h.Command, h.Dir, w.id, w.placement, command)
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	hooks, err := Load(filepath.Join(t.TempDir(), "hooks"))
+	if hooks != nil || err != nil {
+		t.Errorf("Load of a missing directory = %v, %v, want no hooks", hooks, err)
+	}
+}
+
+func TestLoadRejectsUnknownDirectories(t *testing.T) {
+	for _, name := range []string{"during-iis", "before-", "after", "iis"} {
+		dir := t.TempDir()
+		writeFiles(t, dir, name+"/notify.ps1")
+		if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "hooks directory "+name+":") {
+			t.Errorf("directory %s: error %v, want it rejected", name, err)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+// Package ini edits INI files such as php.ini and my.ini in place. Changes
+// touch only the lines they need to; everything else, including comments,
+// blank lines, spacing and line endings, is written back unchanged.
+package ini
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// File is a parsed INI file.
+type File struct {
+	// Fold maps keys to the form they are compared in; nil compares them
+	// as written. See MySQLKey.
+	Fold func(string) string
+
+	lines []line
+}
+
+// Entry is a key assignment, or a commented-out one such as a default in a
+// sample configuration.
+type Entry struct {
+	// Section is the section name as written; empty before the first
+	// section header.
+	Section   string
+	Key       string
+	Value     string
+	Commented bool
+}
+
+type lineKind int
+
+const (
+	other lineKind = iota
+	blank
+	comment
+	header
+	entry
+	commentedEntry
+)
+
+type line struct {
+	text string // without the line ending
+	eol  string // "\n", "\r\n" or "" for a last line without one
+
+	kind    lineKind
+	section string
+	key     string
+	// value is the value span of an entry in text; start == -1 for a key
+	// without a value.
+	start, end int
+	// commentStart and keyStart bound the comment markers of a commented
+	// entry, so it can be uncommented.
+	commentStart, keyStart int
+}
+
+// MySQLKey folds MySQL and MariaDB option names, which ignore case and
+// treat dashes and underscores alike.
+func MySQLKey(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+}
+
+// Parse reads an INI file. It does not fail: lines it does not recognize
+// are kept as they are.
+func Parse(data []byte) *File {
+	f := &File{}
+	f.parse(string(data))
+	return f
+}
+
+// Bytes returns the file's contents.
+func (f *File) Bytes() []byte {
+	var b strings.Builder
+	for _, l := range f.lines {
+		b.WriteString(l.text)
+		b.WriteString(l.eol)
+	}
+	return []byte(b.String())
+}
+
+func (f *File) parse(text string) {
+	f.lines = f.lines[:0]
+	section := ""
+	for text != "" {
+		raw := text
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			raw = text[:i+1]
+		}
+		text = text[len(raw):]
+		content := strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+		l := parseLine(content, section)
+		l.eol = raw[len(content):]
+		if l.kind == header {
+			section = l.section
+		}
+		f.lines = append(f.lines, l)
+	}
+}
+
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+func parseLine(text, section string) line {
+	l := line{text: text, section: section, start: -1, end: -1}
+	lead := len(text) - len(strings.TrimLeft(text, " \t"))
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case trimmed == "":
+		l.kind = blank
+	case trimmed[0] == '[':
+		end := strings.IndexByte(trimmed, ']')
+		if end < 0 {
+			return l
+		}
+		l.kind = header
+		l.section = strings.TrimSpace(trimmed[1:end])
+	case trimmed[0] == ';' || trimmed[0] == '#':
+		l.kind = comment
+		p := lead
+		for p < len(text) && (text[p] == ';' || text[p] == '#') {
+			p++
+		}
+		for p < len(text) && (text[p] == ' ' || text[p] == '\t') {
+			p++
+		}
+		// Only key = value lines count as commented entries; a bare word
+		// is more likely prose.
+		if parseEntry(&l, text, p) && l.start >= 0 {
+			l.kind = commentedEntry
+			l.commentStart, l.keyStart = lead, p
+		} else {
+			l.key, l.start, l.end = "", -1, -1
+		}
+	default:
+		if parseEntry(&l, text, lead) {
+			l.kind = entry
+		}
+	}
+	return l
+}
+
+// parseEntry reads "key = value" or a bare key from text[from:].
+func parseEntry(l *line, text string, from int) bool {
+	rest := text[from:]
+	eq := strings.IndexByte(rest, '=')
+	if eq < 0 {
+		key := strings.TrimSpace(stripComment(rest))
+		if !validKey.MatchString(key) {
+			return false
+		}
+		l.key = key
+		return true
+	}
+	key := strings.TrimSpace(rest[:eq])
+	if !validKey.MatchString(key) {
+		return false
+	}
+	l.key = key
+	start := from + eq + 1
+	for start < len(text) && (text[start] == ' ' || text[start] == '\t') {
+		start++
+	}
+	end := start
+	switch {
+	case start == len(text) || text[start] == ';' || text[start] == '#':
+		// No value, perhaps followed by a comment.
+	case text[start] == '"':
+		if close := strings.IndexByte(text[start+1:], '"'); close >= 0 {
+			end = start + close + 2
+		} else {
+			end = len(text)
+		}
+	default:
+		end = start + len(strings.TrimRight(stripComment(text[start:]), " \t"))
+	}
+	l.start, l.end = start, end
+	return true
+}
+
+// stripComment cuts an inline comment: ; or # after whitespace.
+func stripComment(s string) string {
+	for i := 1; i < len(s); i++ {
+		if (s[i] == ';' || s[i] == '#') && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func (l line) value() string {
+	if l.start < 0 {
+		return ""
+	}
+	v := l.text[l.start:l.end]
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	return v
+}
+
+func (f *File) fold(key string) string {
+	if f.Fold == nil {
+		return key
+	}
+	return f.Fold(key)
+}
+
+func (f *File) matches(l line, section, key string) bool {
+	return strings.EqualFold(l.section, section) && f.fold(l.key) == f.fold(key)
+}
+
+// Entries lists the assignments in the file, commented or not, in order.
+func (f *File) Entries() []Entry {
+	var entries []Entry
+	for _, l := range f.lines {
+		if l.kind == entry || l.kind == commentedEntry {
+			entries = append(entries, Entry{Section: l.section, Key: l.key, Value: l.value(), Commented: l.kind == commentedEntry})
+		}
+	}
+	return entries
+}
+
+// Sections lists the section names in order, as written.
+func (f *File) Sections() []string {
+	var names []string
+	for _, l := range f.lines {
+		if l.kind == header {
+			names = append(names, l.section)
+		}
+	}
+	return names
+}
+
+// Get returns the value of the last assignment to key in section, the one
+// that takes effect. Section names are compared ignoring case; "" is the
+// part of the file before the first section header.
+func (f *File) Get(section, key string) (string, bool) {
+	if i := f.last(entry, section, key, nil); i >= 0 {
+		return f.lines[i].value(), true
+	}
+	return "", false
+}
+
+// Set makes key take value in section and reports whether the file
+// changed. It changes the value of the last assignment to key; without
+// one, it adds an assignment after the last commented-out one, or else at
+// the end of the section, adding the section when it is missing.
+func (f *File) Set(section, key, value string) (bool, error) {
+	if err := checkValue(key, value); err != nil {
+		return false, err
+	}
+	if i := f.last(entry, section, key, nil); i >= 0 {
+		l := f.lines[i]
+		if l.value() == value {
+			return false, nil
+		}
+		switch {
+		case l.start < 0:
+			// A bare key, such as skip-networking.
+			at := len(l.text) - len(strings.TrimLeft(l.text, " \t")) + len(l.key)
+			f.replace(i, l.text[:at]+f.separator()+quote(value)+l.text[at:])
+		case l.start == l.end:
+			// "key =", perhaps followed by a comment. The value is spaced
+			// like the key: "key = value" or "key=value".
+			before, after := l.text[:l.start], l.text[l.end:]
+			if strings.HasSuffix(before, " =") || strings.HasSuffix(before, "\t=") {
+				before += " "
+			}
+			if after != "" {
+				after = " " + after
+			}
+			f.replace(i, before+quote(value)+after)
+		default:
+			f.replace(i, l.text[:l.start]+quote(value)+l.text[l.end:])
+		}
+		return true, nil
+	}
+	if i := f.last(commentedEntry, section, key, nil); i >= 0 {
+		f.insert(i+1, key+f.separator()+quote(value))
+		return true, nil
+	}
+	f.insertInSection(section, key+f.separator()+quote(value))
+	return true, nil
+}
+
+// Enable makes sure key = value is assigned in section, for keys that are
+// assigned more than once, such as PHP's extension. It reports whether the
+// file changed. It uncomments a commented-out key = value, or else adds
+// one after the key's last assignment.
+func (f *File) Enable(section, key, value string) (bool, error) {
+	if err := checkValue(key, value); err != nil {
+		return false, err
+	}
+	same := func(l line) bool { return strings.EqualFold(l.value(), value) }
+	if f.last(entry, section, key, same) >= 0 {
+		return false, nil
+	}
+	if i := f.last(commentedEntry, section, key, same); i >= 0 {
+		l := f.lines[i]
+		f.replace(i, l.text[:l.commentStart]+l.text[l.keyStart:])
+		return true, nil
+	}
+	for _, kind := range []lineKind{entry, commentedEntry} {
+		if i := f.last(kind, section, key, nil); i >= 0 {
+			f.insert(i+1, key+"="+quote(value))
+			return true, nil
+		}
+	}
+	f.insertInSection(section, key+"="+quote(value))
+	return true, nil
+}
+
+// last returns the index of the last line of kind assigning key in
+// section that also satisfies match, or -1.
+func (f *File) last(kind lineKind, section, key string, match func(line) bool) int {
+	for i := len(f.lines) - 1; i >= 0; i-- {
+		l := f.lines[i]
+		if l.kind == kind && f.matches(l, section, key) && (match == nil || match(l)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertInSection adds text after the last non-blank line of section, or
+// in a new section at the end of the file.
+func (f *File) insertInSection(section, text string) {
+	at, found := -1, section == ""
+	for i, l := range f.lines {
+		if l.kind == header && strings.EqualFold(l.section, section) {
+			at, found = i+1, true
+		} else if strings.EqualFold(l.section, section) && found && l.kind != blank {
+			at = i + 1
+		}
+	}
+	if !found {
+		n := len(f.lines)
+		if n > 0 && f.lines[n-1].kind != blank {
+			f.insert(n, "", "["+section+"]", text)
+		} else {
+			f.insert(n, "["+section+"]", text)
+		}
+		return
+	}
+	if at < 0 {
+		at = 0
+	}
+	f.insert(at, text)
+}
+
+// insert adds lines before index i, ending them like the file's other
+// lines. Only the last line of a file can lack an ending; lines added after
+// it take that over.
+func (f *File) insert(i int, texts ...string) {
+	eol := f.newline()
+	added := make([]line, len(texts))
+	for j, text := range texts {
+		added[j] = parseLine(text, "")
+		added[j].eol = eol
+	}
+	if i > 0 && f.lines[i-1].eol == "" {
+		f.lines[i-1].eol = eol
+		added[len(added)-1].eol = ""
+	}
+	f.lines = append(f.lines[:i], append(added, f.lines[i:]...)...)
+	f.reparse()
+}
+
+func (f *File) replace(i int, text string) {
+	f.lines[i].text = text
+	f.reparse()
+}
+
+// reparse refreshes the parsed state after a change.
+func (f *File) reparse() {
+	f.parse(string(f.Bytes()))
+}
+
+// newline is the file's line ending.
+func (f *File) newline() string {
+	for _, l := range f.lines {
+		if l.eol != "" {
+			return l.eol
+		}
+	}
+	return "\n"
+}
+
+// separator follows the file's first assignment: "key = value" or
+// "key=value".
+func (f *File) separator() string {
+	for _, l := range f.lines {
+		if l.kind == entry && l.start >= 0 {
+			eq := strings.IndexByte(l.text, '=')
+			if eq > 0 && (l.text[eq-1] == ' ' || l.text[eq-1] == '\t') {
+				return " = "
+			}
+			return "="
+		}
+	}
+	return " = "
+}
+
+// checkValue rejects values an INI file cannot hold. PHP has no escape
+// for a double quote inside a quoted value.
+func checkValue(key, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("value for %s must be a single line", key)
+	}
+	if strings.Contains(value, `"`) {
+		return fmt.Errorf("value for %s must not contain a double quote", key)
+	}
+	return nil
+}
+
+// quote wraps values that would otherwise be cut at a comment marker or
+// lose surrounding spaces.
+func quote(value string) string {
+	if strings.ContainsAny(value, ";#") || strings.TrimSpace(value) != value {
+		return `"` + value + `"`
+	}
+	return value
+}
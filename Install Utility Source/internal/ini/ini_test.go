@@ -0,0 +1,390 @@
+package ini
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// readFixture loads a sample from testdata with its line endings changed to
+// eol.
+func readFixture(t *testing.T, name, eol string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\n"), []byte(eol))
+}
+
+// diffLines splits before and after into lines, endings included, and
+// returns the lines between their longest common prefix and suffix.
+// Everything outside those lines is byte for byte the same.
+func diffLines(before, after []byte) (removed, added []string) {
+	a, b := bytes.SplitAfter(before, []byte("\n")), bytes.SplitAfter(after, []byte("\n"))
+	p := 0
+	for p < len(a) && p < len(b) && bytes.Equal(a[p], b[p]) {
+		p++
+	}
+	s := 0
+	for s < len(a)-p && s < len(b)-p && bytes.Equal(a[len(a)-1-s], b[len(b)-1-s]) {
+		s++
+	}
+	for _, l := range a[p : len(a)-s] {
+		removed = append(removed, string(l))
+	}
+	for _, l := range b[p : len(b)-s] {
+		added = append(added, string(l))
+	}
+	return removed, added
+}
+
+// withEOL changes the "\n" endings of want to eol.
+func withEOL(want []string, eol string) []string {
+	var out []string
+	for _, l := range want {
+		out = append(out, strings.ReplaceAll(l, "\n", eol))
+	}
+	return out
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"php.ini-production", "my.ini", "my-medium.ini", "my-named-pipe.ini"} {
+		for _, eol := range []string{"\n", "\r\n"} {
+			data := readFixture(t, name, eol)
+			for _, in := range [][]byte{data, bytes.TrimSuffix(data, []byte(eol))} {
+				if out := Parse(in).Bytes(); !bytes.Equal(out, in) {
+					t.Errorf("%s (eol %q): Bytes() differs from the parsed input", name, eol)
+				}
+			}
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		file     string
+		fold     bool
+		section  string
+		key      string
+		want     string
+		wantFind bool
+	}{
+		{"php.ini-production", false, "PHP", "memory_limit", "128M", true},
+		{"php.ini-production", false, "php", "memory_limit", "128M", true},
+		{"php.ini-production", false, "PHP", "error_reporting", "E_ALL & ~E_DEPRECATED & ~E_STRICT", true},
+		{"php.ini-production", false, "PHP", "default_charset", "UTF-8", true},
+		{"php.ini-production", false, "PHP", "max_input_vars", "", false},
+		{"php.ini-production", false, "PHP", "extension", "", false},
+		{"php.ini-production", false, "PHP", "include_path", "", false},
+		{"php.ini-production", false, "Date", "date.timezone", "", false},
+		{"php.ini-production", false, "Pdo_mysql", "pdo_mysql.default_socket", "", true},
+		{"php.ini-production", false, "mail function", "SMTP", "localhost", true},
+		{"php.ini-production", false, "Session", "session.cookie_httponly", "", true},
+		{"php.ini-production", false, "Session", "session.trans_sid_tags", "a=href,area=href,frame=src,form=", true},
+		{"php.ini-production", false, "Session", "memory_limit", "", false},
+		{"my.ini", true, "mysqld", "character_set_server", "utf8mb4", true},
+		{"my.ini", true, "mysqld", "Innodb-Buffer-Pool-Size", "2039M", true},
+		{"my.ini", true, "client", "plugin_dir", `C:\Program Files/MariaDB 11.4/lib/plugin`, true},
+		{"my.ini", false, "mysqld", "character_set_server", "", false},
+		{"my.ini", true, "mysqld", "plugin-dir", "", false},
+		{"my-medium.ini", true, "client", "port", "3306", true},
+		{"my-medium.ini", true, "client", "password", "", false},
+		{"my-medium.ini", true, "mysqld", "skip_external_locking", "", true},
+		{"my-medium.ini", true, "mysqld", "skip-networking", "", false},
+		{"my-medium.ini", true, "mysqld", "server_id", "1", true},
+		{"my-named-pipe.ini", true, "mysqld", "skip_networking", "", true},
+	}
+	for _, tt := range tests {
+		f := Parse(readFixture(t, tt.file, "\n"))
+		if tt.fold {
+			f.Fold = MySQLKey
+		}
+		got, ok := f.Get(tt.section, tt.key)
+		if got != tt.want || ok != tt.wantFind {
+			t.Errorf("%s: Get(%q, %q) = %q, %v, want %q, %v", tt.file, tt.section, tt.key, got, ok, tt.want, tt.wantFind)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	set := func(section, key, value string) func(*File) (bool, error) {
+		return func(f *File) (bool, error) { return f.Set(section, key, value) }
+	}
+	enable := func(section, key, value string) func(*File) (bool, error) {
+		return func(f *File) (bool, error) { return f.Enable(section, key, value) }
+	}
+	tests := []struct {
+		name    string
+		file    string
+		fold    bool
+		edit    func(*File) (bool, error)
+		removed []string
+		added   []string
+	}{
+		{
+			name:    "replace a value",
+			file:    "php.ini-production",
+			edit:    set("PHP", "memory_limit", "512M"),
+			removed: []string{"memory_limit = 128M\n"},
+			added:   []string{"memory_limit = 512M\n"},
+		},
+		{
+			name:    "replace a value with spaces",
+			file:    "php.ini-production",
+			edit:    set("PHP", "error_reporting", "E_ALL"),
+			removed: []string{"error_reporting = E_ALL & ~E_DEPRECATED & ~E_STRICT\n"},
+			added:   []string{"error_reporting = E_ALL\n"},
+		},
+		{
+			name:    "replace a quoted value",
+			file:    "php.ini-production",
+			edit:    set("Session", "session.trans_sid_tags", "a=href"),
+			removed: []string{`session.trans_sid_tags = "a=href,area=href,frame=src,form="` + "\n"},
+			added:   []string{"session.trans_sid_tags = a=href\n"},
+		},
+		{
+			name: "unchanged value",
+			file: "php.ini-production",
+			edit: set("php", "max_execution_time", "30"),
+		},
+		{
+			name:  "add after a commented-out default",
+			file:  "php.ini-production",
+			edit:  set("PHP", "max_input_vars", "5000"),
+			added: []string{"max_input_vars = 5000\n"},
+		},
+		{
+			name:  "add after a commented-out empty default",
+			file:  "php.ini-production",
+			edit:  set("Date", "date.timezone", "Europe/London"),
+			added: []string{"date.timezone = Europe/London\n"},
+		},
+		{
+			name:  "quote a value with a comment marker",
+			file:  "php.ini-production",
+			edit:  set("PHP", "include_path", `.;C:\php\includes`),
+			added: []string{`include_path = ".;C:\php\includes"` + "\n"},
+		},
+		{
+			name:    "fill an empty value",
+			file:    "php.ini-production",
+			edit:    set("Session", "session.cookie_httponly", "1"),
+			removed: []string{"session.cookie_httponly =\n"},
+			added:   []string{"session.cookie_httponly = 1\n"},
+		},
+		{
+			name:    "fill an empty value without spaces",
+			file:    "php.ini-production",
+			edit:    set("Pdo_mysql", "pdo_mysql.default_socket", "MySQL"),
+			removed: []string{"pdo_mysql.default_socket=\n"},
+			added:   []string{"pdo_mysql.default_socket=MySQL\n"},
+		},
+		{
+			name:  "add a missing section",
+			file:  "php.ini-production",
+			edit:  set("xdebug", "xdebug.mode", "debug"),
+			added: []string{"\n", "[xdebug]\n", "xdebug.mode = debug\n"},
+		},
+		{
+			name:    "uncomment an extension",
+			file:    "php.ini-production",
+			edit:    enable("PHP", "extension", "pdo_mysql"),
+			removed: []string{";extension=pdo_mysql\n"},
+			added:   []string{"extension=pdo_mysql\n"},
+		},
+		{
+			name:    "uncomment an extension keeping its comment",
+			file:    "php.ini-production",
+			edit:    enable("PHP", "extension", "exif"),
+			removed: []string{";extension=exif      ; Must be after mbstring as it depends on it\n"},
+			added:   []string{"extension=exif      ; Must be after mbstring as it depends on it\n"},
+		},
+		{
+			name:    "uncomment a zend extension",
+			file:    "php.ini-production",
+			edit:    enable("PHP", "zend_extension", "opcache"),
+			removed: []string{";zend_extension=opcache\n"},
+			added:   []string{"zend_extension=opcache\n"},
+		},
+		{
+			name:  "add an extension after the others",
+			file:  "php.ini-production",
+			edit:  enable("PHP", "extension", "redis"),
+			added: []string{"extension=redis\n"},
+		},
+		{
+			name:    "fold dashes and underscores",
+			file:    "my.ini",
+			fold:    true,
+			edit:    set("mysqld", "innodb-buffer-pool-size", "1G"),
+			removed: []string{"innodb_buffer_pool_size=2039M\n"},
+			added:   []string{"innodb_buffer_pool_size=1G\n"},
+		},
+		{
+			name: "fold an unchanged value",
+			file: "my.ini",
+			fold: true,
+			edit: set("MYSQLD", "character_set_server", "utf8mb4"),
+		},
+		{
+			name:  "add to the end of a section",
+			file:  "my.ini",
+			fold:  true,
+			edit:  set("mysqld", "max_allowed_packet", "64M"),
+			added: []string{"max_allowed_packet=64M\n"},
+		},
+		{
+			name:  "add to the last section",
+			file:  "my.ini",
+			fold:  true,
+			edit:  set("client", "default-character-set", "utf8mb4"),
+			added: []string{"default-character-set=utf8mb4\n"},
+		},
+		{
+			name:  "add a missing my.ini section",
+			file:  "my.ini",
+			fold:  true,
+			edit:  set("mysql", "default-character-set", "utf8mb4"),
+			added: []string{"\n", "[mysql]\n", "default-character-set=utf8mb4\n"},
+		},
+		{
+			name:    "give a bare key a value",
+			file:    "my-named-pipe.ini",
+			fold:    true,
+			edit:    set("mysqld", "skip_networking", "0"),
+			removed: []string{"skip-networking\n"},
+			added:   []string{"skip-networking=0\n"},
+		},
+		{
+			name:    "give a bare key a value in a tab-aligned file",
+			file:    "my-medium.ini",
+			fold:    true,
+			edit:    set("mysqld", "skip_external_locking", "OFF"),
+			removed: []string{"skip-external-locking\n"},
+			added:   []string{"skip-external-locking = OFF\n"},
+		},
+		{
+			name:    "replace a tab-aligned value",
+			file:    "my-medium.ini",
+			fold:    true,
+			edit:    set("client", "port", "3307"),
+			removed: []string{"port\t\t= 3306\n"},
+			added:   []string{"port\t\t= 3307\n"},
+		},
+		{
+			name:  "add after a hash-commented default",
+			file:  "my-medium.ini",
+			fold:  true,
+			edit:  set("mysqld", "innodb_buffer_pool_size", "256M"),
+			added: []string{"innodb_buffer_pool_size = 256M\n"},
+		},
+	}
+	for _, tt := range tests {
+		for _, eol := range []string{"\n", "\r\n"} {
+			before := readFixture(t, tt.file, eol)
+			f := Parse(before)
+			if tt.fold {
+				f.Fold = MySQLKey
+			}
+			changed, err := tt.edit(f)
+			if err != nil {
+				t.Errorf("%s (eol %q): %v", tt.name, eol, err)
+				continue
+			}
+			after := f.Bytes()
+			if want := len(tt.added) > 0 || len(tt.removed) > 0; changed != want {
+				t.Errorf("%s (eol %q): changed = %v, want %v", tt.name, eol, changed, want)
+			}
+			removed, added := diffLines(before, after)
+			if !slices.Equal(removed, withEOL(tt.removed, eol)) || !slices.Equal(added, withEOL(tt.added, eol)) {
+				t.Errorf("%s (eol %q): removed %q and added %q, want removed %q and added %q",
+					tt.name, eol, removed, added, withEOL(tt.removed, eol), withEOL(tt.added, eol))
+			}
+			// A second edit finds the first one in place.
+			again := Parse(after)
+			if tt.fold {
+				again.Fold = MySQLKey
+			}
+			if changed, err := tt.edit(again); err != nil || changed {
+				t.Errorf("%s (eol %q): repeated edit = %v, %v, want no change", tt.name, eol, changed, err)
+			}
+		}
+	}
+}
+
+func TestEditWithoutTrailingNewline(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*File) (bool, error)
+		removed []string
+		added   []string
+	}{
+		{
+			name:    "replace the last line",
+			edit:    func(f *File) (bool, error) { return f.Set("client", "plugin_dir", "D:/plugins") },
+			removed: []string{`plugin-dir=C:\Program Files/MariaDB 11.4/lib/plugin`},
+			added:   []string{"plugin-dir=D:/plugins"},
+		},
+		{
+			name:    "add after the last line",
+			edit:    func(f *File) (bool, error) { return f.Set("client", "default-character-set", "utf8mb4") },
+			removed: []string{`plugin-dir=C:\Program Files/MariaDB 11.4/lib/plugin`},
+			added:   []string{`plugin-dir=C:\Program Files/MariaDB 11.4/lib/plugin` + "\r\n", "default-character-set=utf8mb4"},
+		},
+		{
+			name:    "add a section after the last line",
+			edit:    func(f *File) (bool, error) { return f.Set("mysql", "default-character-set", "utf8mb4") },
+			removed: []string{`plugin-dir=C:\Program Files/MariaDB 11.4/lib/plugin`},
+			added:   []string{`plugin-dir=C:\Program Files/MariaDB 11.4/lib/plugin` + "\r\n", "\r\n", "[mysql]\r\n", "default-character-set=utf8mb4"},
+		},
+	}
+	for _, tt := range tests {
+		before := bytes.TrimSuffix(readFixture(t, "my.ini", "\r\n"), []byte("\r\n"))
+		f := Parse(before)
+		f.Fold = MySQLKey
+		if _, err := tt.edit(f); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		removed, added := diffLines(before, f.Bytes())
+		if !slices.Equal(removed, tt.removed) || !slices.Equal(added, tt.added) {
+			t.Errorf("%s: removed %q and added %q, want removed %q and added %q", tt.name, removed, added, tt.removed, tt.added)
+		}
+	}
+}
+
+func TestEmptyValueBeforeComment(t *testing.T) {
+	before := []byte("[PHP]\nmemory_limit = 128M\nopen_basedir = ; restrict file access\n")
+	f := Parse(before)
+	if got, ok := f.Get("PHP", "open_basedir"); got != "" || !ok {
+		t.Fatalf("Get = %q, %v, want an empty value", got, ok)
+	}
+	if _, err := f.Set("PHP", "open_basedir", `C:\inetpub`); err != nil {
+		t.Fatal(err)
+	}
+	removed, added := diffLines(before, f.Bytes())
+	if want := []string{"open_basedir = C:\\inetpub ; restrict file access\n"}; len(removed) != 1 || !slices.Equal(added, want) {
+		t.Errorf("removed %q and added %q, want added %q", removed, added, want)
+	}
+}
+
+func TestRejectsUnwritableValues(t *testing.T) {
+	for _, value := range []string{`say "hi"`, "two\nlines", "cr\r"} {
+		before := readFixture(t, "php.ini-production", "\n")
+		f := Parse(before)
+		if _, err := f.Set("PHP", "memory_limit", value); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", value)
+		}
+		if _, err := f.Enable("PHP", "extension", value); err == nil {
+			t.Errorf("Enable(%q) succeeded, want an error", value)
+		}
+		if !bytes.Equal(f.Bytes(), before) {
+			t.Errorf("rejected value %q changed the file", value)
+		}
+	}
+}
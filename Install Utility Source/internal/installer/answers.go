@@ -0,0 +1,52 @@
+package installer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"yachtcrm-installer/internal/branding"
+	"yachtcrm-installer/internal/hooks"
+	"yachtcrm-installer/internal/phpconfig"
+)
+
+// Answers are install settings read from a JSON file instead of being
+// prompted for. Absent fields are still prompted for.
+type Answers struct {
+	// Modules lists the product modules to enable; other modules are
+	// disabled. An empty list disables all of them.
+	Modules []string `json:"modules"`
+	// Branding is the CRM name, company profile and logo files.
+	Branding *branding.Profile `json:"branding"`
+	// Inputs answers the install questions by key, e.g. "runtime_dir" or
+	// "admin_email". Values are checked like typed answers; blank takes
+	// the question's default.
+	Inputs map[string]string `json:"inputs"`
+	// Env holds the optional .env sections by .env key. A section is
+	// configured when any of its keys is present and skipped otherwise.
+	Env map[string]string `json:"env"`
+	// PHP chooses the php.ini profile, time zone, extra extensions and
+	// values.
+	PHP *phpconfig.Settings `json:"php"`
+	// Hooks are site-specific programs to run before or after install
+	// steps, in addition to those in the hooks directory.
+	Hooks []hooks.Hook `json:"hooks"`
+}
+
+// LoadAnswers reads an answer file.
+func LoadAnswers(path string) (*Answers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var a Answers
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("parse answer file %s: %w", path, err)
+	}
+	for i := range a.Hooks {
+		if err := a.Hooks[i].Check(); err != nil {
+			return nil, fmt.Errorf("answer file %s: %w", path, err)
+		}
+	}
+	return &a, nil
+}
@@ -0,0 +1,172 @@
+package installer
+
+import (
+	"sync"
+	"time"
+
+	"yachtcrm-installer/internal/tasks"
+)
+
+// EventKind identifies what happened in a run.
+type EventKind string
+
+const (
+	EventRunStarted   EventKind = "run_started"
+	EventStepStarted  EventKind = "step_started"
+	EventLog          EventKind = "log"
+	EventWarning      EventKind = "warning"
+	EventStepFinished EventKind = "step_finished"
+	EventStepSkipped  EventKind = "step_skipped"
+	EventRunFinished  EventKind = "run_finished"
+)
+
+// Event is published on a Context as the runner and its steps progress.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+	// Steps lists the run's steps in order on EventRunStarted.
+	Steps []string
+	// Step is the step the event belongs to; empty outside a step.
+	Step string
+	// Message is the text of a log line or warning, without the
+	// "Warning: " prefix.
+	Message string
+	// Elapsed is how long the step or run took on EventStepFinished and
+	// EventRunFinished.
+	Elapsed time.Duration
+	// Err is the failure on EventStepFinished and EventRunFinished, or on
+	// EventStepSkipped when a skipped step could not supply its outputs;
+	// nil otherwise.
+	Err error
+}
+
+// Line is the event as a log line, as the console prints it. Events that
+// are not printed return false.
+func (e Event) Line() (string, bool) {
+	switch e.Kind {
+	case EventStepStarted:
+		return "Starting step: " + e.Step, true
+	case EventLog:
+		return e.Message, true
+	case EventWarning:
+		return "Warning: " + e.Message, true
+	case EventStepFinished:
+		if e.Err == nil {
+			return "Completed step: " + e.Step, true
+		}
+	case EventStepSkipped:
+		if e.Err == nil {
+			return "Skipped step: " + e.Step, true
+		}
+	}
+	return "", false
+}
+
+type subscriber struct {
+	fn func(Event)
+}
+
+// eventBus is shared by a Context and the copies the runner makes of it
+// for each step.
+type eventBus struct {
+	mu          sync.Mutex
+	logs        []string
+	subscribers []*subscriber
+	// running maps the running steps to whether they have run alongside
+	// another step.
+	running map[string]bool
+}
+
+var busInit sync.Mutex
+
+func (c *Context) bus() *eventBus {
+	busInit.Lock()
+	defer busInit.Unlock()
+	if c.events == nil {
+		c.events = &eventBus{running: map[string]bool{}}
+	}
+	return c.events
+}
+
+// Subscribe calls fn with every event published on c from now on, one
+// event at a time and in order, and returns a function that stops the
+// calls. It is safe to use from any goroutine. fn runs on the publishing
+// goroutine and must not log to c or subscribe to it.
+func (c *Context) Subscribe(fn func(Event)) (unsubscribe func()) {
+	s := &subscriber{fn: fn}
+	b := c.bus()
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, s)
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, sub := range b.subscribers {
+			if sub == s {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// publish delivers e to the console and the subscribers. Holding the lock
+// while delivering keeps every subscriber's view in publishing order. Once
+// a step has run alongside another, its console lines are prefixed with its
+// name.
+func (c *Context) publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b := c.bus()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if e.Kind == EventStepStarted {
+		b.running[e.Step] = false
+		if len(b.running) > 1 {
+			for step := range b.running {
+				b.running[step] = true
+			}
+		}
+	}
+	if line, ok := e.Line(); ok {
+		b.logs = append(b.logs, line)
+		if b.running[e.Step] {
+			line = "[" + e.Step + "] " + line
+		}
+		c.print(line)
+	}
+	if e.Kind == EventStepFinished {
+		delete(b.running, e.Step)
+	}
+	for _, s := range b.subscribers {
+		s.fn(e)
+	}
+}
+
+// RecordState returns a subscriber that keeps state up to date with a
+// run: each step's status and its log lines, ending with the error when it
+// fails.
+func RecordState(state *tasks.State) func(Event) {
+	return func(e Event) {
+		switch e.Kind {
+		case EventStepStarted:
+			state.SetStatus(e.Step, tasks.StepStatusRunning)
+		case EventStepFinished, EventStepSkipped:
+			if e.Err != nil {
+				state.AppendLog(e.Step, "Error: "+e.Err.Error())
+				state.SetStatus(e.Step, tasks.StepStatusFailed)
+				return
+			}
+		}
+		if line, ok := e.Line(); ok && e.Step != "" {
+			state.AppendLog(e.Step, line)
+		}
+		switch e.Kind {
+		case EventStepFinished:
+			state.SetStatus(e.Step, tasks.StepStatusCompleted)
+		case EventStepSkipped:
+			state.SetStatus(e.Step, tasks.StepStatusSkipped)
+		}
+	}
+}
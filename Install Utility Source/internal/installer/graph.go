@@ -0,0 +1,243 @@
+package installer
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// graph returns the indexes of the steps each step waits for. It fails on
+// duplicate step names, dependency cycles, outputs that are not Context
+// fields and outputs declared by two steps that could run at the same
+// time.
+func (r *Runner) graph() ([][]int, error) {
+	index := map[string]int{}
+	for i, step := range r.steps {
+		if _, dup := index[step.Name()]; dup {
+			return nil, fmt.Errorf("step %q is listed twice", step.Name())
+		}
+		index[step.Name()] = i
+	}
+
+	deps := make([][]int, len(r.steps))
+	for i, step := range r.steps {
+		if _, ok := r.placed[step.Name()]; ok {
+			continue
+		}
+		d, ok := step.(Dependent)
+		if !ok {
+			for j := range i {
+				deps[i] = append(deps[i], j)
+			}
+			continue
+		}
+		for _, dep := range d.DependsOn() {
+			if j, ok := index[dep.Name()]; ok && !slices.Contains(deps[i], j) {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+	r.placeDeps(deps, index)
+	if cycle := findCycle(deps); cycle != nil {
+		names := make([]string, len(cycle))
+		for k, i := range cycle {
+			names[k] = r.steps[i].Name()
+		}
+		return nil, fmt.Errorf("step dependency cycle: %s", strings.Join(names, " -> "))
+	}
+	if err := r.checkOutputs(deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// placement is where Insert put a step: before or after the named step.
+type placement struct {
+	target string
+	after  bool
+}
+
+// Insert adds step to the run just before or after the step with ID
+// target, after any steps already inserted there. A step inserted before
+// the target starts once the target's dependencies have completed, and the
+// target waits for it. A step inserted after the target starts once the
+// target has completed, and the steps that wait for the target wait for it
+// too. Call Insert before Select.
+func (r *Runner) Insert(step Step, target string, after bool) error {
+	id := StepID(step)
+	t := -1
+	for i, s := range r.steps {
+		switch StepID(s) {
+		case id:
+			return fmt.Errorf("step ID %s is already used", id)
+		case target:
+			t = i
+		}
+	}
+	if t < 0 {
+		return fmt.Errorf("unknown step %q (see installer steps)", target)
+	}
+	name := r.steps[t].Name()
+	if _, ok := r.placed[name]; ok {
+		return fmt.Errorf("cannot place %s relative to %s, which was itself inserted", id, target)
+	}
+
+	at := t
+	if after {
+		at = t + 1
+		for at < len(r.steps) && r.placed[r.steps[at].Name()] == (placement{name, true}) {
+			at++
+		}
+	}
+	r.steps = slices.Insert(r.steps, at, step)
+	if r.placed == nil {
+		r.placed = map[string]placement{}
+	}
+	r.placed[step.Name()] = placement{target: name, after: after}
+	return nil
+}
+
+// placeDeps fills in the dependencies of the steps added by Insert, in
+// order, so steps inserted at the same place run one after another.
+func (r *Runner) placeDeps(deps [][]int, index map[string]int) {
+	add := func(i, j int) {
+		if !slices.Contains(deps[i], j) {
+			deps[i] = append(deps[i], j)
+		}
+	}
+	for i, step := range r.steps {
+		p, ok := r.placed[step.Name()]
+		if !ok {
+			continue
+		}
+		t := index[p.target]
+		peers := func(k int) bool {
+			return k != i && r.placed[r.steps[k].Name()] == p
+		}
+		if !p.after {
+			if _, ok := r.steps[t].(Dependent); ok {
+				for _, j := range deps[t] {
+					add(i, j)
+				}
+			} else {
+				for j := range i {
+					add(i, j)
+				}
+			}
+			add(t, i)
+			continue
+		}
+		add(i, t)
+		for k := range r.steps {
+			if peers(k) && k < i {
+				add(i, k)
+			} else if k != i && !peers(k) && slices.Contains(deps[k], t) {
+				add(k, i)
+			}
+		}
+	}
+}
+
+// findCycle returns the steps of a dependency cycle, starting and ending
+// with the same step, or nil when there is none.
+func findCycle(deps [][]int) []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(deps))
+	var path []int
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		state[i] = visiting
+		path = append(path, i)
+		for _, j := range deps[i] {
+			switch state[j] {
+			case visiting:
+				start := slices.Index(path, j)
+				return append(slices.Clone(path[start:]), j)
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range deps {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Runner) checkOutputs(deps [][]int) error {
+	contextType := reflect.TypeFor[Context]()
+	producers := map[string][]int{}
+	for i, step := range r.steps {
+		p, ok := step.(Producer)
+		if !ok {
+			continue
+		}
+		for _, field := range p.Outputs() {
+			if f, ok := contextType.FieldByName(field); !ok || !f.IsExported() {
+				return fmt.Errorf("step %q declares output %s, which is not a Context field", step.Name(), field)
+			}
+			producers[field] = append(producers[field], i)
+		}
+	}
+
+	ancestors := make([]map[int]bool, len(deps))
+	var collect func(i int) map[int]bool
+	collect = func(i int) map[int]bool {
+		if ancestors[i] == nil {
+			ancestors[i] = map[int]bool{}
+			for _, j := range deps[i] {
+				ancestors[i][j] = true
+				for k := range collect(j) {
+					ancestors[i][k] = true
+				}
+			}
+		}
+		return ancestors[i]
+	}
+	for field, steps := range producers {
+		for a, i := range steps {
+			for _, j := range steps[a+1:] {
+				if !collect(i)[j] && !collect(j)[i] {
+					return fmt.Errorf("steps %q and %q both set %s but may run at the same time", r.steps[i].Name(), r.steps[j].Name(), field)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// merge copies the exported fields a step changed on its copy of the
+// context, from before to after, into c.
+func (c *Context) merge(before, after *Context) {
+	dst := reflect.ValueOf(c).Elem()
+	b, a := reflect.ValueOf(before).Elem(), reflect.ValueOf(after).Elem()
+	for i := range dst.NumField() {
+		if !dst.Type().Field(i).IsExported() {
+			continue
+		}
+		if !reflect.DeepEqual(b.Field(i).Interface(), a.Field(i).Interface()) {
+			dst.Field(i).Set(a.Field(i))
+		}
+	}
+}
+
+// insertSorted adds i to the sorted list of ready steps, so steps start in
+// the order they are listed when more are ready than may run.
+func insertSorted(ready []int, i int) []int {
+	at, _ := slices.BinarySearch(ready, i)
+	return slices.Insert(ready, at, i)
+}
@@ -2,38 +2,73 @@ package installer
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"yachtcrm-installer/internal/branding"
+	"yachtcrm-installer/internal/phpconfig"
 )
 
 // Context stores user-provided configuration and derived state that the
 // installer steps can share.
 type Context struct {
-	RuntimeDir            string
-	PrerequisitesDir      string
-	CRMSourceDir          string
-	DownloadsDir          string
-	RootMariaDBPassword   string
-	DatabaseName          string
-	DatabaseUser          string
-	DatabaseUserPassword  string
-	SqlDumpPath           string
-	AdminName             string
-	AdminEmail            string
-	AdminPassword         string
-	PhpInstallDir         string
-	PhpIniPath            string
-	PhpExePath            string
-	ComposerPath          string
-	NodeInstallDir        string
-	NodeBinDir            string
-	PhpMyAdminDir         string
-	ComposerInstallerPath string
-	MariaDBInstallerPath  string
-	NodeZipPath           string
-	PhpNtsZipPath         string
-	PhpTsZipPath          string
-	PhpMyAdminZipPath     string
-	MariaDBBinDir         string
-	Logs                  []string
+	InstanceName           string
+	RuntimeDir             string
+	PrerequisitesDir       string
+	CRMSourceDir           string
+	DownloadsDir           string
+	ExternalDatabase       bool
+	DatabaseHost           string
+	DatabasePort           int
+	DatabaseAdminUser      string
+	RootMariaDBPassword    string
+	DatabaseUserHost       string
+	DatabaseName           string
+	DatabaseUser           string
+	DatabaseUserPassword   string
+	SqlDumpPath            string
+	AdminName              string
+	AdminEmail             string
+	AdminPassword          string
+	PhpInstallDir          string
+	PhpIniPath             string
+	PhpExePath             string
+	PHP                    phpconfig.Settings
+	ComposerPath           string
+	NodeInstallDir         string
+	NodeBinDir             string
+	PhpMyAdminDir          string
+	ComposerInstallerPath  string
+	MariaDBInstallerPath   string
+	NodeZipPath            string
+	PhpNtsZipPath          string
+	PhpTsZipPath           string
+	PhpMyAdminZipPath      string
+	MariaDBBinDir          string
+	AppURL                 string
+	FrontendURL            string
+	SiteName               string
+	AppPoolName            string
+	HostHeader             string
+	HTTPPort               int
+	TLSCertThumbprint      string
+	AddHostsEntry          bool
+	SchedulerTaskName      string
+	SchedulerRunAsUser     string
+	SchedulerRunAsPassword string
+	WorkerTaskName         string
+	EnabledModules         []string
+	Branding               branding.Profile
+	Answers                *Answers
+
+	// Output receives log lines as they are written. Nil means stdout.
+	Output io.Writer
+
+	// step is the step a context copy was made for; see Runner.run.
+	step   string
+	events *eventBus
 }
 
 // Step defines a single installer operation.
@@ -42,29 +77,198 @@ type Step interface {
 	Run(*Context) error
 }
 
-// Runner executes each step sequentially, collecting output and halting on
-// the first failure.
+// Dependent is implemented by steps that declare which steps must finish
+// before they start. Dependencies that are not part of the run are ignored.
+// A step that does not implement Dependent starts after every step listed
+// before it.
+type Dependent interface {
+	DependsOn() []Step
+}
+
+// Producer is implemented by steps that set Context fields later steps rely
+// on. Outputs names those fields.
+type Producer interface {
+	Outputs() []string
+}
+
+// Interactive is implemented by steps that may ask questions. The runner
+// runs one interactive step at a time, for the whole step, so a dialog of
+// several questions is never interleaved with another step's.
+type Interactive interface {
+	Interactive() bool
+}
+
+// DefaultParallel is how many steps NewRunner lets run at once.
+const DefaultParallel = 4
+
+// Runner executes steps in dependency order, running independent steps in
+// parallel, and stops starting steps after the first failure.
 type Runner struct {
 	steps []Step
+	// Parallel is the most steps that run at once; below 1 means 1.
+	Parallel int
+
+	// selected and needed are set by Select; nil runs every step.
+	selected, needed []bool
+	// placed holds the placement of the steps added by Insert, by name.
+	placed map[string]placement
 }
 
 func NewRunner(steps []Step) *Runner {
-	return &Runner{steps: steps}
+	return &Runner{steps: steps, Parallel: DefaultParallel}
 }
 
+// Run executes the steps, publishing the run's events on ctx.
 func (r *Runner) Run(ctx *Context) error {
-	for _, step := range r.steps {
-		ctx.Logf("Starting step: %s", step.Name())
-		if err := step.Run(ctx); err != nil {
-			return fmt.Errorf("%s failed: %w", step.Name(), err)
+	start := time.Now()
+	ctx.publish(Event{Kind: EventRunStarted, Time: start, Steps: r.StepNames()})
+	err := r.run(ctx)
+	ctx.publish(Event{Kind: EventRunFinished, Elapsed: time.Since(start), Err: err})
+	return err
+}
+
+// run starts each step once its dependencies have completed. Every step
+// works on its own copy of ctx, so its log lines are attributed to it, and
+// the fields it changed are copied back to ctx when it finishes. Only this
+// goroutine reads or writes ctx while steps run.
+func (r *Runner) run(ctx *Context) error {
+	deps, err := r.graph()
+	if err != nil {
+		return err
+	}
+	parallel := max(r.Parallel, 1)
+
+	waiting := make([]int, len(r.steps))
+	dependents := make([][]int, len(r.steps))
+	var ready []int
+	for i, d := range deps {
+		waiting[i] = len(d)
+		for _, j := range d {
+			dependents[j] = append(dependents[j], i)
+		}
+		if len(d) == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	type result struct {
+		index         int
+		skipped       bool
+		before, after *Context
+		err           error
+	}
+	done := make(chan result)
+	running := 0
+	// prompting is set while an interactive step runs; other interactive
+	// steps stay ready until it finishes.
+	prompting := false
+	var firstErr error
+	for {
+		for firstErr == nil && running < parallel {
+			k := slices.IndexFunc(ready, func(i int) bool { return !prompting || !r.interactive(i) })
+			if k < 0 {
+				break
+			}
+			i := ready[k]
+			ready = slices.Delete(ready, k, k+1)
+			if r.interactive(i) {
+				prompting = true
+			}
+			before := *ctx
+			before.step = r.steps[i].Name()
+			after := before
+			running++
+			go func() {
+				res := result{index: i, before: &before, after: &after}
+				if r.selected == nil || r.selected[i] {
+					res.err = runStep(&after, r.steps[i])
+				} else {
+					res.skipped = true
+					res.err = skipStep(&after, r.steps[i], r.needed[i])
+				}
+				done <- res
+			}()
+		}
+		if running == 0 {
+			return firstErr
+		}
+
+		res := <-done
+		running--
+		if r.interactive(res.index) {
+			prompting = false
+		}
+		ctx.merge(res.before, res.after)
+		if res.err != nil {
+			if firstErr == nil && res.skipped {
+				firstErr = fmt.Errorf("skipping %s: %w", r.steps[res.index].Name(), res.err)
+			} else if firstErr == nil {
+				firstErr = fmt.Errorf("%s failed: %w", r.steps[res.index].Name(), res.err)
+			}
+			continue
+		}
+		for _, i := range dependents[res.index] {
+			if waiting[i]--; waiting[i] == 0 {
+				ready = insertSorted(ready, i)
+			}
 		}
-		ctx.Logf("Completed step: %s", step.Name())
 	}
-	return nil
+}
+
+// interactive reports whether step i may ask questions when it runs.
+// Skipped steps never do.
+func (r *Runner) interactive(i int) bool {
+	if r.selected != nil && !r.selected[i] {
+		return false
+	}
+	s, ok := r.steps[i].(Interactive)
+	return ok && s.Interactive()
+}
+
+func runStep(ctx *Context, step Step) error {
+	start := time.Now()
+	ctx.publish(Event{Kind: EventStepStarted, Time: start, Step: ctx.step})
+	err := step.Run(ctx)
+	ctx.publish(Event{Kind: EventStepFinished, Step: ctx.step, Elapsed: time.Since(start), Err: err})
+	return err
+}
+
+// Steps returns the runner's steps in order.
+func (r *Runner) Steps() []Step {
+	return slices.Clone(r.steps)
+}
+
+// StepNames lists the runner's steps in order, e.g. to build a tasks.State.
+func (r *Runner) StepNames() []string {
+	names := make([]string, len(r.steps))
+	for i, step := range r.steps {
+		names[i] = step.Name()
+	}
+	return names
 }
 
 func (c *Context) Logf(format string, args ...any) {
-	msg := fmt.Sprintf(format, args...)
-	c.Logs = append(c.Logs, msg)
-	fmt.Println(msg)
+	c.publish(Event{Kind: EventLog, Step: c.step, Message: fmt.Sprintf(format, args...)})
+}
+
+// Warnf logs a problem that does not stop the step. It is printed with a
+// "Warning: " prefix.
+func (c *Context) Warnf(format string, args ...any) {
+	c.publish(Event{Kind: EventWarning, Step: c.step, Message: fmt.Sprintf(format, args...)})
+}
+
+// Logs returns a copy of the lines logged so far.
+func (c *Context) Logs() []string {
+	b := c.bus()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.logs...)
+}
+
+func (c *Context) print(line string) {
+	out := c.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintln(out, line)
 }
@@ -0,0 +1,398 @@
+package installer
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStep depends on the steps named in after and runs run, if set.
+type testStep struct {
+	name    string
+	after   []string
+	outputs []string
+	run     func(*Context) error
+}
+
+func (s testStep) Name() string { return s.name }
+
+func (s testStep) DependsOn() []Step {
+	var deps []Step
+	for _, name := range s.after {
+		deps = append(deps, testStep{name: name})
+	}
+	return deps
+}
+
+func (s testStep) Outputs() []string { return s.outputs }
+
+func (s testStep) Run(ctx *Context) error {
+	if s.run == nil {
+		return nil
+	}
+	return s.run(ctx)
+}
+
+// plainStep does not implement Dependent, so it waits for every step
+// listed before it.
+type plainStep struct{ name string }
+
+func (s plainStep) Name() string       { return s.name }
+func (s plainStep) Run(*Context) error { return nil }
+
+// trackedStep records how many tracked steps overlap while it runs.
+type trackedStep struct {
+	name        string
+	interactive bool
+	t           *tracker
+}
+
+func (s trackedStep) Name() string           { return s.name }
+func (s trackedStep) DependsOn() []Step      { return nil }
+func (s trackedStep) Interactive() bool      { return s.interactive }
+func (s trackedStep) Run(ctx *Context) error { s.t.run(s.interactive); return nil }
+
+type tracker struct {
+	mu                       sync.Mutex
+	running, prompting       int
+	maxRunning, maxPrompting int
+}
+
+func (t *tracker) run(interactive bool) {
+	t.mu.Lock()
+	t.running++
+	t.maxRunning = max(t.maxRunning, t.running)
+	if interactive {
+		t.prompting++
+		t.maxPrompting = max(t.maxPrompting, t.prompting)
+	}
+	t.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	t.mu.Lock()
+	t.running--
+	if interactive {
+		t.prompting--
+	}
+	t.mu.Unlock()
+}
+
+func TestRunnerRunsInteractiveStepsOneAtATime(t *testing.T) {
+	tr := &tracker{}
+	steps := []Step{
+		trackedStep{name: "ask 1", interactive: true, t: tr},
+		trackedStep{name: "work 1", t: tr},
+		trackedStep{name: "ask 2", interactive: true, t: tr},
+		trackedStep{name: "work 2", t: tr},
+		trackedStep{name: "ask 3", interactive: true, t: tr},
+	}
+	if err := NewRunner(steps).Run(&Context{Output: io.Discard}); err != nil {
+		t.Fatal(err)
+	}
+	if tr.maxPrompting != 1 {
+		t.Errorf("%d interactive steps ran at once, want 1", tr.maxPrompting)
+	}
+	// The other steps still run alongside the interactive ones.
+	if tr.maxRunning < 2 {
+		t.Errorf("at most %d steps ran at once, want parallel steps", tr.maxRunning)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	r := NewRunner([]Step{
+		testStep{name: "a"},
+		testStep{name: "b", after: []string{"a", "not in this run"}},
+		plainStep{name: "c"},
+		testStep{name: "d"},
+		testStep{name: "e", after: []string{"c", "d"}},
+	})
+	deps, err := r.graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{nil, {0}, {0, 1}, nil, {2, 3}}
+	if !slices.EqualFunc(deps, want, slices.Equal) {
+		t.Errorf("deps = %v, want %v", deps, want)
+	}
+}
+
+func TestGraphErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []Step
+		err   string
+	}{
+		{
+			name:  "duplicate",
+			steps: []Step{testStep{name: "a"}, testStep{name: "a"}},
+			err:   `step "a" is listed twice`,
+		},
+		{
+			name: "cycle",
+			steps: []Step{
+				testStep{name: "a", after: []string{"c"}},
+				testStep{name: "b", after: []string{"a"}},
+				testStep{name: "c", after: []string{"b"}},
+				testStep{name: "d", after: []string{"a"}},
+			},
+			err: "step dependency cycle: a -> c -> b -> a",
+		},
+		{
+			name:  "self",
+			steps: []Step{testStep{name: "a"}, testStep{name: "b", after: []string{"b"}}},
+			err:   "step dependency cycle: b -> b",
+		},
+		{
+			name: "unordered producers",
+			steps: []Step{
+				testStep{name: "a", outputs: []string{"AppURL"}},
+				testStep{name: "b", outputs: []string{"AppURL"}},
+			},
+			err: `steps "a" and "b" both set AppURL but may run at the same time`,
+		},
+		{
+			name: "producers ordered through another step",
+			steps: []Step{
+				testStep{name: "a", outputs: []string{"AppURL"}},
+				testStep{name: "b", after: []string{"a"}},
+				testStep{name: "c", after: []string{"b"}, outputs: []string{"AppURL"}},
+			},
+		},
+		{
+			name: "producers ordered by listing",
+			steps: []Step{
+				testStep{name: "a", outputs: []string{"AppURL"}},
+				plainStep{name: "b"},
+				plainStep{name: "c"},
+				testStep{name: "d", after: []string{"c"}, outputs: []string{"AppURL"}},
+			},
+		},
+		{
+			name:  "unknown output",
+			steps: []Step{testStep{name: "a", outputs: []string{"AppUrl"}}},
+			err:   `step "a" declares output AppUrl, which is not a Context field`,
+		},
+		{
+			name:  "unexported output",
+			steps: []Step{testStep{name: "a", outputs: []string{"events"}}},
+			err:   `step "a" declares output events, which is not a Context field`,
+		},
+	}
+	for _, tt := range tests {
+		_, err := NewRunner(tt.steps).graph()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestRunnerRespectsParallel(t *testing.T) {
+	for _, parallel := range []int{0, 1, 2, 3} {
+		tr := &tracker{}
+		var steps []Step
+		for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			steps = append(steps, trackedStep{name: name, t: tr})
+		}
+		r := NewRunner(steps)
+		r.Parallel = parallel
+		if err := r.Run(&Context{Output: io.Discard}); err != nil {
+			t.Fatal(err)
+		}
+		if want := max(parallel, 1); tr.maxRunning != want {
+			t.Errorf("Parallel %d: at most %d steps ran at once, want %d", parallel, tr.maxRunning, want)
+		}
+	}
+}
+
+func TestRunnerStopsAfterFailure(t *testing.T) {
+	var mu sync.Mutex
+	var started, finished []string
+	step := func(name string, after []string, d time.Duration, err error) Step {
+		return testStep{name: name, after: after, run: func(*Context) error {
+			mu.Lock()
+			started = append(started, name)
+			mu.Unlock()
+			time.Sleep(d)
+			mu.Lock()
+			finished = append(finished, name)
+			mu.Unlock()
+			return err
+		}}
+	}
+	boom := errors.New("boom")
+	r := NewRunner([]Step{
+		step("slow", nil, 50*time.Millisecond, nil),
+		step("fails", nil, 0, boom),
+		step("waiting", nil, 0, nil),
+		step("after slow", []string{"slow"}, 0, nil),
+	})
+	r.Parallel = 2
+	err := r.Run(&Context{Output: io.Discard})
+	if !errors.Is(err, boom) || err.Error() != "fails failed: boom" {
+		t.Errorf("error %v, want fails failed: boom", err)
+	}
+	slices.Sort(started)
+	if want := []string{"fails", "slow"}; !slices.Equal(started, want) {
+		t.Errorf("started %q, want only %q", started, want)
+	}
+	// The step already running when the other failed finishes before Run
+	// returns.
+	if !slices.Contains(finished, "slow") {
+		t.Errorf("finished %q, want slow to drain", finished)
+	}
+}
+
+func TestRunnerMergesChangedFields(t *testing.T) {
+	ctx := &Context{Output: io.Discard, InstanceName: "marina", SiteName: "old site"}
+	r := NewRunner([]Step{
+		testStep{name: "site", run: func(ctx *Context) error {
+			time.Sleep(20 * time.Millisecond)
+			ctx.SiteName = "YachtCRM-marina"
+			return nil
+		}},
+		testStep{name: "pool", run: func(ctx *Context) error {
+			ctx.AppPoolName = "YachtCRM-marina"
+			ctx.EnabledModules = append(ctx.EnabledModules, "service")
+			return nil
+		}},
+		testStep{name: "name", run: func(ctx *Context) error {
+			time.Sleep(10 * time.Millisecond)
+			ctx.InstanceName = "harbor"
+			return nil
+		}},
+		testStep{name: "check", after: []string{"site", "pool", "name"}, run: func(ctx *Context) error {
+			if ctx.SiteName != "YachtCRM-marina" || ctx.AppPoolName != "YachtCRM-marina" || ctx.InstanceName != "harbor" {
+				return errors.New("changes of earlier steps are missing")
+			}
+			return nil
+		}},
+	})
+	if err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Each step only copied back what it changed, so the steps running
+	// alongside it did not undo each other's changes.
+	if ctx.SiteName != "YachtCRM-marina" || ctx.AppPoolName != "YachtCRM-marina" || ctx.InstanceName != "harbor" {
+		t.Errorf("site %q, pool %q, instance %q after the run", ctx.SiteName, ctx.AppPoolName, ctx.InstanceName)
+	}
+	if !slices.Equal(ctx.EnabledModules, []string{"service"}) {
+		t.Errorf("modules %q, want [service]", ctx.EnabledModules)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ctx := &Context{InstanceName: "marina", HTTPPort: 80, EnabledModules: []string{"service"}}
+	before := *ctx
+	after := before
+	after.HTTPPort = 8080
+	after.EnabledModules = []string{"service", "parts"}
+	after.step = "copy"
+	// Another step changed InstanceName meanwhile; this step did not.
+	ctx.InstanceName = "harbor"
+
+	ctx.merge(&before, &after)
+	if ctx.HTTPPort != 8080 || !slices.Equal(ctx.EnabledModules, []string{"service", "parts"}) {
+		t.Errorf("port %d, modules %q, want the step's changes", ctx.HTTPPort, ctx.EnabledModules)
+	}
+	if ctx.InstanceName != "harbor" {
+		t.Errorf("instance %q, want the unchanged field left alone", ctx.InstanceName)
+	}
+	if ctx.step != "" {
+		t.Errorf("step %q, want unexported fields left alone", ctx.step)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	r := NewRunner([]Step{
+		testStep{name: "a"},
+		testStep{name: "b", after: []string{"a"}},
+		testStep{name: "c", after: []string{"b"}},
+		testStep{name: "d", after: []string{"a", "b"}},
+		testStep{name: "e", after: []string{"a"}},
+	})
+	for _, h := range []struct {
+		name, target string
+		after        bool
+	}{
+		{"before 1", "b", false},
+		{"after 1", "b", true},
+		{"before 2", "b", false},
+		{"after 2", "b", true},
+	} {
+		if err := r.Insert(testStep{name: h.name}, h.target, h.after); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := r.StepNames(), []string{"a", "before 1", "before 2", "b", "after 1", "after 2", "c", "d", "e"}; !slices.Equal(got, want) {
+		t.Errorf("steps %q, want %q", got, want)
+	}
+
+	deps, err := r.graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{
+		nil,          // a
+		{0},          // before 1 starts with b's own dependencies
+		{0, 1},       // before 2 follows before 1
+		{0, 1, 2},    // b waits for its before hooks
+		{3},          // after 1 waits for b
+		{3, 4},       // after 2 follows after 1
+		{3, 4, 5},    // c waits for b and its after hooks
+		{0, 3, 4, 5}, // and so does d
+		{0},          // e does not depend on b
+	}
+	if !slices.EqualFunc(deps, want, slices.Equal) {
+		t.Errorf("deps = %v, want %v", deps, want)
+	}
+}
+
+func TestInsertBeforeListedStep(t *testing.T) {
+	r := NewRunner([]Step{plainStep{name: "a"}, plainStep{name: "b"}, plainStep{name: "c"}})
+	if err := r.Insert(testStep{name: "hook"}, "b", false); err != nil {
+		t.Fatal(err)
+	}
+	deps, err := r.graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The hook waits for the steps listed before b, and b and the steps
+	// after it wait for the hook.
+	want := [][]int{nil, {0}, {0, 1}, {0, 1, 2}}
+	if !slices.EqualFunc(deps, want, slices.Equal) {
+		t.Errorf("deps = %v, want %v", deps, want)
+	}
+}
+
+func TestInsertErrors(t *testing.T) {
+	r := NewRunner([]Step{testStep{name: "a"}, testStep{name: "b", after: []string{"a"}}})
+	if err := r.Insert(testStep{name: "hook"}, "a", true); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		step   string
+		target string
+		err    string
+	}{
+		{"other hook", "hook", "cannot place other hook relative to hook, which was itself inserted"},
+		{"other hook", "z", `unknown step "z" (see installer steps)`},
+		{"b", "a", "step ID b is already used"},
+		{"hook", "b", "step ID hook is already used"},
+	}
+	for _, tt := range tests {
+		err := r.Insert(testStep{name: tt.step}, tt.target, false)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Insert(%s, %s): error %v, want %q", tt.step, tt.target, err, tt.err)
+		}
+	}
+	if got, want := r.StepNames(), []string{"a", "hook", "b"}; !slices.Equal(got, want) {
+		t.Errorf("steps %q after refused inserts, want %q", got, want)
+	}
+}
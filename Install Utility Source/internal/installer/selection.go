@@ -0,0 +1,171 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Described is implemented by steps that can be selected by ID.
+type Described interface {
+	ID() string
+	Description() string
+}
+
+// Skipper is implemented by steps that can be left out of a run when later
+// steps rely on their outputs. Skip supplies the outputs another way, for
+// example from what is already installed, and fails when it cannot.
+type Skipper interface {
+	Skip(*Context) error
+}
+
+// Mandatory is implemented by steps that run whatever the selection,
+// such as collecting the settings every other step reads.
+type Mandatory interface {
+	Mandatory() bool
+}
+
+// StepID is the ID a step is selected by: its ID when it has one, otherwise
+// its name.
+func StepID(step Step) string {
+	if d, ok := step.(Described); ok {
+		return d.ID()
+	}
+	return step.Name()
+}
+
+// Selection picks the steps of a run by ID. Only and From/To narrow the
+// steps to run, Skip removes steps from them; an empty Selection runs every
+// step.
+type Selection struct {
+	Only []string
+	Skip []string
+	// From and To bound the run to a range of the steps in their listed
+	// order, inclusive.
+	From string
+	To   string
+}
+
+// Select restricts the run to sel. Steps left out are reported as skipped.
+// It fails when a step left out sets Context fields a selected step relies
+// on and cannot supply them another way.
+func (r *Runner) Select(sel Selection) error {
+	index := map[string]int{}
+	for i, step := range r.steps {
+		index[StepID(step)] = i
+	}
+	lookup := func(id string) (int, error) {
+		i, ok := index[id]
+		if !ok {
+			return 0, fmt.Errorf("unknown step %q (see installer steps)", id)
+		}
+		return i, nil
+	}
+
+	selected := make([]bool, len(r.steps))
+	for i := range selected {
+		selected[i] = len(sel.Only) == 0
+	}
+	for _, id := range sel.Only {
+		i, err := lookup(id)
+		if err != nil {
+			return err
+		}
+		selected[i] = true
+	}
+	from, to := 0, len(r.steps)-1
+	var err error
+	if sel.From != "" {
+		if from, err = lookup(sel.From); err != nil {
+			return err
+		}
+	}
+	if sel.To != "" {
+		if to, err = lookup(sel.To); err != nil {
+			return err
+		}
+	}
+	if from > to {
+		return fmt.Errorf("step %s comes after %s", sel.From, sel.To)
+	}
+	for i := range selected {
+		if i < from || i > to {
+			selected[i] = false
+		}
+	}
+	for _, id := range sel.Skip {
+		i, err := lookup(id)
+		if err != nil {
+			return err
+		}
+		if isMandatory(r.steps[i]) {
+			return fmt.Errorf("step %s always runs and cannot be skipped", id)
+		}
+		selected[i] = false
+	}
+
+	chosen := false
+	for i, step := range r.steps {
+		if isMandatory(step) {
+			selected[i] = true
+		} else if selected[i] {
+			chosen = true
+		}
+	}
+	if !chosen {
+		return fmt.Errorf("no steps selected")
+	}
+
+	deps, err := r.graph()
+	if err != nil {
+		return err
+	}
+	needed := neededSkips(deps, selected)
+	for i, step := range r.steps {
+		if !needed[i] {
+			continue
+		}
+		p, produces := step.(Producer)
+		if _, ok := step.(Skipper); produces && len(p.Outputs()) > 0 && !ok {
+			return fmt.Errorf("step %s is not selected, but it sets %s for the selected steps and cannot supply them another way", StepID(step), strings.Join(p.Outputs(), ", "))
+		}
+	}
+	r.selected, r.needed = selected, needed
+	return nil
+}
+
+// neededSkips marks the steps that are not selected but that a selected
+// step depends on, directly or through other steps that are not selected.
+func neededSkips(deps [][]int, selected []bool) []bool {
+	needed := make([]bool, len(deps))
+	var visit func(i int)
+	visit = func(i int) {
+		for _, j := range deps[i] {
+			if !selected[j] && !needed[j] {
+				needed[j] = true
+				visit(j)
+			}
+		}
+	}
+	for i := range deps {
+		if selected[i] {
+			visit(i)
+		}
+	}
+	return needed
+}
+
+func isMandatory(step Step) bool {
+	m, ok := step.(Mandatory)
+	return ok && m.Mandatory()
+}
+
+// skipStep stands in for a step left out of the run. When a selected step
+// relies on its outputs it supplies them through Skip.
+func skipStep(ctx *Context, step Step, supply bool) error {
+	var err error
+	if s, ok := step.(Skipper); ok && supply {
+		err = s.Skip(ctx)
+	}
+	ctx.publish(Event{Kind: EventStepSkipped, Step: ctx.step, Err: err})
+	return err
+}
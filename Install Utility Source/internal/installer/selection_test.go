@@ -0,0 +1,188 @@
+package installer
+
+import (
+	"slices"
+	"testing"
+)
+
+// describedStep is selected by id, which differs from its name.
+type describedStep struct {
+	testStep
+	id        string
+	mandatory bool
+}
+
+func (s describedStep) ID() string          { return s.id }
+func (s describedStep) Description() string { return s.name }
+func (s describedStep) Mandatory() bool     { return s.mandatory }
+
+// skippableStep can supply its outputs when it is left out.
+type skippableStep struct{ describedStep }
+
+func (skippableStep) Skip(*Context) error { return nil }
+
+// selectionSteps returns steps shaped like an install: settings always
+// run, php and node can be skipped, database cannot, and the later steps
+// depend on them.
+func selectionSteps() []Step {
+	step := func(id string, outputs []string, after ...string) describedStep {
+		s := describedStep{testStep: testStep{name: "Step " + id, outputs: outputs}, id: id}
+		for _, a := range after {
+			s.after = append(s.after, "Step "+a)
+		}
+		return s
+	}
+	settings := step("settings", []string{"InstanceName"})
+	settings.mandatory = true
+	return []Step{
+		settings,
+		skippableStep{step("php", []string{"PhpExePath"})},
+		skippableStep{step("node", []string{"NodeBinDir"})},
+		step("database", []string{"DatabaseName"}),
+		step("composer", nil, "php"),
+		step("frontend", nil, "node"),
+		step("register", nil, "composer", "frontend", "database"),
+	}
+}
+
+func TestSelect(t *testing.T) {
+	all := []string{"settings", "php", "node", "database", "composer", "frontend", "register"}
+	tests := []struct {
+		name     string
+		sel      Selection
+		selected []string
+		needed   []string
+		err      string
+	}{
+		{
+			name:     "everything",
+			selected: all,
+		},
+		{
+			name:     "only",
+			sel:      Selection{Only: []string{"composer"}},
+			selected: []string{"settings", "composer"},
+			needed:   []string{"php"},
+		},
+		{
+			name:     "only, needing steps through other skipped steps",
+			sel:      Selection{Only: []string{"database", "register"}},
+			selected: []string{"settings", "database", "register"},
+			needed:   []string{"php", "node", "composer", "frontend"},
+		},
+		{
+			name:     "skip",
+			sel:      Selection{Skip: []string{"frontend"}},
+			selected: []string{"settings", "php", "node", "database", "composer", "register"},
+			needed:   []string{"frontend"},
+		},
+		{
+			name:     "from and to",
+			sel:      Selection{From: "php", To: "frontend"},
+			selected: []string{"settings", "php", "node", "database", "composer", "frontend"},
+		},
+		{
+			name:     "to",
+			sel:      Selection{To: "node"},
+			selected: []string{"settings", "php", "node"},
+		},
+		{
+			name:     "from with skip",
+			sel:      Selection{From: "database", Skip: []string{"composer"}},
+			selected: []string{"settings", "database", "frontend", "register"},
+			needed:   []string{"php", "node", "composer"},
+		},
+		{
+			name: "from, leaving out a step that cannot be skipped",
+			sel:  Selection{From: "composer"},
+			err:  "step database is not selected, but it sets DatabaseName for the selected steps and cannot supply them another way",
+		},
+		{
+			name: "only mandatory steps",
+			sel:  Selection{Only: []string{"settings"}},
+			err:  "no steps selected",
+		},
+		{
+			name: "everything skipped",
+			sel:  Selection{Only: []string{"php"}, Skip: []string{"php"}},
+			err:  "no steps selected",
+		},
+		{
+			name: "skip mandatory",
+			sel:  Selection{Skip: []string{"settings"}},
+			err:  "step settings always runs and cannot be skipped",
+		},
+		{
+			name: "range backwards",
+			sel:  Selection{From: "frontend", To: "composer"},
+			err:  "step frontend comes after composer",
+		},
+		{
+			name: "unknown",
+			sel:  Selection{Only: []string{"Step php"}},
+			err:  `unknown step "Step php" (see installer steps)`,
+		},
+	}
+	for _, tt := range tests {
+		r := NewRunner(selectionSteps())
+		err := r.Select(tt.sel)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			if r.selected != nil {
+				t.Errorf("%s: selection kept after an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var selected, needed []string
+		for i, step := range r.steps {
+			if r.selected[i] {
+				selected = append(selected, StepID(step))
+			}
+			if r.needed[i] {
+				needed = append(needed, StepID(step))
+			}
+		}
+		if !slices.Equal(selected, tt.selected) || !slices.Equal(needed, tt.needed) {
+			t.Errorf("%s: selected %q, needed %q, want %q, %q", tt.name, selected, needed, tt.selected, tt.needed)
+		}
+	}
+}
+
+func TestNeededSkips(t *testing.T) {
+	// 0 <- 1 <- 2 <- 3, and 4 depends on 0 and 3.
+	deps := [][]int{nil, {0}, {1}, {2}, {0, 3}}
+	tests := []struct {
+		selected []bool
+		needed   []bool
+	}{
+		{
+			selected: []bool{true, true, true, true, true},
+			needed:   []bool{false, false, false, false, false},
+		},
+		{
+			selected: []bool{false, false, false, false, true},
+			needed:   []bool{true, true, true, true, false},
+		},
+		{
+			// A selected step in the chain needs its own dependencies, but
+			// steps selected after it do not reach past it.
+			selected: []bool{false, false, true, false, true},
+			needed:   []bool{true, true, false, true, false},
+		},
+		{
+			selected: []bool{true, false, false, false, false},
+			needed:   []bool{false, false, false, false, false},
+		},
+	}
+	for _, tt := range tests {
+		if got := neededSkips(deps, tt.selected); !slices.Equal(got, tt.needed) {
+			t.Errorf("neededSkips(%v) = %v, want %v", tt.selected, got, tt.needed)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package instances
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yachtcrm-installer/internal/powershell"
+)
+
+// Binding is one IIS site binding.
+type Binding struct {
+	Site     string
+	Protocol string
+	Port     int
+	Host     string
+}
+
+// SiteBindings lists the HTTP and HTTPS bindings of every IIS site.
+func SiteBindings() ([]Binding, error) {
+	result := powershell.Run(`Import-Module WebAdministration
+Get-Website | ForEach-Object {
+    $name = $_.Name
+    $_.Bindings.Collection | Where-Object { $_.protocol -in @('http','https') } | ForEach-Object { '{0}|{1}|{2}' -f $name, $_.protocol, $_.bindingInformation }
+}`)
+	if result.Err != nil {
+		return nil, fmt.Errorf("list IIS bindings: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+
+	var bindings []Binding
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "|")
+		if len(parts) != 3 {
+			continue
+		}
+		// bindingInformation is "ip:port:host"; IPv6 addresses contain
+		// colons, so split from the right.
+		info := parts[2]
+		hostIdx := strings.LastIndex(info, ":")
+		if hostIdx == -1 {
+			continue
+		}
+		portIdx := strings.LastIndex(info[:hostIdx], ":")
+		port, err := strconv.Atoi(info[portIdx+1 : hostIdx])
+		if err != nil {
+			continue
+		}
+		bindings = append(bindings, Binding{Site: parts[0], Protocol: parts[1], Port: port, Host: info[hostIdx+1:]})
+	}
+	return bindings, nil
+}
+
+// BindingConflicts reports bindings on other sites that would receive the
+// instance's traffic. A binding without a host header only collides with
+// another binding without one on the same port.
+func BindingConflicts(inst Instance, bindings []Binding) []string {
+	var conflicts []string
+	for _, b := range bindings {
+		if strings.EqualFold(b.Site, inst.SiteName) {
+			continue
+		}
+		if b.Port == inst.Port && strings.EqualFold(b.Host, inst.HostName) {
+			host := b.Host
+			if host == "" {
+				host = "(any host)"
+			}
+			conflicts = append(conflicts, fmt.Sprintf("IIS site %s already binds %s port %d for %s", b.Site, b.Protocol, b.Port, host))
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,213 @@
+package instances
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/phpconfig"
+)
+
+// DefaultName is the instance a plain `installer install` creates. It keeps
+// the resource names used before instances existed so older installs are
+// picked up unchanged.
+const DefaultName = "default"
+
+// ErrNotFound is returned when no instance has the requested name.
+var ErrNotFound = errors.New("instance not found")
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,30}$`)
+
+// Instance records the resources that belong to one installed copy of
+// YachtCRM-DMS so it can be upgraded or removed later.
+type Instance struct {
+	Name          string `json:"name"`
+	SiteName      string `json:"site_name"`
+	AppPool       string `json:"app_pool"`
+	RuntimeDir    string `json:"runtime_dir"`
+	HostName      string `json:"host_name"`
+	Port          int    `json:"port"`
+	AppURL        string `json:"app_url"`
+	DatabaseHost  string `json:"database_host,omitempty"`
+	DatabasePort  int    `json:"database_port,omitempty"`
+	DatabaseName  string `json:"database_name"`
+	DatabaseUser  string `json:"database_user"`
+	UserHost      string `json:"database_user_host,omitempty"`
+	ExternalDB    bool   `json:"external_database,omitempty"`
+	SchedulerTask string `json:"scheduler_task"`
+	WorkerTask    string `json:"worker_task"`
+	PhpExePath    string `json:"php_exe_path"`
+	// PHP is nil for instances registered before php.ini profiles.
+	PHP           *phpconfig.Settings `json:"php,omitempty"`
+	NodeBinDir    string              `json:"node_bin_dir"`
+	MariaDBBinDir string              `json:"mariadb_bin_dir"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// ValidateName checks that name can be embedded in site, task and database
+// names.
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("instance name %q must be 1-31 lowercase letters, digits or dashes", name)
+	}
+	return nil
+}
+
+// Defaults returns the resource names for a new instance.
+func Defaults(name string) Instance {
+	if name == DefaultName {
+		return Instance{
+			Name:          name,
+			SiteName:      "YachtCRM-DMS",
+			AppPool:       "YachtCRM-DMS",
+			RuntimeDir:    filepath.Join(`C:\`, `inetpub`, `wwwroot`, `yachtcrm`),
+			Port:          80,
+			DatabaseName:  "yachtcrm",
+			DatabaseUser:  "yachtcrm_user",
+			SchedulerTask: "YachtCRM Artisan Scheduler",
+			WorkerTask:    "YachtCRM Queue Worker",
+		}
+	}
+	dbSuffix := strings.ReplaceAll(name, "-", "_")
+	return Instance{
+		Name:          name,
+		SiteName:      "YachtCRM-DMS-" + name,
+		AppPool:       "YachtCRM-DMS-" + name,
+		RuntimeDir:    filepath.Join(`C:\`, `inetpub`, `wwwroot`, `yachtcrm-`+name),
+		Port:          80,
+		DatabaseName:  "yachtcrm_" + dbSuffix,
+		DatabaseUser:  "yachtcrm_" + dbSuffix,
+		SchedulerTask: "YachtCRM Artisan Scheduler (" + name + ")",
+		WorkerTask:    "YachtCRM Queue Worker (" + name + ")",
+	}
+}
+
+// databaseServer identifies the server holding the instance's database.
+// Entries written before remote databases were supported are local.
+func (i Instance) databaseServer() string {
+	host, port := strings.ToLower(i.DatabaseHost), i.DatabasePort
+	if host == "" || host == "localhost" {
+		host = "127.0.0.1"
+	}
+	if port == 0 {
+		port = 3306
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// RegistryPath is the JSON file that lists installed instances.
+func RegistryPath() string {
+	base := os.Getenv("ProgramData")
+	if base == "" {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "YachtCRM-DMS", "instances.json")
+}
+
+// Registry is the set of installed instances keyed by name.
+type Registry struct {
+	path      string
+	Instances map[string]Instance `json:"instances"`
+}
+
+// Load reads the registry, returning an empty one if the file does not
+// exist yet.
+func Load() (*Registry, error) {
+	reg := &Registry{path: RegistryPath(), Instances: make(map[string]Instance)}
+	data, err := os.ReadFile(reg.path)
+	if os.IsNotExist(err) {
+		return reg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read instance registry: %w", err)
+	}
+	if err := json.Unmarshal(data, reg); err != nil {
+		return nil, fmt.Errorf("parse instance registry %s: %w", reg.path, err)
+	}
+	if reg.Instances == nil {
+		reg.Instances = make(map[string]Instance)
+	}
+	return reg, nil
+}
+
+// Save writes the registry atomically.
+func (r *Registry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("create registry directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write instance registry: %w", err)
+	}
+	return os.Rename(tmp, r.path)
+}
+
+func (r *Registry) Get(name string) (Instance, error) {
+	inst, ok := r.Instances[name]
+	if !ok {
+		return Instance{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return inst, nil
+}
+
+// Put adds or replaces an instance, stamping its timestamps.
+func (r *Registry) Put(inst Instance) {
+	now := time.Now().UTC()
+	if existing, ok := r.Instances[inst.Name]; ok && !existing.CreatedAt.IsZero() {
+		inst.CreatedAt = existing.CreatedAt
+	}
+	if inst.CreatedAt.IsZero() {
+		inst.CreatedAt = now
+	}
+	inst.UpdatedAt = now
+	r.Instances[inst.Name] = inst
+}
+
+func (r *Registry) Delete(name string) {
+	delete(r.Instances, name)
+}
+
+// List returns the instances sorted by name.
+func (r *Registry) List() []Instance {
+	list := make([]Instance, 0, len(r.Instances))
+	for _, inst := range r.Instances {
+		list = append(list, inst)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Conflicts reports other registered instances that would share resources
+// with inst: the same binding, runtime directory, database or site.
+func (r *Registry) Conflicts(inst Instance) []string {
+	var conflicts []string
+	for _, other := range r.List() {
+		if other.Name == inst.Name {
+			continue
+		}
+		if other.Port == inst.Port && strings.EqualFold(other.HostName, inst.HostName) {
+			conflicts = append(conflicts, fmt.Sprintf("port %d with host header %q is used by instance %s", inst.Port, inst.HostName, other.Name))
+		}
+		if strings.EqualFold(filepath.Clean(other.RuntimeDir), filepath.Clean(inst.RuntimeDir)) {
+			conflicts = append(conflicts, fmt.Sprintf("runtime directory %s is used by instance %s", inst.RuntimeDir, other.Name))
+		}
+		if other.DatabaseName == inst.DatabaseName && other.databaseServer() == inst.databaseServer() {
+			conflicts = append(conflicts, fmt.Sprintf("database %s is used by instance %s", inst.DatabaseName, other.Name))
+		}
+		if strings.EqualFold(other.SiteName, inst.SiteName) {
+			conflicts = append(conflicts, fmt.Sprintf("IIS site %s is used by instance %s", inst.SiteName, other.Name))
+		}
+	}
+	return conflicts
+}
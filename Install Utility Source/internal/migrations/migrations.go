@@ -0,0 +1,124 @@
+// Package migrations compares the Laravel migrations shipped with the code
+// against those recorded in the database.
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"yachtcrm-installer/internal/database"
+)
+
+// vendorDirs are package migration directories, relative to vendor/, that
+// Laravel loads alongside database/migrations.
+var vendorDirs = []string{
+	filepath.Join("laravel", "sanctum", "database", "migrations"),
+}
+
+// Applied is one row of the migrations table.
+type Applied struct {
+	Name  string
+	Batch int
+}
+
+// Status is the result of comparing code and database.
+type Status struct {
+	Applied []Applied
+	// Pending exist in the code but have not run.
+	Pending []string
+	// Unknown are recorded in the database but missing from the code,
+	// which usually means the code is older than the database.
+	Unknown []string
+}
+
+// Files lists migration names, without .php, available to artisan in
+// backendDir, sorted in the order Laravel runs them.
+func Files(backendDir string) ([]string, error) {
+	dirs := []string{filepath.Join(backendDir, "database", "migrations")}
+	for _, dir := range vendorDirs {
+		dirs = append(dirs, filepath.Join(backendDir, "vendor", dir))
+	}
+
+	var names []string
+	for i, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if i > 0 && os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("read migrations: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".php") {
+				names = append(names, strings.TrimSuffix(entry.Name(), ".php"))
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ReadApplied returns the rows of the migrations table, or none if the
+// table does not exist yet.
+func ReadApplied(db *database.DB) ([]Applied, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'migrations'", nil, &count); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.Query("SELECT migration, batch FROM migrations ORDER BY batch, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var applied []Applied
+	for rows.Next() {
+		var a Applied
+		if err := rows.Scan(&a.Name, &a.Batch); err != nil {
+			return nil, fmt.Errorf("read migrations table: %w", err)
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+// Compare classifies the migrations in files and applied.
+func Compare(files []string, applied []Applied) Status {
+	known := make(map[string]bool, len(files))
+	for _, name := range files {
+		known[name] = true
+	}
+	ran := make(map[string]bool, len(applied))
+	status := Status{Applied: applied}
+	for _, a := range applied {
+		ran[a.Name] = true
+		if !known[a.Name] {
+			status.Unknown = append(status.Unknown, a.Name)
+		}
+	}
+	for _, name := range files {
+		if !ran[name] {
+			status.Pending = append(status.Pending, name)
+		}
+	}
+	return status
+}
+
+// Check reads both sides and compares them.
+func Check(db *database.DB, backendDir string) (Status, error) {
+	files, err := Files(backendDir)
+	if err != nil {
+		return Status{}, err
+	}
+	applied, err := ReadApplied(db)
+	if err != nil {
+		return Status{}, err
+	}
+	return Compare(files, applied), nil
+}
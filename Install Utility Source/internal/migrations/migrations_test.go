@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		applied []string
+		pending []string
+		unknown []string
+	}{
+		{
+			name:    "fresh database",
+			files:   []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table"},
+			pending: []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table"},
+		},
+		{
+			name:    "up to date",
+			files:   []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table"},
+			applied: []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table"},
+		},
+		{
+			name:    "newer code",
+			files:   []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table", "2024_03_01_000000_add_hull_to_boats"},
+			applied: []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table"},
+			pending: []string{"2024_03_01_000000_add_hull_to_boats"},
+		},
+		{
+			name:    "newer database",
+			files:   []string{"2014_10_12_000000_create_users_table"},
+			applied: []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table"},
+			unknown: []string{"2024_01_05_000000_create_boats_table"},
+		},
+		{
+			name:    "both",
+			files:   []string{"2014_10_12_000000_create_users_table", "2024_02_01_000000_create_invoices_table"},
+			applied: []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table"},
+			pending: []string{"2024_02_01_000000_create_invoices_table"},
+			unknown: []string{"2024_01_05_000000_create_boats_table"},
+		},
+	}
+	for _, tt := range tests {
+		var applied []Applied
+		for i, name := range tt.applied {
+			applied = append(applied, Applied{Name: name, Batch: i + 1})
+		}
+		status := Compare(tt.files, applied)
+		if !slices.Equal(status.Pending, tt.pending) || !slices.Equal(status.Unknown, tt.unknown) {
+			t.Errorf("%s: pending %q, unknown %q, want pending %q, unknown %q", tt.name, status.Pending, status.Unknown, tt.pending, tt.unknown)
+		}
+		if len(status.Applied) != len(applied) {
+			t.Errorf("%s: %d applied, want %d", tt.name, len(status.Applied), len(applied))
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	backend := t.TempDir()
+	if _, err := Files(backend); err == nil {
+		t.Error("Files without database/migrations succeeded, want an error")
+	}
+
+	write := func(dir string, names ...string) {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	appDir := filepath.Join(backend, "database", "migrations")
+	write(appDir, "2024_01_05_000000_create_boats_table.php", "2014_10_12_000000_create_users_table.php", "README.md")
+	write(filepath.Join(appDir, "archive"))
+
+	// Without vendor/ the application's migrations are all there is.
+	got, err := Files(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2014_10_12_000000_create_users_table", "2024_01_05_000000_create_boats_table"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Files = %q, want %q", got, want)
+	}
+
+	write(filepath.Join(backend, "vendor", "laravel", "sanctum", "database", "migrations"), "2019_12_14_000001_create_personal_access_tokens_table.php")
+	got, err = Files(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"2014_10_12_000000_create_users_table", "2019_12_14_000001_create_personal_access_tokens_table", "2024_01_05_000000_create_boats_table"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Files with Sanctum = %q, want %q", got, want)
+	}
+}
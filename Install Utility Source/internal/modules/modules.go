@@ -0,0 +1,143 @@
+// Package modules switches the CRM's optional product modules on and off in
+// the modules and navigation_order tables.
+package modules
+
+import (
+	"fmt"
+	"strings"
+
+	"yachtcrm-installer/internal/database"
+)
+
+// Module is a row of the modules table.
+type Module struct {
+	Key          string
+	Name         string
+	Description  string
+	Enabled      bool
+	DisplayOrder int
+}
+
+// Defaults are the modules ModuleSeeder creates, in display order.
+var Defaults = []Module{
+	{Key: "yacht", Name: "Yacht Management", Description: "Manage yachts, maintenance records, and yacht-specific services", Enabled: true, DisplayOrder: 1},
+	{Key: "dms", Name: "Vehicle/RV DMS", Description: "Dealer Management System for vehicles and RVs", DisplayOrder: 2},
+	{Key: "timeclock", Name: "Timeclock", Description: "Employee time tracking and reporting system", DisplayOrder: 3},
+	{Key: "accounting", Name: "Accounting", Description: "Full accounting system with QuickBooks-style interface", DisplayOrder: 4},
+}
+
+// navItems lists the navigation_order item keys each module owns, as the
+// frontend's Navigation component assigns them.
+var navItems = map[string][]string{
+	"yacht":      {"yachts", "maintenance"},
+	"dms":        {"vehicles", "work-orders"},
+	"timeclock":  {"timeclock"},
+	"accounting": {"accounting"},
+}
+
+// Keys returns the module keys in display order.
+func Keys() []string {
+	keys := make([]string, len(Defaults))
+	for i, m := range Defaults {
+		keys[i] = m.Key
+	}
+	return keys
+}
+
+// Validate reports an error for any key that is not a known module.
+func Validate(keys []string) error {
+	for _, k := range keys {
+		if _, ok := navItems[k]; !ok {
+			return fmt.Errorf("unknown module %q (expected %s)", k, strings.Join(Keys(), ", "))
+		}
+	}
+	return nil
+}
+
+// Parse splits a comma- or space-separated module list. "none" selects no
+// modules.
+func Parse(value string) ([]string, error) {
+	keys := []string{}
+	for _, k := range strings.FieldsFunc(strings.ToLower(value), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if k != "none" {
+			keys = append(keys, k)
+		}
+	}
+	return keys, Validate(keys)
+}
+
+// List returns the modules table in display order.
+func List(db *database.DB) ([]Module, error) {
+	rows, err := db.Query("SELECT `key`, name, COALESCE(description, ''), enabled, display_order FROM modules ORDER BY display_order, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Module
+	for rows.Next() {
+		var m Module
+		if err := rows.Scan(&m.Key, &m.Name, &m.Description, &m.Enabled, &m.DisplayOrder); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// Set enables or disables the given modules and shows or hides their
+// navigation entries for every role. Modules missing from the table, as in
+// dumps taken before the seeder ran, are created first.
+func Set(db *database.DB, keys []string, enabled bool) error {
+	if err := Validate(keys); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, m := range Defaults {
+		if _, err := tx.Exec("INSERT IGNORE INTO modules (`key`, name, description, enabled, display_order, created_at, updated_at) VALUES (?, ?, ?, ?, ?, NOW(), NOW())",
+			m.Key, m.Name, m.Description, m.Enabled, m.DisplayOrder); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("add module %s: %w", m.Key, err)
+		}
+	}
+	for _, k := range keys {
+		if _, err := tx.Exec("UPDATE modules SET enabled = ?, updated_at = NOW() WHERE `key` = ?", enabled, k); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("update module %s: %w", k, err)
+		}
+		items := navItems[k]
+		args := []any{enabled}
+		for _, item := range items {
+			args = append(args, item)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(items)), ",")
+		if _, err := tx.Exec("UPDATE navigation_order SET is_visible = ?, updated_at = NOW() WHERE item_key IN ("+placeholders+")", args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("update navigation for %s: %w", k, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Apply makes exactly the given modules enabled.
+func Apply(db *database.DB, enabled []string) error {
+	if err := Validate(enabled); err != nil {
+		return err
+	}
+	on := map[string]bool{}
+	for _, k := range enabled {
+		on[k] = true
+	}
+	var off []string
+	for _, k := range Keys() {
+		if !on[k] {
+			off = append(off, k)
+		}
+	}
+	if err := Set(db, off, false); err != nil {
+		return err
+	}
+	return Set(db, enabled, true)
+}
@@ -0,0 +1,191 @@
+// Package phpconfig holds the php.ini profiles the installer applies.
+package phpconfig
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	// PHP takes IANA time zone names, which Windows does not ship.
+	_ "time/tzdata"
+)
+
+// DefaultProfile is used when no profile is chosen.
+const DefaultProfile = "production"
+
+// Settings choose the php.ini configuration of an install.
+type Settings struct {
+	// Profile is production, development or high-memory; empty is
+	// production.
+	Profile string `json:"profile"`
+	// Timezone is date.timezone, e.g. "America/New_York"; empty is UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Extensions are enabled in addition to the profile's.
+	Extensions []string `json:"extensions,omitempty"`
+	// Values are php.ini directives set after the profile's, e.g.
+	// {"memory_limit": "512M"}.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// Config is the resolved php.ini configuration.
+type Config struct {
+	// Extensions are loaded with extension=, ZendExtensions with
+	// zend_extension=.
+	Extensions     []string
+	ZendExtensions []string
+	// Values are php.ini directives in the order they are written.
+	Values [][2]string
+}
+
+var baseExtensions = []string{"curl", "fileinfo", "gd", "mbstring", "openssl", "pdo_mysql", "zip", "bcmath", "intl", "exif"}
+
+// base is shared by every profile.
+var base = [][2]string{
+	{"memory_limit", "256M"},
+	{"max_execution_time", "300"},
+	{"max_input_vars", "3000"},
+	{"upload_max_filesize", "20M"},
+	{"post_max_size", "25M"},
+	{"max_file_uploads", "20"},
+	{"date.timezone", "UTC"},
+	// FastCGI workers resolve the same include paths on every request.
+	{"realpath_cache_size", "4096K"},
+	{"realpath_cache_ttl", "600"},
+	{"session.gc_maxlifetime", "7200"},
+	{"session.cookie_httponly", "1"},
+	{"session.use_strict_mode", "1"},
+	{"opcache.enable", "1"},
+	{"opcache.enable_cli", "0"},
+	{"opcache.memory_consumption", "256"},
+	{"opcache.interned_strings_buffer", "16"},
+	// The backend and its vendor directory hold well over 10,000 PHP
+	// files.
+	{"opcache.max_accelerated_files", "20000"},
+	// Upgrades replace files in place, so cached scripts are rechecked.
+	{"opcache.validate_timestamps", "1"},
+	{"opcache.revalidate_freq", "60"},
+	{"display_errors", "Off"},
+	{"display_startup_errors", "Off"},
+	{"log_errors", "On"},
+	{"error_reporting", "E_ALL & ~E_DEPRECATED & ~E_STRICT"},
+	{"expose_php", "Off"},
+}
+
+// profiles hold each profile's changes to base.
+var profiles = map[string][][2]string{
+	"production": nil,
+	"development": {
+		{"display_errors", "On"},
+		{"display_startup_errors", "On"},
+		{"error_reporting", "E_ALL"},
+		{"opcache.revalidate_freq", "0"},
+	},
+	"high-memory": {
+		{"memory_limit", "1024M"},
+		{"max_execution_time", "600"},
+		{"upload_max_filesize", "100M"},
+		{"post_max_size", "110M"},
+		{"realpath_cache_size", "8192K"},
+		{"opcache.memory_consumption", "512"},
+		{"opcache.interned_strings_buffer", "32"},
+	},
+}
+
+// Profiles lists the profile names.
+func Profiles() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	validExtension = regexp.MustCompile(`^[a-z0-9_]+$`)
+	validKey       = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+)
+
+// Validate checks the profile name, time zone, extensions and values.
+func (s Settings) Validate() error {
+	if _, ok := profiles[s.ProfileName()]; !ok {
+		return fmt.Errorf("unknown PHP profile %q (expected %s)", s.Profile, strings.Join(Profiles(), ", "))
+	}
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "Local" {
+			return fmt.Errorf("unknown time zone %q", s.Timezone)
+		}
+	}
+	for _, ext := range s.Extensions {
+		if !validExtension.MatchString(ext) {
+			return fmt.Errorf("invalid PHP extension name %q", ext)
+		}
+	}
+	for key, value := range s.Values {
+		if !validKey.MatchString(key) {
+			return fmt.Errorf("invalid php.ini directive %q", key)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("php.ini value for %s must be a single line", key)
+		}
+		// php.ini has no escape for a double quote in a quoted value.
+		if strings.Contains(value, `"`) {
+			return fmt.Errorf("php.ini value for %s must not contain a double quote", key)
+		}
+	}
+	return nil
+}
+
+// ProfileName is the profile the settings use.
+func (s Settings) ProfileName() string {
+	if s.Profile == "" {
+		return DefaultProfile
+	}
+	return s.Profile
+}
+
+// Resolve returns the configuration to write: the base settings, the
+// profile's changes, the time zone and then the settings' own extensions
+// and values.
+func (s Settings) Resolve() (Config, error) {
+	if err := s.Validate(); err != nil {
+		return Config{}, err
+	}
+	c := Config{
+		Extensions:     slices.Clone(baseExtensions),
+		ZendExtensions: []string{"opcache"},
+		Values:         slices.Clone(base),
+	}
+	for _, kv := range profiles[s.ProfileName()] {
+		c.set(kv[0], kv[1])
+	}
+	if s.Timezone != "" {
+		c.set("date.timezone", s.Timezone)
+	}
+	for _, ext := range s.Extensions {
+		if !slices.Contains(c.Extensions, ext) {
+			c.Extensions = append(c.Extensions, ext)
+		}
+	}
+	keys := make([]string, 0, len(s.Values))
+	for key := range s.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c.set(key, s.Values[key])
+	}
+	return c, nil
+}
+
+func (c *Config) set(key, value string) {
+	for i, kv := range c.Values {
+		if kv[0] == key {
+			c.Values[i][1] = value
+			return
+		}
+	}
+	c.Values = append(c.Values, [2]string{key, value})
+}
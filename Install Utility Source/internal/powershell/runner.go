@@ -31,3 +31,8 @@ func Run(command string) Result {
 		Err:    err,
 	}
 }
+
+// Quote returns value as a single-quoted PowerShell string literal.
+func Quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
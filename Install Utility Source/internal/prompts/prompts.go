@@ -5,33 +5,89 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+
+	"golang.org/x/term"
 )
 
 var reader = bufio.NewReader(os.Stdin)
 
+// asking holds one question at a time, retries included. The runner keeps
+// whole interactive steps apart; asking covers a single question asked
+// from anywhere else.
+var asking sync.Mutex
+
+// Prompter answers questions in place of the console, for example inside the
+// terminal UI. Ask receives the full prompt text and must not echo the
+// answer when secret is set; Notify shows validation feedback.
+type Prompter interface {
+	Ask(prompt string, secret bool) (string, error)
+	Notify(message string)
+}
+
+var prompter Prompter
+
+// SetPrompter routes every question through p. Passing nil restores the
+// console.
+func SetPrompter(p Prompter) {
+	prompter = p
+}
+
+func ask(prompt string, secret bool) (string, error) {
+	if prompter != nil {
+		value, err := prompter.Ask(prompt, secret)
+		return strings.TrimSpace(value), err
+	}
+
+	fmt.Print(prompt)
+	if secret && term.IsTerminal(int(os.Stdin.Fd())) {
+		value, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		return strings.TrimSpace(string(value)), err
+	}
+	value, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(value), nil
+}
+
+func notify(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if prompter != nil {
+		prompter.Notify(msg)
+		return
+	}
+	fmt.Println(msg)
+}
+
 func AskString(question string, required bool) (string, error) {
 	return AskStringDefault(question, "", required)
 }
 
 func AskStringDefault(question, def string, required bool) (string, error) {
+	asking.Lock()
+	defer asking.Unlock()
+	return askDefault(question, def, required, false)
+}
+
+func askDefault(question, def string, required, secret bool) (string, error) {
 	prompt := question
 	if def != "" {
 		prompt = fmt.Sprintf("%s [%s]", question, def)
 	}
 
 	for {
-		fmt.Printf("%s: ", prompt)
-		value, err := reader.ReadString('\n')
+		value, err := ask(prompt+": ", secret)
 		if err != nil {
 			return "", err
 		}
-		value = strings.TrimSpace(value)
 		if value == "" {
 			if def != "" {
 				return def, nil
 			}
 			if required {
-				fmt.Println("This value is required.")
+				notify("This value is required.")
 				continue
 			}
 		}
@@ -39,10 +95,54 @@ func AskStringDefault(question, def string, required bool) (string, error) {
 	}
 }
 
+// AskValidated prompts until the answer passes validate. Empty optional
+// answers are returned without validation.
+func AskValidated(question, def string, required bool, validate func(string) error) (string, error) {
+	asking.Lock()
+	defer asking.Unlock()
+	for {
+		value, err := askDefault(question, def, required, false)
+		if err != nil {
+			return "", err
+		}
+		if value == "" || validate == nil {
+			return value, nil
+		}
+		if err := validate(value); err != nil {
+			notify("Invalid value: %v", err)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// AskPassword reads a required value without echoing it when stdin is a
+// console (or the terminal UI is active). Redirected input is read as-is.
 func AskPassword(question string) (string, error) {
-	// PowerShell host does not easily support masking without heavy interop.
-	// For now prompt normally but redact the value in logs.
-	return AskString(fmt.Sprintf("%s (input hidden in logs)", question), true)
+	asking.Lock()
+	defer asking.Unlock()
+	return askDefault(question, "", true, true)
+}
+
+// AskSecret is AskPassword for values that may be optional and must pass
+// validate, such as API keys.
+func AskSecret(question string, required bool, validate func(string) error) (string, error) {
+	asking.Lock()
+	defer asking.Unlock()
+	for {
+		value, err := askDefault(question, "", required, true)
+		if err != nil {
+			return "", err
+		}
+		if value == "" || validate == nil {
+			return value, nil
+		}
+		if err := validate(value); err != nil {
+			notify("Invalid value: %v", err)
+			continue
+		}
+		return value, nil
+	}
 }
 
 func Confirm(question string, defaultYes bool) (bool, error) {
@@ -51,13 +151,14 @@ func Confirm(question string, defaultYes bool) (bool, error) {
 		def = "[y/N]"
 	}
 
+	asking.Lock()
+	defer asking.Unlock()
 	for {
-		fmt.Printf("%s %s ", question, def)
-		value, err := reader.ReadString('\n')
+		value, err := ask(fmt.Sprintf("%s %s ", question, def), false)
 		if err != nil {
 			return false, err
 		}
-		value = strings.TrimSpace(strings.ToLower(value))
+		value = strings.ToLower(value)
 		if value == "" {
 			return defaultYes, nil
 		}
@@ -67,7 +168,7 @@ func Confirm(question string, defaultYes bool) (bool, error) {
 		case "n", "no":
 			return false, nil
 		default:
-			fmt.Println("Please answer yes or no.")
+			notify("Please answer yes or no.")
 		}
 	}
 }
@@ -0,0 +1,132 @@
+package sanitize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// digest is the keyed hash of an original value. Fake values are derived
+// from it alone, so the same input always maps to the same output within
+// a salt, and the output reveals nothing about the input without the salt.
+type digest [sha256.Size]byte
+
+func newDigest(salt []byte, kind, value string) digest {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	var d digest
+	copy(d[:], mac.Sum(nil))
+	return d
+}
+
+// n returns the i'th 32-bit word of the digest, i in [0, 8).
+func (d digest) n(i int) int {
+	return int(binary.BigEndian.Uint32(d[i*4:]) & 0x7fffffff)
+}
+
+// stream returns at least size bytes derived from the digest.
+func (d digest) stream(size int) []byte {
+	out := make([]byte, 0, size+sha256.Size)
+	block := d
+	for len(out) < size {
+		out = append(out, block[:]...)
+		block = sha256.Sum256(block[:])
+	}
+	return out[:size]
+}
+
+func pick(d digest, i int, words []string) string {
+	return words[d.n(i)%len(words)]
+}
+
+// unusablePassword is not a valid bcrypt hash, so password_verify rejects
+// every password against it.
+const unusablePassword = "$2y$10$sanitized.account.password.disabled.use.reset.link.."
+
+func fakeName(d digest) string {
+	return pick(d, 0, firstNames) + " " + pick(d, 1, lastNames)
+}
+
+func fakeCompany(d digest) string {
+	return pick(d, 0, companyWords) + " " + pick(d, 1, companyNouns) + " " + pick(d, 2, companySuffixes)
+}
+
+// fakeEmail includes part of the digest so distinct addresses stay distinct
+// under unique keys.
+func fakeEmail(d digest) string {
+	return fmt.Sprintf("%s.%s.%s@example.com",
+		strings.ToLower(pick(d, 0, firstNames)), strings.ToLower(pick(d, 1, lastNames)), hex.EncodeToString(d[28:]))
+}
+
+// fakePhone uses the 555-01xx range reserved for fiction.
+func fakePhone(d digest) string {
+	return fmt.Sprintf("(%03d) 555-01%02d", 200+d.n(2)%800, d.n(3)%100)
+}
+
+func fakeAddress(d digest) string {
+	return fmt.Sprintf("%d %s %s", 1+d.n(2)%9899, pick(d, 0, streets), pick(d, 1, streetSuffixes))
+}
+
+func fakeWebsite(d digest) string {
+	return fmt.Sprintf("https://www.%s-%s.example", strings.ToLower(pick(d, 0, companyWords)), strings.ToLower(pick(d, 1, companyNouns)))
+}
+
+// fakeDigits replaces every digit and keeps separators, so formatted
+// account and tax numbers keep their shape.
+func fakeDigits(d digest, orig string) string {
+	stream := d.stream(len(orig))
+	b := []byte(orig)
+	for i, c := range b {
+		if c >= '0' && c <= '9' {
+			b[i] = '0' + stream[i]%10
+		}
+	}
+	return string(b)
+}
+
+func fakeToken(d digest, size int) string {
+	if size <= 0 {
+		size = 32
+	}
+	return hex.EncodeToString(d.stream((size + 1) / 2))[:size]
+}
+
+var firstNames = []string{
+	"Alex", "Avery", "Blake", "Cameron", "Casey", "Dana", "Drew", "Elliot",
+	"Emerson", "Finley", "Harper", "Hayden", "Jamie", "Jordan", "Kendall", "Logan",
+	"Morgan", "Parker", "Quinn", "Reese", "Riley", "Rowan", "Sawyer", "Taylor",
+}
+
+var lastNames = []string{
+	"Anchor", "Bayliss", "Brooks", "Carver", "Dalton", "Ellison", "Fairway", "Graves",
+	"Harbor", "Islay", "Jetty", "Keel", "Lowell", "Marsh", "Norland", "Oakes",
+	"Pierce", "Quay", "Rigby", "Shoal", "Tidewell", "Upton", "Vale", "Whitlock",
+}
+
+var streets = []string{
+	"Harbor", "Marina", "Lighthouse", "Bayview", "Seaside", "Pelican", "Mariner", "Cove",
+	"Dockside", "Compass", "Gull", "Sandpiper", "Tide", "Wharf", "Coral", "Beacon",
+}
+
+var streetSuffixes = []string{"St", "Ave", "Rd", "Ln", "Dr", "Way", "Ct", "Blvd"}
+
+var cities = []string{
+	"Port Ellis", "Bay Harbor", "Cedar Point", "Gull Island", "Northshore", "Saltwater",
+	"Seabrook", "South Landing", "Stillwater", "Westport", "Whitecap", "Windward",
+}
+
+var states = []string{"FL", "GA", "SC", "NC", "VA", "MD", "NJ", "NY", "CT", "RI", "MA", "ME", "TX", "LA", "AL", "CA", "WA", "MI"}
+
+var companyWords = []string{
+	"Blue", "Coastal", "Harbor", "Keystone", "Meridian", "Northstar", "Pacific", "Summit",
+	"Tidal", "Trident", "Atlas", "Pinnacle",
+}
+
+var companyNouns = []string{"Marine", "Supply", "Rigging", "Electric", "Canvas", "Fuel", "Parts", "Services", "Outfitters", "Engines"}
+
+var companySuffixes = []string{"LLC", "Inc", "Co", "Group", "Ltd"}
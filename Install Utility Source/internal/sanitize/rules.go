@@ -0,0 +1,149 @@
+// Package sanitize anonymizes a CRM database export so it can be shared
+// and used to seed new installs without customer data.
+package sanitize
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Rules say which tables are exported without rows and which columns are
+// replaced by which generator.
+type Rules struct {
+	Truncate []string                     `json:"truncate"`
+	Tables   map[string]map[string]string `json:"tables"`
+}
+
+// DefaultRules covers the tables of the stock schema that hold personal,
+// payment or credential data.
+const DefaultRules = `{
+  "truncate": [
+    "sessions",
+    "personal_access_tokens",
+    "password_reset_tokens",
+    "jobs",
+    "failed_jobs",
+    "job_batches",
+    "cache",
+    "cache_locks"
+  ],
+  "tables": {
+    "users": {
+      "name": "name",
+      "email": "email",
+      "password": "password",
+      "remember_token": "null"
+    },
+    "mfa_settings": {
+      "totp_secret": "null",
+      "recovery_codes": "null"
+    },
+    "customers": {
+      "name": "name",
+      "email": "email",
+      "phone": "phone",
+      "address": "address",
+      "city": "city",
+      "state": "state",
+      "zip": "zip",
+      "billing_address": "address",
+      "billing_city": "city",
+      "billing_state": "state",
+      "billing_zip": "zip",
+      "notes": "redact"
+    },
+    "vendors": {
+      "vendor_name": "company",
+      "company_name": "company",
+      "contact_person": "name",
+      "email": "email",
+      "phone": "phone",
+      "website": "website",
+      "address": "address",
+      "city": "city",
+      "state": "state",
+      "zip": "zip",
+      "account_number": "digits",
+      "tax_id": "digits",
+      "notes": "redact"
+    },
+    "payments": {
+      "provider_transaction_id": "token",
+      "notes": "redact"
+    },
+    "bank_accounts": {
+      "account_number": "digits",
+      "routing_number": "digits"
+    },
+    "email_log": {
+      "recipient_email": "email",
+      "error_message": "redact"
+    }
+  }
+}
+`
+
+// generators maps rule names to functions of the original value's digest
+// and the original value.
+var generators = map[string]func(h digest, orig string) *string{
+	"name":     func(h digest, _ string) *string { return str(fakeName(h)) },
+	"company":  func(h digest, _ string) *string { return str(fakeCompany(h)) },
+	"email":    func(h digest, _ string) *string { return str(fakeEmail(h)) },
+	"phone":    func(h digest, _ string) *string { return str(fakePhone(h)) },
+	"address":  func(h digest, _ string) *string { return str(fakeAddress(h)) },
+	"city":     func(h digest, _ string) *string { return str(pick(h, 3, cities)) },
+	"state":    func(h digest, _ string) *string { return str(pick(h, 4, states)) },
+	"zip":      func(h digest, _ string) *string { return str(fmt.Sprintf("%05d", h.n(5)%100000)) },
+	"website":  func(h digest, _ string) *string { return str(fakeWebsite(h)) },
+	"digits":   func(h digest, orig string) *string { return str(fakeDigits(h, orig)) },
+	"token":    func(h digest, orig string) *string { return str(fakeToken(h, len(orig))) },
+	"password": func(digest, string) *string { return str(unusablePassword) },
+	"redact":   func(digest, string) *string { return str("[redacted]") },
+	"empty":    func(digest, string) *string { return str("") },
+	"null":     func(digest, string) *string { return nil },
+	"keep":     func(_ digest, orig string) *string { return &orig },
+}
+
+func str(s string) *string { return &s }
+
+// Generators returns the rule names a column can use.
+func Generators() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseRules decodes and checks a rules document.
+func ParseRules(data []byte) (Rules, error) {
+	var r Rules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Rules{}, fmt.Errorf("parse rules: %w", err)
+	}
+	for table, columns := range r.Tables {
+		for column, gen := range columns {
+			if _, ok := generators[gen]; !ok {
+				return Rules{}, fmt.Errorf("%s.%s: unknown generator %q (expected one of %s)", table, column, gen, strings.Join(Generators(), ", "))
+			}
+		}
+	}
+	return r, nil
+}
+
+// LoadRules reads a rules file, or returns the default rules when path is
+// empty.
+func LoadRules(path string) (Rules, error) {
+	if path == "" {
+		return ParseRules([]byte(DefaultRules))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, err
+	}
+	return ParseRules(data)
+}
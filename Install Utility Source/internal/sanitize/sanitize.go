@@ -0,0 +1,149 @@
+package sanitize
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"yachtcrm-installer/internal/database"
+)
+
+// Sanitizer rewrites dumped rows according to a set of rules.
+type Sanitizer struct {
+	rules Rules
+	salt  []byte
+	// plans caches, per table, the generator for each column position.
+	plans map[string][]string
+}
+
+// New returns a Sanitizer. An empty salt is replaced with a random one, so
+// the fake values are consistent within one export but cannot be matched
+// across exports.
+func New(rules Rules, salt string) (*Sanitizer, error) {
+	key := []byte(salt)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &Sanitizer{rules: rules, salt: key, plans: map[string][]string{}}, nil
+}
+
+// SchemaOnly returns the tables exported without rows.
+func (s *Sanitizer) SchemaOnly() map[string]bool {
+	tables := make(map[string]bool, len(s.rules.Truncate))
+	for _, t := range s.rules.Truncate {
+		tables[t] = true
+	}
+	return tables
+}
+
+// Check compares the rules with the connected database. Rules for columns
+// that do not exist are errors, since a typo would leave data in clear
+// text; tables the database lacks are returned as warnings. Primary and
+// foreign key columns are refused so references between rows survive.
+func (s *Sanitizer) Check(db *database.DB) (warnings []string, err error) {
+	// columns maps table and column to whether the column is nullable.
+	columns := map[string]map[string]bool{}
+	rows, err := db.Query("SELECT TABLE_NAME, COLUMN_NAME, IS_NULLABLE = 'YES' FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE()")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var table, column string
+		var nullable bool
+		if err := rows.Scan(&table, &column, &nullable); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if columns[table] == nil {
+			columns[table] = map[string]bool{}
+		}
+		columns[table][column] = nullable
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	rows, err = db.Query("SELECT TABLE_NAME, COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND (CONSTRAINT_NAME = 'PRIMARY' OR REFERENCED_TABLE_NAME IS NOT NULL) " +
+		"UNION SELECT REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys[table+"."+column] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, table := range s.rules.Truncate {
+		if columns[table] == nil {
+			warnings = append(warnings, fmt.Sprintf("table %s to truncate does not exist", table))
+		}
+	}
+	for table, rules := range s.rules.Tables {
+		if columns[table] == nil {
+			warnings = append(warnings, fmt.Sprintf("table %s has rules but does not exist", table))
+			continue
+		}
+		for column, gen := range rules {
+			nullable, exists := columns[table][column]
+			switch {
+			case !exists:
+				problems = append(problems, fmt.Sprintf("%s.%s does not exist", table, column))
+			case keys[table+"."+column]:
+				problems = append(problems, fmt.Sprintf("%s.%s is a key column and cannot be rewritten", table, column))
+			case gen == "null" && !nullable:
+				problems = append(problems, fmt.Sprintf("%s.%s is NOT NULL and cannot use the null generator", table, column))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return warnings, fmt.Errorf("rules do not match the database:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return warnings, nil
+}
+
+// Transform rewrites one row in place. It has the signature of
+// database.DumpOptions.Transform.
+func (s *Sanitizer) Transform(table string, columns []string, values []sql.RawBytes) error {
+	rules := s.rules.Tables[table]
+	if len(rules) == 0 {
+		return nil
+	}
+	plan, ok := s.plans[table]
+	if !ok {
+		plan = make([]string, len(columns))
+		for i, c := range columns {
+			plan[i] = rules[c]
+		}
+		s.plans[table] = plan
+	}
+	for i, gen := range plan {
+		if gen == "" || values[i] == nil {
+			continue
+		}
+		orig := string(values[i])
+		// Generators share a digest per kind of value, so the same person
+		// or address gets the same fake in every table.
+		d := newDigest(s.salt, gen, strings.ToLower(strings.TrimSpace(orig)))
+		if v := generators[gen](d, orig); v == nil {
+			values[i] = nil
+		} else {
+			values[i] = sql.RawBytes(*v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package sanitize
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+const testRules = `{
+  "tables": {
+    "customers": {
+      "name": "name",
+      "email": "email",
+      "phone": "phone",
+      "notes": "null",
+      "tax_id": "digits",
+      "boat": "keep"
+    }
+  }
+}`
+
+var testColumns = []string{"id", "name", "email", "phone", "notes", "tax_id", "boat"}
+
+func testRow() []sql.RawBytes {
+	return []sql.RawBytes{
+		sql.RawBytes("7"),
+		sql.RawBytes("Jane Doe"),
+		sql.RawBytes("jane@customer.test"),
+		sql.RawBytes("+1 (305) 867-5309"),
+		sql.RawBytes("Prefers slip 12"),
+		sql.RawBytes("12-3456789"),
+		sql.RawBytes("Sea Breeze"),
+	}
+}
+
+func newTestSanitizer(t *testing.T, salt string) *Sanitizer {
+	t.Helper()
+	rules, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(rules, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func transform(t *testing.T, s *Sanitizer, row []sql.RawBytes) []sql.RawBytes {
+	t.Helper()
+	if err := s.Transform("customers", testColumns, row); err != nil {
+		t.Fatal(err)
+	}
+	return row
+}
+
+func TestTransform(t *testing.T) {
+	orig := testRow()
+	row := transform(t, newTestSanitizer(t, "salt"), testRow())
+
+	if string(row[0]) != "7" {
+		t.Errorf("id without a rule = %q, want it unchanged", row[0])
+	}
+	for _, i := range []int{1, 2, 3, 5} {
+		if string(row[i]) == string(orig[i]) {
+			t.Errorf("%s = %q, want it replaced", testColumns[i], row[i])
+		}
+	}
+	if !strings.HasSuffix(string(row[2]), "@example.com") {
+		t.Errorf("email = %q, want an example.com address", row[2])
+	}
+	if row[4] != nil {
+		t.Errorf("notes with the null generator = %q, want NULL", row[4])
+	}
+	if string(row[6]) != "Sea Breeze" {
+		t.Errorf("boat with the keep generator = %q, want it unchanged", row[6])
+	}
+}
+
+func TestTransformIsDeterministicPerSalt(t *testing.T) {
+	a := transform(t, newTestSanitizer(t, "salt"), testRow())
+	b := transform(t, newTestSanitizer(t, "salt"), testRow())
+	c := transform(t, newTestSanitizer(t, "other salt"), testRow())
+	for i, column := range testColumns {
+		if string(a[i]) != string(b[i]) {
+			t.Errorf("%s: %q and %q with the same salt, want the same fake", column, a[i], b[i])
+		}
+	}
+	for _, i := range []int{1, 2, 5} {
+		if string(a[i]) == string(c[i]) {
+			t.Errorf("%s: %q with both salts, want different fakes", testColumns[i], a[i])
+		}
+	}
+
+	// Values are compared trimmed and ignoring case, so the same customer
+	// entered twice gets the same fake.
+	s := newTestSanitizer(t, "salt")
+	row := testRow()
+	row[2] = sql.RawBytes(" JANE@Customer.test ")
+	if got := transform(t, s, row)[2]; string(got) != string(a[2]) {
+		t.Errorf("email differing in case and spaces = %q, want %q", got, a[2])
+	}
+}
+
+func TestTransformKeepsNull(t *testing.T) {
+	row := testRow()
+	for i := range row {
+		row[i] = nil
+	}
+	transform(t, newTestSanitizer(t, "salt"), row)
+	for i, v := range row {
+		if v != nil {
+			t.Errorf("NULL %s = %q, want NULL", testColumns[i], v)
+		}
+	}
+}
+
+func TestTransformIgnoresOtherTables(t *testing.T) {
+	s := newTestSanitizer(t, "salt")
+	row := testRow()
+	if err := s.Transform("boats", testColumns, row); err != nil {
+		t.Fatal(err)
+	}
+	orig := testRow()
+	for i := range row {
+		if string(row[i]) != string(orig[i]) {
+			t.Errorf("boats.%s = %q, want it unchanged", testColumns[i], row[i])
+		}
+	}
+}
+
+func TestFakeDigits(t *testing.T) {
+	d := newDigest([]byte("salt"), "digits", "x")
+	for _, orig := range []string{"12-3456789", "021000021", "4111 1111 1111 1111", "ACCT-0042/B", ""} {
+		got := fakeDigits(d, orig)
+		if len(got) != len(orig) {
+			t.Errorf("fakeDigits(%q) = %q, want the same length", orig, got)
+			continue
+		}
+		for i := range orig {
+			isDigit := orig[i] >= '0' && orig[i] <= '9'
+			if isDigit != (got[i] >= '0' && got[i] <= '9') || !isDigit && got[i] != orig[i] {
+				t.Errorf("fakeDigits(%q) = %q, want digits replaced and separators kept", orig, got)
+				break
+			}
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	if _, err := ParseRules([]byte(DefaultRules)); err != nil {
+		t.Errorf("default rules: %v", err)
+	}
+
+	_, err := ParseRules([]byte(`{"tables": {"customers": {"email": "scramble"}}}`))
+	if err == nil || !strings.Contains(err.Error(), `customers.email: unknown generator "scramble"`) {
+		t.Errorf("unknown generator: error %v, want it named with its column", err)
+	}
+
+	if _, err := ParseRules([]byte(`{"tables": [`)); err == nil {
+		t.Error("malformed rules: no error")
+	}
+}
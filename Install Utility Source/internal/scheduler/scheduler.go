@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yachtcrm-installer/internal/powershell"
+)
+
+// DefaultTaskName matches the task registered by install.ps1 so both
+// installers manage the same scheduled task.
+const DefaultTaskName = "YachtCRM Artisan Scheduler"
+
+// Result codes reported by Task Scheduler that do not indicate a failure.
+const (
+	ResultSuccess    = 0
+	ResultRunning    = 0x41301
+	ResultNeverRun   = 0x41303
+	ResultNotStarted = 0x41325
+)
+
+// ErrNotFound is returned when the named task does not exist.
+var ErrNotFound = errors.New("scheduled task not found")
+
+// Task describes the Windows scheduled task that runs `artisan schedule:run`
+// every minute.
+type Task struct {
+	Name       string
+	PhpExePath string
+	BackendDir string
+	// RunAsUser defaults to SYSTEM. Built-in service accounts do not need a
+	// password; any other account does.
+	RunAsUser     string
+	RunAsPassword string
+}
+
+// Status is the subset of Get-ScheduledTaskInfo the installer reports on.
+type Status struct {
+	Name        string
+	State       string
+	RunAsUser   string
+	LastRunTime string
+	NextRunTime string
+	LastResult  int64
+}
+
+// Healthy reports whether the last run succeeded or the task has simply not
+// run yet.
+func (s Status) Healthy() bool {
+	switch s.LastResult {
+	case ResultSuccess, ResultRunning, ResultNeverRun, ResultNotStarted:
+		return s.State != "Disabled"
+	}
+	return false
+}
+
+// IsServiceAccount reports whether user is a built-in account that runs
+// without a stored password.
+func IsServiceAccount(user string) bool {
+	switch strings.TrimPrefix(strings.ToUpper(user), `NT AUTHORITY\`) {
+	case "", "SYSTEM", "LOCAL SERVICE", "LOCALSERVICE", "NETWORK SERVICE", "NETWORKSERVICE":
+		return true
+	}
+	return false
+}
+
+// Register creates the task or replaces an existing task with the same name.
+func Register(task Task) error {
+	if task.Name == "" {
+		return errors.New("task name is empty")
+	}
+	user := task.RunAsUser
+	if user == "" {
+		user = "SYSTEM"
+	}
+
+	if !IsServiceAccount(user) && task.RunAsPassword == "" {
+		return fmt.Errorf("a password is required to run the scheduler as %s", user)
+	}
+
+	script := strings.Builder{}
+	script.WriteString(fmt.Sprintf("$action = New-ScheduledTaskAction -Execute %s -Argument 'artisan schedule:run' -WorkingDirectory %s\n",
+		powershell.Quote(task.PhpExePath), powershell.Quote(task.BackendDir)))
+	script.WriteString("$trigger = New-ScheduledTaskTrigger -Once -At (Get-Date).Date -RepetitionInterval (New-TimeSpan -Minutes 1)\n")
+	script.WriteString("$settings = New-ScheduledTaskSettingsSet -AllowStartIfOnBatteries -DontStopIfGoingOnBatteries -StartWhenAvailable -MultipleInstances IgnoreNew -ExecutionTimeLimit (New-TimeSpan -Minutes 10)\n")
+	if IsServiceAccount(user) {
+		script.WriteString(fmt.Sprintf("$principal = New-ScheduledTaskPrincipal -UserId %s -LogonType ServiceAccount -RunLevel Highest\n", powershell.Quote(user)))
+		script.WriteString(fmt.Sprintf("Register-ScheduledTask -TaskName %s -Action $action -Trigger $trigger -Settings $settings -Principal $principal -Force | Out-Null\n",
+			powershell.Quote(task.Name)))
+	} else {
+		script.WriteString(fmt.Sprintf("Register-ScheduledTask -TaskName %s -Action $action -Trigger $trigger -Settings $settings -User %s -Password %s -RunLevel Highest -Force | Out-Null\n",
+			powershell.Quote(task.Name), powershell.Quote(user), powershell.Quote(task.RunAsPassword)))
+	}
+
+	result := powershell.Run(script.String())
+	if result.Err != nil {
+		return fmt.Errorf("register scheduled task: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	return nil
+}
+
+// Remove deletes the task. Removing a task that does not exist is not an
+// error.
+func Remove(name string) error {
+	script := fmt.Sprintf(`if (Get-ScheduledTask -TaskName %[1]s -ErrorAction SilentlyContinue) { Unregister-ScheduledTask -TaskName %[1]s -Confirm:$false }`, powershell.Quote(name))
+	result := powershell.Run(script)
+	if result.Err != nil {
+		return fmt.Errorf("remove scheduled task: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	return nil
+}
+
+// Start triggers an immediate run of the task.
+func Start(name string) error {
+	result := powershell.Run(fmt.Sprintf(`Start-ScheduledTask -TaskName %s`, powershell.Quote(name)))
+	if result.Err != nil {
+		return fmt.Errorf("start scheduled task: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	return nil
+}
+
+// Query returns the task's current state and last result.
+func Query(name string) (Status, error) {
+	script := fmt.Sprintf(`$task = Get-ScheduledTask -TaskName %s -ErrorAction SilentlyContinue
+if (-not $task) { return }
+$info = $task | Get-ScheduledTaskInfo
+'{0}|{1}|{2}|{3}|{4}' -f $task.State, $task.Principal.UserId, $info.LastRunTime, $info.NextRunTime, $info.LastTaskResult`, powershell.Quote(name))
+	result := powershell.Run(script)
+	if result.Err != nil {
+		return Status{}, fmt.Errorf("query scheduled task: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	if result.Stdout == "" {
+		return Status{}, ErrNotFound
+	}
+
+	parts := strings.Split(result.Stdout, "|")
+	if len(parts) != 5 {
+		return Status{}, fmt.Errorf("unexpected scheduled task output: %s", result.Stdout)
+	}
+	lastResult, err := strconv.ParseInt(strings.TrimSpace(parts[4]), 10, 64)
+	if err != nil {
+		return Status{}, fmt.Errorf("parse last task result %q: %w", parts[4], err)
+	}
+	return Status{
+		Name:        name,
+		State:       parts[0],
+		RunAsUser:   parts[1],
+		LastRunTime: parts[2],
+		NextRunTime: parts[3],
+		LastResult:  lastResult,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package steps
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"yachtcrm-installer/internal/branding"
+	"yachtcrm-installer/internal/installer"
+)
+
+// collectBranding takes the CRM name, company profile and logos from the
+// answer file or asks for them.
+func collectBranding(ctx *installer.Context) error {
+	if ctx.Answers != nil && ctx.Answers.Branding != nil {
+		if err := ctx.Answers.Branding.Validate(); err != nil {
+			return fmt.Errorf("answer file: %w", err)
+		}
+		ctx.Branding = *ctx.Answers.Branding
+		if ctx.Branding.CRMName == "" {
+			ctx.Branding.CRMName = branding.DefaultName
+		}
+		return nil
+	}
+
+	values := map[string]string{}
+	for _, in := range brandingInputs() {
+		if !in.Applies(values) {
+			continue
+		}
+		value, err := askInput(ctx, in)
+		if err != nil {
+			return err
+		}
+		values[in.Key] = value
+	}
+	delete(values, "branding_profile")
+	var err error
+	ctx.Branding, err = branding.ProfileFromValues(values)
+	return err
+}
+
+func maxLength(n int) func(string) error {
+	return func(value string) error {
+		if len([]rune(value)) > n {
+			return fmt.Errorf("must be at most %d characters", n)
+		}
+		return nil
+	}
+}
+
+// ApplyBranding installs the logos and writes the branding settings row.
+// APP_NAME is set by ConfigureEnv.
+type ApplyBranding struct{}
+
+func (ApplyBranding) Name() string { return "Apply Branding" }
+
+func (ApplyBranding) Run(ctx *installer.Context) error {
+	p := ctx.Branding
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	paths := map[string]string{}
+	for _, field := range branding.LogoFields {
+		src, ok := p.Logos[field]
+		if !ok {
+			continue
+		}
+		rel, err := branding.InstallLogo(backendDir, field, src)
+		if err != nil {
+			return fmt.Errorf("install %s: %w", field, err)
+		}
+		paths[field] = rel
+		ctx.Logf("Installed %s from %s", field, src)
+	}
+
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := branding.Save(db, p, paths); err != nil {
+		return fmt.Errorf("save branding settings: %w", err)
+	}
+	ctx.Logf("Branding saved for %s", p.CRMName)
+	return nil
+}
@@ -0,0 +1,195 @@
+package steps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"yachtcrm-installer/internal/installer"
+)
+
+// Build stamps record the inputs a build was produced from so a rerun can
+// skip work that is already up to date.
+const (
+	composerStamp = ".installer-composer.sha256"
+	frontendStamp = ".installer-build.sha256"
+)
+
+type InstallBackendDependencies struct{}
+
+func (InstallBackendDependencies) Name() string { return "Install Backend Dependencies" }
+
+func (s InstallBackendDependencies) Run(ctx *installer.Context) error {
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	lockPath := filepath.Join(backendDir, "composer.lock")
+	vendorDir := filepath.Join(backendDir, "vendor")
+
+	hash, err := hashFiles(backendDir, []string{"composer.json", "composer.lock"})
+	if err != nil {
+		return fmt.Errorf("hash composer files: %w", err)
+	}
+	if fileExists(filepath.Join(vendorDir, "autoload.php")) && readStamp(filepath.Join(vendorDir, composerStamp)) == hash {
+		ctx.Logf("vendor/ already matches %s; skipping composer install", lockPath)
+		return nil
+	}
+
+	composerPhar := filepath.Join(ctx.PhpInstallDir, "composer.phar")
+	if !fileExists(composerPhar) {
+		return fmt.Errorf("composer.phar not found at %s", composerPhar)
+	}
+
+	ctx.Logf("Running composer install in %s", backendDir)
+	err = runLogged(ctx, backendDir, nil, ctx.PhpExePath, composerPhar,
+		"install", "--no-dev", "--optimize-autoloader", "--prefer-dist", "--no-interaction", "--no-progress")
+	if err != nil {
+		return fmt.Errorf("composer install: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(vendorDir, composerStamp), []byte(hash), 0o644); err != nil {
+		ctx.Warnf("unable to record composer stamp: %v", err)
+	}
+	ctx.Logf("Backend dependencies installed")
+	return nil
+}
+
+type BuildFrontend struct{}
+
+func (BuildFrontend) Name() string { return "Build Frontend" }
+
+func (s BuildFrontend) Run(ctx *installer.Context) error {
+	frontendDir := filepath.Join(ctx.RuntimeDir, "frontend")
+	distDir := filepath.Join(frontendDir, "dist")
+	if !dirExists(frontendDir) {
+		return fmt.Errorf("frontend directory not found at %s", frontendDir)
+	}
+
+	apiURL := frontendAPIURL(ctx.AppURL)
+	if err := writeFrontendEnv(frontendDir, apiURL); err != nil {
+		return fmt.Errorf("write frontend .env: %w", err)
+	}
+	ctx.Logf("Frontend API base URL set to %s", apiURL)
+
+	hash, err := hashFiles(frontendDir, []string{".env", "package.json", "package-lock.json", "index.html", "vite.config.js", "src", "public"})
+	if err != nil {
+		return fmt.Errorf("hash frontend sources: %w", err)
+	}
+	if fileExists(filepath.Join(distDir, "index.html")) && readStamp(filepath.Join(distDir, frontendStamp)) == hash {
+		ctx.Logf("frontend/dist already matches the sources; skipping build")
+		return nil
+	}
+
+	npmCmd := filepath.Join(ctx.NodeBinDir, "npm.cmd")
+	if !fileExists(npmCmd) {
+		return fmt.Errorf("npm.cmd not found at %s", npmCmd)
+	}
+	// npm scripts spawn node by name, so the freshly installed Node.js must
+	// be first on PATH even before a new session picks up the machine PATH.
+	env := append(os.Environ(), "PATH="+ctx.NodeBinDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx.Logf("Running npm ci in %s", frontendDir)
+	if err := runLogged(ctx, frontendDir, env, npmCmd, "ci", "--no-audit", "--no-fund"); err != nil {
+		return fmt.Errorf("npm ci: %w", err)
+	}
+	ctx.Logf("Running npm run build")
+	if err := runLogged(ctx, frontendDir, env, npmCmd, "run", "build"); err != nil {
+		return fmt.Errorf("npm run build: %w", err)
+	}
+
+	if !fileExists(filepath.Join(distDir, "index.html")) {
+		return fmt.Errorf("build finished but %s has no index.html", distDir)
+	}
+	if err := os.WriteFile(filepath.Join(distDir, frontendStamp), []byte(hash), 0o644); err != nil {
+		ctx.Warnf("unable to record frontend build stamp: %v", err)
+	}
+	ctx.Logf("Frontend built to %s", distDir)
+	return nil
+}
+
+// frontendAPIURL returns the API base the SPA calls. IIS serves
+// backend/public at the site root, so the API lives under /api.
+func frontendAPIURL(appURL string) string {
+	if appURL == "" {
+		appURL = "http://localhost"
+	}
+	return strings.TrimRight(appURL, "/") + "/api"
+}
+
+func writeFrontendEnv(frontendDir, apiURL string) error {
+	envPath := filepath.Join(frontendDir, ".env")
+	if !fileExists(envPath) {
+		return os.WriteFile(envPath, []byte("VITE_API_BASE_URL="+apiURL+"\n"), 0o644)
+	}
+	return updateEnvFile(envPath, map[string]string{"VITE_API_BASE_URL": apiURL})
+}
+
+// hashFiles hashes the named files and directory trees below root in a
+// stable order. Missing entries are hashed as absent.
+func hashFiles(root string, entries []string) (string, error) {
+	h := sha256.New()
+	for _, entry := range entries {
+		full := filepath.Join(root, entry)
+		info, err := os.Stat(full)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(h, "missing:%s\n", entry)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() {
+			if err := hashFile(h, root, full); err != nil {
+				return "", err
+			}
+			continue
+		}
+		var files []string
+		err = filepath.WalkDir(full, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			if err := hashFile(h, root, file); err != nil {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(h io.Writer, root, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(h, "file:%s\n", filepath.ToSlash(rel))
+	_, err = io.Copy(h, f)
+	return err
+}
+
+func readStamp(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
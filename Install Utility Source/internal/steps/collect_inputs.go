@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"yachtcrm-installer/internal/installer"
-	"yachtcrm-installer/internal/prompts"
+	"yachtcrm-installer/internal/instances"
 )
 
 type CollectInputs struct{}
@@ -14,7 +15,33 @@ type CollectInputs struct{}
 func (CollectInputs) Name() string { return "Collect Inputs" }
 
 func (CollectInputs) Run(ctx *installer.Context) error {
-	runtimeDir, err := prompts.AskString("Enter the YachtCRM-DMS runtime directory", true)
+	if ctx.InstanceName == "" {
+		name, err := askInput(ctx, instanceNameInput)
+		if err != nil {
+			return err
+		}
+		ctx.InstanceName = name
+	}
+	if err := instances.ValidateName(ctx.InstanceName); err != nil {
+		return err
+	}
+	registry, err := instances.Load()
+	if err != nil {
+		return err
+	}
+	defaults, err := registry.Get(ctx.InstanceName)
+	if err != nil {
+		defaults = instances.Defaults(ctx.InstanceName)
+	} else {
+		ctx.Logf("Instance %s is already installed; its settings are offered as defaults", ctx.InstanceName)
+	}
+	ctx.SiteName = defaults.SiteName
+	ctx.AppPoolName = defaults.AppPool
+	ctx.SchedulerTaskName = defaults.SchedulerTask
+	ctx.WorkerTaskName = defaults.WorkerTask
+	in := inputsByKey(inputPages(defaults))
+
+	runtimeDir, err := askInput(ctx, in["runtime_dir"])
 	if err != nil {
 		return err
 	}
@@ -25,6 +52,18 @@ func (CollectInputs) Run(ctx *installer.Context) error {
 	}
 	ctx.RuntimeDir = runtimeDir
 
+	hostHeader, err := askInput(ctx, in["host_header"])
+	if err != nil {
+		return err
+	}
+	ctx.HostHeader = hostHeader
+
+	port, err := askInput(ctx, in["http_port"])
+	if err != nil {
+		return err
+	}
+	ctx.HTTPPort, _ = strconv.Atoi(port)
+
 	exePath, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("determine executable path: %w", err)
@@ -44,7 +83,7 @@ func (CollectInputs) Run(ctx *installer.Context) error {
 	downloadsDir := filepath.Join(exeDir, "downloads")
 	ctx.DownloadsDir = downloadsDir
 
-	phpDir, err := prompts.AskStringDefault("Enter PHP installation directory", "C:\\PHP", true)
+	phpDir, err := askInput(ctx, in["php_dir"])
 	if err != nil {
 		return err
 	}
@@ -56,7 +95,7 @@ func (CollectInputs) Run(ctx *installer.Context) error {
 	ctx.PhpIniPath = filepath.Join(ctx.PhpInstallDir, "php.ini")
 	ctx.PhpExePath = filepath.Join(ctx.PhpInstallDir, "php.exe")
 
-	nodeDir, err := prompts.AskStringDefault("Enter Node.js installation directory", "C:\\nodejs", true)
+	nodeDir, err := askInput(ctx, in["node_dir"])
 	if err != nil {
 		return err
 	}
@@ -67,7 +106,7 @@ func (CollectInputs) Run(ctx *installer.Context) error {
 	ctx.NodeInstallDir = nodeDir
 	ctx.NodeBinDir = nodeDir
 
-	pmaDir, err := prompts.AskStringDefault("Enter phpMyAdmin installation directory", "C:\\inetpub\\wwwroot\\phpMyAdmin", true)
+	pmaDir, err := askInput(ctx, in["phpmyadmin_dir"])
 	if err != nil {
 		return err
 	}
@@ -77,7 +116,7 @@ func (CollectInputs) Run(ctx *installer.Context) error {
 	}
 	ctx.PhpMyAdminDir = pmaDir
 
-	sqlPath, err := prompts.AskString("Enter path to sanitized YachtCRM-DMS SQL dump", true)
+	sqlPath, err := askInput(ctx, in["sql_dump"])
 	if err != nil {
 		return err
 	}
@@ -87,57 +126,74 @@ func (CollectInputs) Run(ctx *installer.Context) error {
 	}
 	ctx.SqlDumpPath = sqlPath
 
-	rootPwd, err := prompts.AskPassword("Enter MariaDB root password to configure")
-	if err != nil {
+	if err := collectDatabaseInputs(ctx, in); err != nil {
 		return err
 	}
-	ctx.RootMariaDBPassword = rootPwd
 
-	dbName, err := prompts.AskString("Enter YachtCRM-DMS database name", true)
+	dbName, err := askInput(ctx, in["database_name"])
 	if err != nil {
 		return err
 	}
 	ctx.DatabaseName = dbName
 
-	dbUser, err := prompts.AskString("Enter YachtCRM-DMS database username", true)
+	dbUser, err := askInput(ctx, in["database_user"])
 	if err != nil {
 		return err
 	}
 	ctx.DatabaseUser = dbUser
 
-	dbUserPwd, err := prompts.AskPassword("Enter password for YachtCRM-DMS database user")
+	dbUserPwd, err := askInput(ctx, in["database_password"])
 	if err != nil {
 		return err
 	}
 	ctx.DatabaseUserPassword = dbUserPwd
 
-	adminName, err := prompts.AskString("Enter name for initial YachtCRM-DMS admin user", true)
+	if err := checkInstanceConflicts(ctx, registry); err != nil {
+		return err
+	}
+
+	adminName, err := askInput(ctx, in["admin_name"])
 	if err != nil {
 		return err
 	}
 	ctx.AdminName = adminName
 
-	adminEmail, err := prompts.AskString("Enter email for initial YachtCRM-DMS admin user", true)
+	adminEmail, err := askInput(ctx, in["admin_email"])
 	if err != nil {
 		return err
 	}
 	ctx.AdminEmail = adminEmail
 
-	adminPass, err := prompts.AskPassword("Enter password for initial YachtCRM-DMS admin user")
+	adminPass, err := askInput(ctx, in["admin_password"])
 	if err != nil {
 		return err
 	}
 	ctx.AdminPassword = adminPass
 
+	if err := collectModules(ctx, in["modules"]); err != nil {
+		return err
+	}
+	if err := collectBranding(ctx); err != nil {
+		return err
+	}
+	if err := collectPhpSettings(ctx); err != nil {
+		return err
+	}
+
+	ctx.Logf("Installing instance %s as IIS site %s", ctx.InstanceName, ctx.SiteName)
 	ctx.Logf("Runtime directory set to %s", ctx.RuntimeDir)
 	ctx.Logf("Prerequisites directory defaulting to %s", ctx.PrerequisitesDir)
 	ctx.Logf("CRM_Source directory defaulting to %s", ctx.CRMSourceDir)
 	ctx.Logf("Downloads directory set to %s", ctx.DownloadsDir)
-	ctx.Logf("PHP will be installed to %s", ctx.PhpInstallDir)
+	ctx.Logf("PHP will be installed to %s with the %s php.ini profile", ctx.PhpInstallDir, ctx.PHP.ProfileName())
 	ctx.Logf("Node.js will be installed to %s", ctx.NodeInstallDir)
 	ctx.Logf("phpMyAdmin will be installed to %s", ctx.PhpMyAdminDir)
 	ctx.Logf("SQL dump located at %s", ctx.SqlDumpPath)
-	ctx.Logf("MariaDB database %s with user %s will be created", ctx.DatabaseName, ctx.DatabaseUser)
+	if ctx.ExternalDatabase {
+		ctx.Logf("Database %s with user %s on existing server %s:%d", ctx.DatabaseName, ctx.DatabaseUser, ctx.DatabaseHost, ctx.DatabasePort)
+	} else {
+		ctx.Logf("MariaDB database %s with user %s will be created", ctx.DatabaseName, ctx.DatabaseUser)
+	}
 	ctx.Logf("Admin user %s <%s> will be provisioned", ctx.AdminName, ctx.AdminEmail)
 
 	return nil
@@ -0,0 +1,220 @@
+package steps
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"yachtcrm-installer/internal/ini"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/phpconfig"
+	"yachtcrm-installer/internal/powershell"
+)
+
+// The settings below are written by the install steps and checked by
+// Verify, so both agree on what an installed server looks like.
+
+// mariaDBSettings are set in the local server's my.ini, in order.
+var mariaDBSettings = [][2]string{
+	{"innodb_buffer_pool_size", "1G"},
+	{"max_connections", "150"},
+	{"query_cache_size", "64M"},
+	{"innodb_log_file_size", "256M"},
+}
+
+// collectPhpSettings takes the php.ini profile and changes from the answer
+// file. Without them the site gets the default profile.
+func collectPhpSettings(ctx *installer.Context) error {
+	if ctx.Answers == nil || ctx.Answers.PHP == nil {
+		return nil
+	}
+	if err := ctx.Answers.PHP.Validate(); err != nil {
+		return fmt.Errorf("answer file: %w", err)
+	}
+	ctx.PHP = *ctx.Answers.PHP
+	return nil
+}
+
+// applyPhpIni enables the extensions and sets the values of cfg, logging
+// each change.
+func applyPhpIni(ctx *installer.Context, contents string, cfg phpconfig.Config) (string, error) {
+	f := ini.Parse([]byte(contents))
+	for _, ext := range cfg.Extensions {
+		if !phpExtensionEnabled(f, "extension", ext) {
+			if _, err := f.Enable(phpSection(f, "extension"), "extension", ext); err != nil {
+				return "", fmt.Errorf("php.ini: %w", err)
+			}
+			ctx.Logf("php.ini: enabled extension %s", ext)
+		}
+	}
+	for _, ext := range cfg.ZendExtensions {
+		if !phpExtensionEnabled(f, "zend_extension", ext) {
+			if _, err := f.Enable(phpSection(f, "zend_extension"), "zend_extension", ext); err != nil {
+				return "", fmt.Errorf("php.ini: %w", err)
+			}
+			ctx.Logf("php.ini: enabled Zend extension %s", ext)
+		}
+	}
+	for _, kv := range cfg.Values {
+		old, found := phpIniValue(f, kv[0])
+		if found && strings.EqualFold(old, kv[1]) {
+			continue
+		}
+		if _, err := f.Set(phpSection(f, kv[0]), kv[0], kv[1]); err != nil {
+			return "", fmt.Errorf("php.ini: %w", err)
+		}
+		if found {
+			ctx.Logf("php.ini: %s = %s (was %s)", kv[0], kv[1], old)
+		} else {
+			ctx.Logf("php.ini: %s = %s", kv[0], kv[1])
+		}
+	}
+	return string(f.Bytes()), nil
+}
+
+// phpSection is the php.ini section a directive is written in. PHP does
+// not scope directives by section, so an existing assignment is changed
+// where it is, then a commented-out default; a new directive goes in the
+// section named after its prefix, such as [opcache], or else in [PHP].
+func phpSection(f *ini.File, key string) string {
+	active, commented := "", ""
+	foundActive, foundCommented := false, false
+	for _, e := range f.Entries() {
+		switch {
+		case e.Key != key:
+		case !e.Commented:
+			active, foundActive = e.Section, true
+		case !foundCommented:
+			commented, foundCommented = e.Section, true
+		}
+	}
+	if foundActive {
+		return active
+	}
+	if foundCommented {
+		return commented
+	}
+	if prefix, _, ok := strings.Cut(key, "."); ok {
+		for _, name := range f.Sections() {
+			if strings.EqualFold(name, prefix) {
+				return name
+			}
+		}
+	}
+	return "PHP"
+}
+
+// phpIniValue returns the value of the last assignment to key in any
+// section, which is the one PHP uses.
+func phpIniValue(f *ini.File, key string) (string, bool) {
+	value, found := "", false
+	for _, e := range f.Entries() {
+		if e.Key == key && !e.Commented {
+			value, found = e.Value, true
+		}
+	}
+	return value, found
+}
+
+// phpExtensionEnabled reports whether an uncommented directive loads ext,
+// by name or by DLL file name.
+func phpExtensionEnabled(f *ini.File, directive, ext string) bool {
+	for _, e := range f.Entries() {
+		if e.Commented || e.Key != directive {
+			continue
+		}
+		value := strings.Trim(e.Value, "'")
+		if strings.EqualFold(value, ext) || strings.EqualFold(value, "php_"+ext+".dll") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMyIni reads a MariaDB option file, whose option names ignore case
+// and treat dashes and underscores alike.
+func parseMyIni(contents string) *ini.File {
+	f := ini.Parse([]byte(contents))
+	f.Fold = ini.MySQLKey
+	return f
+}
+
+// applyMariaDBIni sets the server tuning values in [mysqld].
+func applyMariaDBIni(contents string) (string, error) {
+	f := parseMyIni(contents)
+	for _, kv := range mariaDBSettings {
+		if _, err := f.Set("mysqld", kv[0], kv[1]); err != nil {
+			return "", fmt.Errorf("my.ini: %w", err)
+		}
+	}
+	return string(f.Bytes()), nil
+}
+
+func restartMariaDB() powershell.Result {
+	return powershell.Run(`Get-Service -Name "MariaDB*" -ErrorAction SilentlyContinue | ForEach-Object { Restart-Service -Name $_.Name -Force }`)
+}
+
+func phpIniPath(ctx *installer.Context) string {
+	if ctx.PhpIniPath != "" {
+		return ctx.PhpIniPath
+	}
+	return filepath.Join(ctx.PhpInstallDir, "php.ini")
+}
+
+// sitePaths are the directories IIS serves the backend and frontend from.
+func sitePaths(ctx *installer.Context) (backend, frontend string) {
+	return filepath.Join(ctx.RuntimeDir, "backend", "public"), filepath.Join(ctx.RuntimeDir, "frontend", "dist")
+}
+
+func phpCgiPath(ctx *installer.Context) string {
+	return filepath.Join(ctx.PhpInstallDir, "php-cgi.exe")
+}
+
+// iisScript creates or updates the application pool, the site, its
+// frontend virtual directory and the PHP FastCGI handler.
+func iisScript(ctx *installer.Context) string {
+	backendPath, frontendPath := sitePaths(ctx)
+	port := ctx.HTTPPort
+	if port == 0 {
+		port = 80
+	}
+	phpCgi := phpCgiPath(ctx)
+	return fmt.Sprintf(`Import-Module WebAdministration
+$pool = '%s'
+if (-not (Test-Path IIS:\AppPools\$pool)) { New-WebAppPool -Name $pool | Out-Null }
+Set-ItemProperty IIS:\AppPools\$pool managedRuntimeVersion ""
+Set-ItemProperty IIS:\AppPools\$pool managedPipelineMode "Integrated"
+Set-ItemProperty IIS:\AppPools\$pool enable32BitAppOnWin64 0
+$site = '%s'
+$physical = '%s'
+if (Get-Website $site -ErrorAction SilentlyContinue) {
+    Set-ItemProperty IIS:\Sites\$site physicalPath $physical
+    Set-ItemProperty IIS:\Sites\$site applicationPool $pool
+} else {
+    New-Website -Name $site -Port %d -HostHeader '%s' -PhysicalPath $physical -ApplicationPool $pool | Out-Null
+}
+$frontendPath = '%s'
+if (Get-WebVirtualDirectory -Site $site -Name 'frontend' -ErrorAction SilentlyContinue) {
+    Remove-WebVirtualDirectory -Site $site -Name 'frontend'
+}
+New-WebVirtualDirectory -Site $site -Name 'frontend' -PhysicalPath $frontendPath | Out-Null
+$appcmd = Join-Path $env:windir 'system32\\inetsrv\\appcmd.exe'
+& $appcmd set config -section:system.webServer/handlers /-"[name='PHP_via_FastCGI']" 2>$null
+& $appcmd set config -section:system.webServer/fastCgi /-"[fullPath='%s']" 2>$null
+& $appcmd set config -section:system.webServer/fastCgi /+"[fullPath='%s']" /commit:apphost | Out-Null
+& $appcmd set config -section:system.webServer/handlers /+"[name='PHP_via_FastCGI',path='*.php',verb='GET,HEAD,POST,PUT,DELETE,PATCH,OPTIONS',modules='FastCgiModule',scriptProcessor='%s',resourceType='Either',requireAccess='Script']" /commit:apphost | Out-Null
+`, appPoolName(ctx), iisSiteName(ctx), backendPath, port, escapeSingleQuotes(ctx.HostHeader), frontendPath, phpCgi, phpCgi, phpCgi)
+}
+
+// writableDirs are the backend directories IIS_IUSRS gets full control of.
+func writableDirs(ctx *installer.Context) []string {
+	return []string{
+		filepath.Join(ctx.RuntimeDir, "backend", "storage"),
+		filepath.Join(ctx.RuntimeDir, "backend", "bootstrap", "cache"),
+		filepath.Join(ctx.RuntimeDir, "backend", "storage", "app", "public"),
+	}
+}
+
+func grantIISAccess(dir string) powershell.Result {
+	return powershell.Run(fmt.Sprintf(`icacls "%s" /grant "IIS_IUSRS:(OI)(CI)F" /T`, dir))
+}
@@ -0,0 +1,253 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yachtcrm-installer/internal/database"
+	"yachtcrm-installer/internal/installer"
+)
+
+// Minimum server versions the CRM schema is tested against.
+var (
+	minMySQLVersion   = [3]int{8, 0, 0}
+	minMariaDBVersion = [3]int{10, 6, 0}
+)
+
+// appUserPrivileges are the schema privileges Laravel migrations and the
+// application need on the CRM database.
+var appUserPrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "DROP", "ALTER", "INDEX", "REFERENCES"}
+
+// collectDatabaseInputs asks whether to install a local MariaDB or use an
+// existing server, and how to connect to it.
+func collectDatabaseInputs(ctx *installer.Context, in map[string]Input) error {
+	local, err := askYesNo(ctx, in["local_database"])
+	if err != nil {
+		return err
+	}
+	ctx.ExternalDatabase = !local
+	ctx.DatabaseHost = "127.0.0.1"
+	ctx.DatabasePort = 3306
+	ctx.DatabaseAdminUser = "root"
+	ctx.DatabaseUserHost = "localhost"
+
+	if local {
+		rootPwd, err := askInput(ctx, in["mariadb_root_password"])
+		if err != nil {
+			return err
+		}
+		ctx.RootMariaDBPassword = rootPwd
+		return nil
+	}
+
+	host, err := askInput(ctx, in["database_host"])
+	if err != nil {
+		return err
+	}
+	ctx.DatabaseHost = host
+
+	port, err := askInput(ctx, in["database_port"])
+	if err != nil {
+		return err
+	}
+	ctx.DatabasePort, _ = strconv.Atoi(port)
+
+	hasAdmin, err := askYesNo(ctx, in["database_has_admin"])
+	if err != nil {
+		return err
+	}
+	if !hasAdmin {
+		ctx.DatabaseAdminUser = ""
+		ctx.Logf("The database and its user must already exist; the installer will connect as the application user only")
+		return nil
+	}
+
+	adminUser, err := askInput(ctx, in["database_admin_user"])
+	if err != nil {
+		return err
+	}
+	ctx.DatabaseAdminUser = adminUser
+
+	adminPwd, err := askInput(ctx, in["database_admin_password"])
+	if err != nil {
+		return err
+	}
+	ctx.RootMariaDBPassword = adminPwd
+
+	userHost, err := askInput(ctx, in["database_user_host"])
+	if err != nil {
+		return err
+	}
+	ctx.DatabaseUserHost = userHost
+	return nil
+}
+
+// dbAccount returns the account used for installer-side SQL: the admin
+// account when one was given, otherwise the application user.
+func dbAccount(ctx *installer.Context) (user, password string) {
+	if ctx.DatabaseAdminUser != "" {
+		return ctx.DatabaseAdminUser, ctx.RootMariaDBPassword
+	}
+	return ctx.DatabaseUser, ctx.DatabaseUserPassword
+}
+
+// openDatabase connects to the configured server with the account
+// installer-side SQL runs as. An empty schema connects without a default
+// database.
+func openDatabase(ctx *installer.Context, schema string) (*database.DB, error) {
+	user, password := dbAccount(ctx)
+	return database.Open(database.Config{
+		Host:     ctx.DatabaseHost,
+		Port:     ctx.DatabasePort,
+		User:     user,
+		Password: password,
+		Database: schema,
+	})
+}
+
+type ValidateDatabaseServer struct{}
+
+func (ValidateDatabaseServer) Name() string { return "Validate Database Server" }
+
+func (s ValidateDatabaseServer) Run(ctx *installer.Context) error {
+	user, _ := dbAccount(ctx)
+	ctx.Logf("Connecting to %s:%d as %s", ctx.DatabaseHost, ctx.DatabasePort, user)
+
+	db, err := openDatabase(ctx, "")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	version, err := db.Version()
+	if err != nil {
+		return err
+	}
+	if err := checkServerVersion(version); err != nil {
+		return err
+	}
+	ctx.Logf("Database server version %s", version)
+
+	ok, err := db.SupportsCollation("utf8mb4", "utf8mb4_unicode_ci")
+	if err != nil {
+		return fmt.Errorf("check character sets: %w", err)
+	}
+	if !ok {
+		return errors.New("database server does not support utf8mb4 with utf8mb4_unicode_ci")
+	}
+
+	if ctx.DatabaseAdminUser != "" {
+		if err := checkAdminPrivileges(ctx, db); err != nil {
+			return err
+		}
+	} else if err := checkAppUserPrivileges(ctx, db); err != nil {
+		return err
+	}
+	ctx.Logf("Database server meets the YachtCRM-DMS requirements")
+	return nil
+}
+
+// checkServerVersion accepts MySQL 8.0+ and MariaDB 10.6+. version is the
+// raw VERSION() string, e.g. "10.11.6-MariaDB" or "8.0.36".
+func checkServerVersion(version string) error {
+	numeric, _, _ := strings.Cut(version, "-")
+	var parts [3]int
+	for i, field := range strings.SplitN(numeric, ".", 3) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return fmt.Errorf("unrecognised database server version %q", version)
+		}
+		parts[i] = n
+	}
+	minimum, product := minMySQLVersion, "MySQL"
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		minimum, product = minMariaDBVersion, "MariaDB"
+	}
+	for i := range parts {
+		if parts[i] != minimum[i] {
+			if parts[i] < minimum[i] {
+				return fmt.Errorf("%s %s is too old; %d.%d or newer is required", product, version, minimum[0], minimum[1])
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// checkAdminPrivileges confirms the admin account can create the database
+// and the application user and pass privileges on to it.
+func checkAdminPrivileges(ctx *installer.Context, db *database.DB) error {
+	granted, grantable, err := db.Privileges("")
+	if err != nil {
+		return fmt.Errorf("read admin privileges: %w", err)
+	}
+	var missing []string
+	for _, priv := range []string{"CREATE", "CREATE USER"} {
+		if !granted[priv] {
+			missing = append(missing, priv)
+		}
+	}
+	if !grantable {
+		missing = append(missing, "GRANT OPTION")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("admin account %s lacks global privileges: %s", ctx.DatabaseAdminUser, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkAppUserPrivileges confirms a pre-created application user can reach
+// its database and run migrations without an admin account.
+func checkAppUserPrivileges(ctx *installer.Context, db *database.DB) error {
+	exists, err := db.DatabaseExists(ctx.DatabaseName)
+	if err != nil {
+		return fmt.Errorf("check database %s: %w", ctx.DatabaseName, err)
+	}
+	if !exists {
+		return fmt.Errorf("database %s does not exist or is not visible to %s; create it first or supply an admin account", ctx.DatabaseName, ctx.DatabaseUser)
+	}
+
+	granted, _, err := db.Privileges(ctx.DatabaseName)
+	if err != nil {
+		return fmt.Errorf("read privileges on %s: %w", ctx.DatabaseName, err)
+	}
+	var missing []string
+	for _, priv := range appUserPrivileges {
+		if !granted[priv] {
+			missing = append(missing, priv)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("user %s lacks privileges on %s: %s", ctx.DatabaseUser, ctx.DatabaseName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// provisionDatabase creates the CRM database and application user and
+// grants it the database. Without an admin account both must already
+// exist, which ValidateDatabaseServer has confirmed.
+func provisionDatabase(ctx *installer.Context) error {
+	if ctx.DatabaseAdminUser == "" {
+		ctx.Logf("Using pre-created database %s and user %s", ctx.DatabaseName, ctx.DatabaseUser)
+		return nil
+	}
+	db, err := openDatabase(ctx, "")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.CreateDatabase(ctx.DatabaseName); err != nil {
+		return fmt.Errorf("create database %s: %w", ctx.DatabaseName, err)
+	}
+	if err := db.CreateUser(ctx.DatabaseUser, ctx.DatabaseUserHost, ctx.DatabaseUserPassword); err != nil {
+		return fmt.Errorf("create user %s: %w", ctx.DatabaseUser, err)
+	}
+	if err := db.GrantAll(ctx.DatabaseName, ctx.DatabaseUser, ctx.DatabaseUserHost); err != nil {
+		return fmt.Errorf("grant %s on %s: %w", ctx.DatabaseUser, ctx.DatabaseName, err)
+	}
+	ctx.Logf("Database %s and user %s@%s configured on %s", ctx.DatabaseName, ctx.DatabaseUser, ctx.DatabaseUserHost, ctx.DatabaseHost)
+	return nil
+}
@@ -0,0 +1,70 @@
+package steps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"yachtcrm-installer/internal/demo"
+	"yachtcrm-installer/internal/installer"
+)
+
+// SeedDemo loads the demo dataset for sales and training installs.
+type SeedDemo struct {
+	Options demo.Options
+}
+
+func (SeedDemo) Name() string { return "Load Demo Data" }
+
+func (s SeedDemo) Run(ctx *installer.Context) error {
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx.Logf("Generating demo data (seed %d, %d customers, dated as of %s)", s.Options.Seed, s.Options.Size, s.Options.AsOf.Format("2006-01-02"))
+	summary, err := demo.Seed(db, s.Options)
+	if err != nil {
+		return fmt.Errorf("load demo data: %w", err)
+	}
+	ctx.Logf("Demo data loaded: %s", formatSummary(summary))
+	return nil
+}
+
+// RemoveDemo deletes the rows SeedDemo inserted.
+type RemoveDemo struct{}
+
+func (RemoveDemo) Name() string { return "Remove Demo Data" }
+
+func (RemoveDemo) Run(ctx *installer.Context) error {
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	summary, err := demo.Remove(db)
+	if err != nil {
+		return fmt.Errorf("remove demo data: %w", err)
+	}
+	if len(summary) == 0 {
+		ctx.Logf("No demo data found in %s", ctx.DatabaseName)
+		return nil
+	}
+	ctx.Logf("Demo data removed: %s", formatSummary(summary))
+	return nil
+}
+
+func formatSummary(summary demo.Summary) string {
+	tables := make([]string, 0, len(summary))
+	for table := range summary {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	parts := make([]string, len(tables))
+	for i, table := range tables {
+		parts[i] = fmt.Sprintf("%s %d", table, summary[table])
+	}
+	return strings.Join(parts, ", ")
+}
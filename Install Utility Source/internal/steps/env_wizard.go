@@ -0,0 +1,338 @@
+package steps
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/prompts"
+)
+
+// envField describes a single .env key collected by the wizard.
+type envField struct {
+	Key      string
+	Question string
+	Default  string
+	Secret   bool
+	Validate func(string) error
+}
+
+// envSection groups related optional .env keys behind one confirmation.
+type envSection struct {
+	Title  string
+	Fields []envField
+	// After runs once the fields are collected, e.g. for a test send.
+	After func(ctx *installer.Context, env map[string]string) error
+}
+
+func envSections() []envSection {
+	return []envSection{
+		{
+			Title: "Mail (MAIL_*)",
+			Fields: []envField{
+				{Key: "MAIL_MAILER", Question: "Mail transport (smtp, sendmail, log)", Default: "smtp", Validate: oneOf("smtp", "sendmail", "log")},
+				{Key: "MAIL_HOST", Question: "SMTP host", Default: "127.0.0.1", Validate: validateHost},
+				{Key: "MAIL_PORT", Question: "SMTP port", Default: "587", Validate: validatePort},
+				{Key: "MAIL_USERNAME", Question: "SMTP username (blank for none)"},
+				{Key: "MAIL_PASSWORD", Question: "SMTP password (blank for none)", Secret: true},
+				{Key: "MAIL_ENCRYPTION", Question: "SMTP encryption (tls, ssl, null)", Default: "tls", Validate: oneOf("tls", "ssl", "null")},
+				{Key: "MAIL_FROM_ADDRESS", Question: "From address", Validate: validateEmail},
+				{Key: "MAIL_FROM_NAME", Question: "From name", Default: "${APP_NAME}"},
+			},
+			After: offerTestMail,
+		},
+		{
+			Title: "Stripe (STRIPE_*)",
+			Fields: []envField{
+				{Key: "STRIPE_KEY", Question: "Stripe publishable key (pk_...)", Validate: hasPrefix("pk_live_", "pk_test_")},
+				{Key: "STRIPE_SECRET", Question: "Stripe secret key (sk_...)", Secret: true, Validate: hasPrefix("sk_live_", "sk_test_", "rk_live_", "rk_test_")},
+				{Key: "STRIPE_WEBHOOK_SECRET", Question: "Stripe webhook signing secret (whsec_...)", Secret: true, Validate: hasPrefix("whsec_")},
+			},
+		},
+		{
+			Title: "Square (SQUARE_*)",
+			Fields: []envField{
+				{Key: "SQUARE_APPLICATION_ID", Question: "Square application ID", Validate: hasPrefix("sq0idp-", "sandbox-sq0idb-")},
+				{Key: "SQUARE_ACCESS_TOKEN", Question: "Square access token", Secret: true},
+				{Key: "SQUARE_ENVIRONMENT", Question: "Square environment (sandbox, production)", Default: "production", Validate: oneOf("sandbox", "production")},
+				{Key: "SQUARE_WEBHOOK_SIGNATURE_KEY", Question: "Square webhook signature key", Secret: true},
+			},
+		},
+		{
+			Title: "Session, cache and queue drivers",
+			Fields: []envField{
+				{Key: "SESSION_DRIVER", Question: "Session driver (file, database, cookie)", Default: "database", Validate: oneOf("file", "database", "cookie")},
+				{Key: "SESSION_LIFETIME", Question: "Session lifetime in minutes", Default: "120", Validate: validatePositiveInt},
+				{Key: "CACHE_DRIVER", Question: "Cache driver (file, database, array)", Default: "database", Validate: oneOf("file", "database", "array")},
+				{Key: "QUEUE_CONNECTION", Question: "Queue connection (sync, database)", Default: "sync", Validate: oneOf("sync", "database")},
+			},
+		},
+		{
+			Title: "Logging (LOG_*)",
+			Fields: []envField{
+				{Key: "LOG_CHANNEL", Question: "Log channel (stack, single, daily, errorlog)", Default: "daily", Validate: oneOf("stack", "single", "daily", "errorlog")},
+				{Key: "LOG_LEVEL", Question: "Log level", Default: "error", Validate: oneOf("debug", "info", "notice", "warning", "error", "critical", "alert", "emergency")},
+			},
+		},
+	}
+}
+
+func (f envField) input() Input {
+	return Input{Key: f.Key, Question: f.Question, Default: f.Default, Secret: f.Secret, Validate: f.Validate}
+}
+
+func sectionQuestion(section envSection) string {
+	return fmt.Sprintf("Configure %s settings now?", section.Title)
+}
+
+// collectEnvSections walks the optional sections. Sections the operator
+// skips have their sample placeholder values cleared so the example
+// credentials never look like real configuration. With an "env" map in the
+// answer file, a section is configured when the map has any of its keys.
+func collectEnvSections(ctx *installer.Context, env map[string]string) error {
+	var answers map[string]string
+	if ctx.Answers != nil {
+		answers = ctx.Answers.Env
+	}
+	for _, section := range envSections() {
+		configure, err := configureSection(answers, section)
+		if err != nil {
+			return err
+		}
+		if !configure {
+			for _, field := range section.Fields {
+				if isPlaceholder(env[field.Key]) {
+					env[field.Key] = ""
+				}
+			}
+			ctx.Logf("Skipped %s settings", section.Title)
+			continue
+		}
+
+		for _, field := range section.Fields {
+			in := field.input()
+			current := env[field.Key]
+			if isPlaceholder(current) {
+				current = ""
+			}
+			// Secrets are not shown as defaults, so a blank answer keeps the
+			// stored one instead of clearing it.
+			keepSecret := field.Secret && current != ""
+			switch {
+			case keepSecret:
+				in.Question = strings.TrimSuffix(in.Question, " (blank for none)") + " (blank keeps the current value)"
+			case current != "":
+				in.Default = current
+			}
+			value, err := ask(answers, in)
+			if err != nil {
+				return err
+			}
+			if value == "" && keepSecret {
+				value = current
+			}
+			env[field.Key] = value
+		}
+		ctx.Logf("%s settings configured", section.Title)
+
+		if section.After != nil {
+			if err := section.After(ctx, env); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func configureSection(answers map[string]string, section envSection) (bool, error) {
+	if answers == nil {
+		return prompts.Confirm(sectionQuestion(section), false)
+	}
+	for _, field := range section.Fields {
+		if _, ok := answers[field.Key]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isPlaceholder(value string) bool {
+	lower := strings.ToLower(value)
+	return strings.Contains(lower, "your_") || strings.Contains(lower, "your-") || strings.Contains(lower, "yourdomain")
+}
+
+// mailTestInput is the recipient of a test email. With an answer file a
+// test is sent only when the file names one, so unattended installs never
+// stop here.
+var mailTestInput = Input{Key: "mail_test_to", Question: "Send a test email to (blank to skip)", Validate: validateEmail}
+
+func offerTestMail(ctx *installer.Context, env map[string]string) error {
+	if env["MAIL_MAILER"] != "smtp" {
+		return nil
+	}
+	var to string
+	if ctx.Answers != nil {
+		if _, ok := ctx.Answers.Inputs[mailTestInput.Key]; !ok {
+			return nil
+		}
+		value, err := askInput(ctx, mailTestInput)
+		if err != nil || value == "" {
+			return err
+		}
+		to = value
+	} else {
+		send, err := prompts.Confirm("Send a test email now?", false)
+		if err != nil || !send {
+			return err
+		}
+		if to, err = prompts.AskValidated("Send test email to", env["MAIL_FROM_ADDRESS"], true, validateEmail); err != nil {
+			return err
+		}
+	}
+	if err := sendTestMail(env, to); err != nil {
+		// A failed test send should not abort the install; the operator can
+		// correct the settings in .env afterwards.
+		ctx.Warnf("test email to %s failed: %v", to, err)
+		return nil
+	}
+	ctx.Logf("Test email sent to %s via %s:%s", to, env["MAIL_HOST"], env["MAIL_PORT"])
+	return nil
+}
+
+// sendTestMail delivers a short message using the MAIL_* settings. With
+// MAIL_ENCRYPTION=null and no username it speaks plain SMTP, which is what
+// local stand-ins such as MailHog or smtp4dev expect.
+func sendTestMail(env map[string]string, to string) error {
+	host := env["MAIL_HOST"]
+	addr := net.JoinHostPort(host, env["MAIL_PORT"])
+	from := env["MAIL_FROM_ADDRESS"]
+	if from == "" {
+		from = to
+	}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	if env["MAIL_ENCRYPTION"] == "ssl" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if env["MAIL_ENCRYPTION"] == "tls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS; set MAIL_ENCRYPTION to null or ssl")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if env["MAIL_USERNAME"] != "" {
+		if err := client.Auth(smtp.PlainAuth("", env["MAIL_USERNAME"], env["MAIL_PASSWORD"], host)); err != nil {
+			return fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("RCPT TO: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA: %w", err)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: YachtCRM-DMS installer test\r\nDate: %s\r\n\r\nThis is a test message sent by the YachtCRM-DMS installer.\r\n",
+		from, to, time.Now().Format(time.RFC1123Z))
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finish message: %w", err)
+	}
+	return client.Quit()
+}
+
+// formatEnvValue quotes values that dotenv would otherwise split or treat
+// as a comment.
+func formatEnvValue(value string) string {
+	if strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && len(value) > 1 {
+		return value
+	}
+	if strings.ContainsAny(value, " #\"'") {
+		return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+	}
+	return value
+}
+
+func oneOf(allowed ...string) func(string) error {
+	return func(value string) error {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+	}
+}
+
+func hasPrefix(prefixes ...string) func(string) error {
+	return func(value string) error {
+		for _, p := range prefixes {
+			if strings.HasPrefix(value, p) && len(value) > len(p) {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected a value starting with %s", strings.Join(prefixes, " or "))
+	}
+}
+
+func validateEmail(value string) error {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return errors.New("not a valid email address")
+	}
+	return nil
+}
+
+func validateHost(value string) error {
+	if strings.ContainsAny(value, " /:") {
+		return errors.New("host must not contain spaces, slashes or a port")
+	}
+	return nil
+}
+
+func validatePort(value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return errors.New("port must be a number between 1 and 65535")
+	}
+	return nil
+}
+
+func validatePositiveInt(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return errors.New("must be a positive whole number")
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package steps
+
+import (
+	"bufio"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"yachtcrm-installer/internal/installer"
+)
+
+// smtpSession is what the fake SMTP server received.
+type smtpSession struct {
+	commands []string
+	data     string
+}
+
+// fakeSMTP accepts one plain SMTP session, like a local MailHog or
+// smtp4dev would, and reports it on the returned channel.
+func fakeSMTP(t *testing.T, extensions ...string) (host, port string, session <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	done := make(chan smtpSession, 1)
+	go func() {
+		var s smtpSession
+		defer func() { done <- s }()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			s.commands = append(s.commands, cmd)
+			verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0])
+			switch verb {
+			case "EHLO":
+				for _, ext := range extensions {
+					reply("250-" + ext)
+				}
+				reply("250 fake")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				s.data = data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port, done
+}
+
+func TestSendTestMail(t *testing.T) {
+	host, port, session := fakeSMTP(t)
+	env := map[string]string{
+		"MAIL_HOST":         host,
+		"MAIL_PORT":         port,
+		"MAIL_ENCRYPTION":   "null",
+		"MAIL_FROM_ADDRESS": "crm@example.com",
+	}
+	if err := sendTestMail(env, "ops@example.com"); err != nil {
+		t.Fatalf("sendTestMail: %v", err)
+	}
+	s := <-session
+
+	var from, to []string
+	for _, cmd := range s.commands {
+		switch {
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			from = append(from, cmd)
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			to = append(to, cmd)
+		}
+	}
+	if len(from) != 1 || from[0] != "MAIL FROM:<crm@example.com>" {
+		t.Errorf("MAIL FROM = %q, want one MAIL FROM:<crm@example.com>", from)
+	}
+	if len(to) != 1 || to[0] != "RCPT TO:<ops@example.com>" {
+		t.Errorf("RCPT TO = %q, want one RCPT TO:<ops@example.com>", to)
+	}
+	if last := s.commands[len(s.commands)-1]; last != "QUIT" {
+		t.Errorf("last command = %q, want QUIT", last)
+	}
+
+	headers, body, ok := strings.Cut(s.data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("message has no header/body separator: %q", s.data)
+	}
+	for _, want := range []string{"From: crm@example.com", "To: ops@example.com", "Subject: YachtCRM-DMS installer test", "Date: "} {
+		if !strings.Contains(headers, want) {
+			t.Errorf("headers %q do not contain %q", headers, want)
+		}
+	}
+	if want := "This is a test message sent by the YachtCRM-DMS installer.\r\n"; body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestSendTestMailFromDefaultsToRecipient(t *testing.T) {
+	host, port, session := fakeSMTP(t)
+	env := map[string]string{"MAIL_HOST": host, "MAIL_PORT": port, "MAIL_ENCRYPTION": "null"}
+	if err := sendTestMail(env, "ops@example.com"); err != nil {
+		t.Fatalf("sendTestMail: %v", err)
+	}
+	s := <-session
+	found := false
+	for _, cmd := range s.commands {
+		if cmd == "MAIL FROM:<ops@example.com>" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("commands %q have no MAIL FROM:<ops@example.com>", s.commands)
+	}
+}
+
+func TestSendTestMailRequiresStartTLS(t *testing.T) {
+	host, port, session := fakeSMTP(t)
+	env := map[string]string{"MAIL_HOST": host, "MAIL_PORT": port, "MAIL_ENCRYPTION": "tls", "MAIL_FROM_ADDRESS": "crm@example.com"}
+	err := sendTestMail(env, "ops@example.com")
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("sendTestMail error = %v, want a STARTTLS error", err)
+	}
+	for _, cmd := range (<-session).commands {
+		if strings.HasPrefix(cmd, "MAIL FROM:") {
+			t.Errorf("sent %q without STARTTLS", cmd)
+		}
+	}
+}
+
+func TestCollectEnvSectionsKeepsSecrets(t *testing.T) {
+	ctx := &installer.Context{Answers: &installer.Answers{Env: map[string]string{
+		"MAIL_MAILER":       "log",
+		"MAIL_HOST":         "",
+		"MAIL_PORT":         "",
+		"MAIL_USERNAME":     "",
+		"MAIL_PASSWORD":     "",
+		"MAIL_ENCRYPTION":   "",
+		"MAIL_FROM_ADDRESS": "crm@example.com",
+		"MAIL_FROM_NAME":    "",
+	}}}
+	env := map[string]string{
+		"MAIL_PASSWORD": "stored-secret",
+		"MAIL_USERNAME": "crm",
+		"STRIPE_SECRET": "sk_test_your_stripe_secret",
+	}
+	if err := collectEnvSections(ctx, env); err != nil {
+		t.Fatal(err)
+	}
+	if got := env["MAIL_PASSWORD"]; got != "stored-secret" {
+		t.Errorf("MAIL_PASSWORD = %q, want the stored secret kept", got)
+	}
+	// A blank non-secret answer takes the current value as its default.
+	if got := env["MAIL_USERNAME"]; got != "crm" {
+		t.Errorf("MAIL_USERNAME = %q, want crm", got)
+	}
+	// Skipped sections still lose their sample placeholders.
+	if got := env["STRIPE_SECRET"]; got != "" {
+		t.Errorf("STRIPE_SECRET = %q, want the placeholder cleared", got)
+	}
+}
+
+func TestOfferTestMailWithAnswers(t *testing.T) {
+	host, port, session := fakeSMTP(t)
+	env := map[string]string{"MAIL_MAILER": "smtp", "MAIL_HOST": host, "MAIL_PORT": port, "MAIL_ENCRYPTION": "null", "MAIL_FROM_ADDRESS": "crm@example.com"}
+
+	// Without a recipient in the answer file no test is sent and nothing
+	// is prompted for, which would fail on the test's empty stdin.
+	ctx := &installer.Context{Answers: &installer.Answers{}}
+	if err := offerTestMail(ctx, env); err != nil {
+		t.Fatalf("offerTestMail without mail_test_to: %v", err)
+	}
+
+	ctx.Answers.Inputs = map[string]string{"mail_test_to": "ops@example.com"}
+	if err := offerTestMail(ctx, env); err != nil {
+		t.Fatalf("offerTestMail: %v", err)
+	}
+	s := <-session
+	if !slices.Contains(s.commands, "RCPT TO:<ops@example.com>") {
+		t.Errorf("commands %q have no RCPT TO:<ops@example.com>", s.commands)
+	}
+}
@@ -0,0 +1,177 @@
+package steps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/hooks"
+	"yachtcrm-installer/internal/installer"
+)
+
+// HookStep runs a site-specific hook as an install step.
+type HookStep struct {
+	Hook hooks.Hook
+}
+
+func (s HookStep) Name() string { return "Run hook " + s.Hook.ID }
+
+func (s HookStep) ID() string { return s.Hook.ID }
+
+func (s HookStep) Description() string {
+	if s.Hook.Description != "" {
+		return s.Hook.Description
+	}
+	return "Run " + s.Hook.Command
+}
+
+func (s HookStep) Run(ctx *installer.Context) error {
+	err := s.run(ctx)
+	if err != nil && s.Hook.Optional {
+		ctx.Warnf("hook %s failed, continuing because it is optional: %v", s.Hook.ID, err)
+		return nil
+	}
+	return err
+}
+
+func (s HookStep) run(ctx *installer.Context) error {
+	input, err := hookInput(ctx, s.Hook).Encode()
+	if err != nil {
+		return fmt.Errorf("encode hook input: %w", err)
+	}
+
+	timeout := s.Hook.TimeLimit()
+	runCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	name, args := s.Hook.CommandLine()
+	cmd := exec.CommandContext(runCtx, name, args...)
+	cmd.Dir = s.Hook.Dir
+	// Programs the hook started may keep its output open after it is
+	// killed; stop waiting for them.
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Stdin = bytes.NewReader(input)
+	out := &hookWriter{logWriter: logWriter{ctx: ctx, prefix: "  "}}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	ctx.Logf("Running %s", s.Hook.Command)
+	err = cmd.Run()
+	out.Flush()
+	switch {
+	case runCtx.Err() != nil:
+		return fmt.Errorf("hook timed out after %s", timeout)
+	case err != nil && len(out.errors) > 0:
+		return errors.New(strings.Join(out.errors, "; "))
+	case err != nil:
+		return fmt.Errorf("run hook: %w", err)
+	}
+	return nil
+}
+
+// hookWriter logs hook output like logWriter, turning status lines into
+// warnings and collecting the error messages.
+type hookWriter struct {
+	logWriter
+	errors []string
+}
+
+func (w *hookWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		w.line(string(w.buf[:idx]))
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (w *hookWriter) Flush() {
+	w.line(string(w.buf))
+	w.buf = nil
+}
+
+func (w *hookWriter) line(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	switch kind, msg := hooks.ParseLine(line); kind {
+	case hooks.Warning:
+		w.ctx.Warnf("%s", msg)
+	case hooks.Error:
+		w.ctx.Logf("%sError: %s", w.prefix, msg)
+		w.errors = append(w.errors, msg)
+	default:
+		w.ctx.Logf("%s%s", w.prefix, msg)
+	}
+}
+
+// hookInput is what a hook is told about the install: the context without
+// passwords.
+func hookInput(ctx *installer.Context, h hooks.Hook) hooks.Input {
+	return hooks.Input{
+		Hook:               h.ID,
+		Placement:          h.Placement(),
+		InstanceName:       ctx.InstanceName,
+		RuntimeDir:         ctx.RuntimeDir,
+		CRMSourceDir:       ctx.CRMSourceDir,
+		ExternalDatabase:   ctx.ExternalDatabase,
+		DatabaseHost:       ctx.DatabaseHost,
+		DatabasePort:       ctx.DatabasePort,
+		DatabaseName:       ctx.DatabaseName,
+		DatabaseUser:       ctx.DatabaseUser,
+		AdminName:          ctx.AdminName,
+		AdminEmail:         ctx.AdminEmail,
+		PhpInstallDir:      ctx.PhpInstallDir,
+		PhpIniPath:         ctx.PhpIniPath,
+		PhpExePath:         ctx.PhpExePath,
+		ComposerPath:       ctx.ComposerPath,
+		NodeBinDir:         ctx.NodeBinDir,
+		MariaDBBinDir:      ctx.MariaDBBinDir,
+		PhpMyAdminDir:      ctx.PhpMyAdminDir,
+		AppURL:             ctx.AppURL,
+		FrontendURL:        ctx.FrontendURL,
+		SiteName:           ctx.SiteName,
+		AppPoolName:        ctx.AppPoolName,
+		HostHeader:         ctx.HostHeader,
+		HTTPPort:           ctx.HTTPPort,
+		SchedulerTaskName:  ctx.SchedulerTaskName,
+		SchedulerRunAsUser: ctx.SchedulerRunAsUser,
+		WorkerTaskName:     ctx.WorkerTaskName,
+		EnabledModules:     ctx.EnabledModules,
+		CRMName:            ctx.Branding.CRMName,
+		Log:                ctx.Logs(),
+	}
+}
+
+// AddHooks inserts the hooks from the hooks directory next to the
+// installer, then those in answers, into runner.
+func AddHooks(runner *installer.Runner, answers *installer.Answers) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("determine executable path: %w", err)
+	}
+	list, err := hooks.Load(filepath.Join(filepath.Dir(exePath), "hooks"))
+	if err != nil {
+		return err
+	}
+	if answers != nil {
+		list = append(list, answers.Hooks...)
+	}
+	for _, h := range list {
+		target, after := h.Target()
+		if err := runner.Insert(HookStep{Hook: h}, target, after); err != nil {
+			return fmt.Errorf("hook %s: %w", h.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yachtcrm-installer/internal/branding"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/instances"
+	"yachtcrm-installer/internal/modules"
+	"yachtcrm-installer/internal/prompts"
+)
+
+// Input is one install setting. The steps prompt for it unless the answer
+// file supplies it, and the web wizard renders it as a form field. Keys are
+// lower case for "inputs" answers and the .env key for "env" answers.
+type Input struct {
+	Key      string `json:"key"`
+	Question string `json:"question"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Secret   bool   `json:"secret,omitempty"`
+	// YesNo inputs are confirmations; their value is "yes" or "no".
+	YesNo bool `json:"yes_no,omitempty"`
+	// When limits the input to answers where other inputs have these
+	// values, e.g. the root password only for a local MariaDB.
+	When     map[string]string  `json:"when,omitempty"`
+	Validate func(string) error `json:"-"`
+}
+
+// InputPage groups inputs into one page of the web wizard. Pages with a
+// Toggle are optional .env sections that are only written when enabled.
+type InputPage struct {
+	Title  string  `json:"title"`
+	Toggle string  `json:"toggle,omitempty"`
+	Env    bool    `json:"env,omitempty"`
+	Inputs []Input `json:"inputs"`
+}
+
+// Check treats value the way a typed answer is treated: blank takes the
+// default, and the result must be present when required and pass Validate.
+func (in Input) Check(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		value = in.Default
+	}
+	if in.YesNo {
+		switch strings.ToLower(value) {
+		case "y", "yes", "true":
+			return "yes", nil
+		case "n", "no", "false":
+			return "no", nil
+		}
+		return "", errors.New("answer yes or no")
+	}
+	if value == "" {
+		if in.Required {
+			return "", errors.New("this value is required")
+		}
+		return "", nil
+	}
+	if in.Validate != nil {
+		if err := in.Validate(value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+// Applies reports whether the input is asked, given the other answers.
+func (in Input) Applies(values map[string]string) bool {
+	for key, want := range in.When {
+		if values[key] != want {
+			return false
+		}
+	}
+	return true
+}
+
+var instanceNameInput = Input{Key: "instance_name", Question: "Enter instance name", Default: instances.DefaultName, Required: true, Validate: instances.ValidateName}
+
+// InputPages lists everything the install steps ask, with the defaults of
+// the named instance: its registered settings when it is already
+// installed, otherwise the defaults for a new instance.
+func InputPages(name string) ([]InputPage, error) {
+	if name == "" {
+		name = instances.DefaultName
+	}
+	if err := instances.ValidateName(name); err != nil {
+		return nil, err
+	}
+	registry, err := instances.Load()
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := registry.Get(name)
+	if err != nil {
+		defaults = instances.Defaults(name)
+	}
+	return inputPages(defaults), nil
+}
+
+func inputPages(defaults instances.Instance) []InputPage {
+	local := map[string]string{"local_database": "yes"}
+	external := map[string]string{"local_database": "no"}
+	externalAdmin := map[string]string{"local_database": "no", "database_has_admin": "yes"}
+
+	pages := []InputPage{
+		{Title: "Instance", Inputs: []Input{
+			instanceNameInput,
+			{Key: "runtime_dir", Question: "Enter the YachtCRM-DMS runtime directory", Default: defaults.RuntimeDir, Required: true},
+			{Key: "host_header", Question: "Enter IIS host header (blank to answer any host name)", Default: defaults.HostName, Validate: validateHost},
+			{Key: "http_port", Question: "Enter IIS HTTP port", Default: strconv.Itoa(defaults.Port), Required: true, Validate: validatePort},
+		}},
+		{Title: "Software", Inputs: []Input{
+			{Key: "php_dir", Question: "Enter PHP installation directory", Default: `C:\PHP`, Required: true},
+			{Key: "node_dir", Question: "Enter Node.js installation directory", Default: `C:\nodejs`, Required: true},
+			{Key: "phpmyadmin_dir", Question: "Enter phpMyAdmin installation directory", Default: `C:\inetpub\wwwroot\phpMyAdmin`, Required: true},
+			{Key: "sql_dump", Question: "Enter path to sanitized YachtCRM-DMS SQL dump", Required: true, Validate: validateFile},
+		}},
+		{Title: "Database server", Inputs: []Input{
+			{Key: "local_database", Question: "Install a local MariaDB server? (answer no to use an existing or remote MySQL/MariaDB server)", Default: "yes", YesNo: true},
+			{Key: "mariadb_root_password", Question: "Enter MariaDB root password to configure", Required: true, Secret: true, When: local},
+			{Key: "database_host", Question: "Enter database server host", Required: true, Validate: validateHost, When: external},
+			{Key: "database_port", Question: "Enter database server port", Default: "3306", Required: true, Validate: validatePort, When: external},
+			{Key: "database_has_admin", Question: "Do you have an admin account that can create the database and user? (answer no if both already exist)", Default: "yes", YesNo: true, When: external},
+			{Key: "database_admin_user", Question: "Enter database admin username", Default: "root", Required: true, When: externalAdmin},
+			{Key: "database_admin_password", Question: "Enter database admin password", Required: true, Secret: true, When: externalAdmin},
+			{Key: "database_user_host", Question: "Host the application user connects from (MySQL host pattern)", Default: "%", Required: true, When: externalAdmin},
+		}},
+		{Title: "CRM database", Inputs: []Input{
+			{Key: "database_name", Question: "Enter YachtCRM-DMS database name", Default: defaults.DatabaseName, Required: true},
+			{Key: "database_user", Question: "Enter YachtCRM-DMS database username", Default: defaults.DatabaseUser, Required: true},
+			{Key: "database_password", Question: "Enter password for YachtCRM-DMS database user", Required: true, Secret: true},
+		}},
+		{Title: "Database import", Inputs: seedInputs()},
+		{Title: "Administrator", Inputs: []Input{
+			{Key: "admin_name", Question: "Enter name for initial YachtCRM-DMS admin user", Required: true},
+			{Key: "admin_email", Question: "Enter email for initial YachtCRM-DMS admin user", Required: true, Validate: validateEmail},
+			{Key: "admin_password", Question: "Enter password for initial YachtCRM-DMS admin user", Required: true, Secret: true},
+		}},
+		{Title: "Modules", Inputs: []Input{modulesInput()}},
+		{Title: "Branding", Inputs: brandingInputs()},
+		{Title: "Site address", Inputs: siteInputs()},
+		{Title: "Background jobs", Inputs: append(schedulerInputs(), queueWorkerInput)},
+	}
+	for _, section := range envSections() {
+		page := InputPage{Title: section.Title, Toggle: sectionQuestion(section), Env: true}
+		for _, field := range section.Fields {
+			page.Inputs = append(page.Inputs, field.input())
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// siteInputs are asked by ConfigureEnv. Blank answers after the first take
+// defaults derived from the application URL; the input key upper-cased is
+// the derived .env key.
+func siteInputs() []Input {
+	return []Input{
+		{Key: "app_url", Question: "Application URL", Default: "http://localhost", Required: true, Validate: validateAppURL},
+		{Key: "frontend_url", Question: "Frontend URL"},
+		{Key: "sanctum_stateful_domains", Question: "SANCTUM_STATEFUL_DOMAINS"},
+		{Key: "session_domain", Question: "SESSION_DOMAIN"},
+	}
+}
+
+func modulesInput() Input {
+	return Input{
+		Key:      "modules",
+		Question: fmt.Sprintf("Enter modules to enable (%s, or none; blank keeps the dump's settings)", strings.Join(modules.Keys(), ", ")),
+		Validate: func(v string) error {
+			_, err := modules.Parse(v)
+			return err
+		},
+	}
+}
+
+// brandingInputs are keyed by settings column so the answers convert
+// directly into a branding.Profile, apart from branding_profile, which
+// gates the company profile and logos.
+func brandingInputs() []Input {
+	profile := map[string]string{"branding_profile": "yes"}
+	inputs := []Input{
+		{Key: "crm_name", Question: "Enter the CRM display name", Default: branding.DefaultName, Required: true, Validate: maxLength(255)},
+		{Key: "branding_profile", Question: "Enter the company profile and logos now? (they can be changed later under System Settings > Branding)", Default: "yes", YesNo: true},
+		{Key: "business_name", Question: "Company name (blank to skip)", Validate: maxLength(255), When: profile},
+		{Key: "business_legal_name", Question: "Legal name (blank to skip)", Validate: maxLength(255), When: profile},
+		{Key: "business_phone", Question: "Phone (blank to skip)", Validate: maxLength(50), When: profile},
+		{Key: "business_email", Question: "Email (blank to skip)", Validate: validateEmail, When: profile},
+		{Key: "business_website", Question: "Website (blank to skip)", Validate: maxLength(255), When: profile},
+		{Key: "business_tax_id", Question: "Tax ID (blank to skip)", Validate: maxLength(100), When: profile},
+		{Key: "business_address_line1", Question: "Address line 1 (blank to skip)", Validate: maxLength(255), When: profile},
+		{Key: "business_address_line2", Question: "Address line 2 (blank to skip)", Validate: maxLength(255), When: profile},
+		{Key: "business_city", Question: "City (blank to skip)", Validate: maxLength(120), When: profile},
+		{Key: "business_state", Question: "State (blank to skip)", Validate: maxLength(120), When: profile},
+		{Key: "business_postal_code", Question: "Postal code (blank to skip)", Validate: maxLength(30), When: profile},
+		{Key: "business_country", Question: "Country (blank to skip)", Validate: maxLength(120), When: profile},
+	}
+	logos := []struct{ field, question string }{
+		{"logo_login", "Login page logo"},
+		{"logo_header", "Header logo"},
+		{"logo_invoice", "Invoice logo"},
+	}
+	for _, l := range logos {
+		inputs = append(inputs, Input{
+			Key:      l.field,
+			Question: l.question + " image file (PNG, JPEG, GIF or SVG up to 2 MB; blank to skip)",
+			Validate: branding.ValidateLogo,
+			When:     profile,
+		})
+	}
+	return inputs
+}
+
+func inputsByKey(pages []InputPage) map[string]Input {
+	byKey := map[string]Input{}
+	for _, page := range pages {
+		for _, in := range page.Inputs {
+			byKey[in.Key] = in
+		}
+	}
+	return byKey
+}
+
+// askInput takes the answer file's "inputs" value for in when there is one
+// and prompts otherwise.
+func askInput(ctx *installer.Context, in Input) (string, error) {
+	var answers map[string]string
+	if ctx.Answers != nil {
+		answers = ctx.Answers.Inputs
+	}
+	return ask(answers, in)
+}
+
+func askYesNo(ctx *installer.Context, in Input) (bool, error) {
+	value, err := askInput(ctx, in)
+	return value == "yes", err
+}
+
+func ask(answers map[string]string, in Input) (string, error) {
+	if value, ok := answers[in.Key]; ok {
+		value, err := in.Check(value)
+		if err != nil {
+			return "", fmt.Errorf("answer file: %s: %w", in.Key, err)
+		}
+		return value, nil
+	}
+
+	switch {
+	case in.YesNo:
+		yes, err := prompts.Confirm(in.Question, in.Default == "yes")
+		if err != nil {
+			return "", err
+		}
+		if yes {
+			return "yes", nil
+		}
+		return "no", nil
+	case in.Secret:
+		return prompts.AskSecret(in.Question, in.Required, in.Validate)
+	default:
+		return prompts.AskValidated(in.Question, in.Default, in.Required, in.Validate)
+	}
+}
+
+func validateFile(value string) error {
+	if !fileExists(value) {
+		return errors.New("file not found")
+	}
+	return nil
+}
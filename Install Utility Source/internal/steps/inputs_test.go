@@ -0,0 +1,36 @@
+package steps
+
+import (
+	"testing"
+
+	"yachtcrm-installer/internal/instances"
+)
+
+// TestInputPages checks that every question the steps ask from the answer
+// file has a unique key, and that conditions refer to earlier inputs.
+func TestInputPages(t *testing.T) {
+	seen := map[string]Input{}
+	for _, page := range inputPages(instances.Defaults(instances.DefaultName)) {
+		for _, in := range page.Inputs {
+			if _, dup := seen[in.Key]; dup {
+				t.Errorf("input %s appears twice", in.Key)
+			}
+			for key, want := range in.When {
+				cond, ok := seen[key]
+				if !ok {
+					t.Errorf("input %s depends on %s, which is not asked before it", in.Key, key)
+					continue
+				}
+				if _, err := cond.Check(want); err != nil {
+					t.Errorf("input %s depends on %s = %q, which is not a valid answer: %v", in.Key, key, want, err)
+				}
+			}
+			seen[in.Key] = in
+		}
+	}
+	for _, key := range []string{"scheduler_action", "scheduler_user", "scheduler_password", "queue_worker", "seed_resume", "seed_existing"} {
+		if _, ok := seen[key]; !ok {
+			t.Errorf("input %s is not listed", key)
+		}
+	}
+}
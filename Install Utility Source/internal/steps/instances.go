@@ -0,0 +1,334 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/instances"
+	"yachtcrm-installer/internal/powershell"
+	"yachtcrm-installer/internal/prompts"
+	"yachtcrm-installer/internal/scheduler"
+	"yachtcrm-installer/internal/worker"
+)
+
+// checkInstanceConflicts stops an install that would take over another
+// instance's binding, directory, database or site.
+func checkInstanceConflicts(ctx *installer.Context, registry *instances.Registry) error {
+	inst := instanceRecord(ctx)
+	conflicts := registry.Conflicts(inst)
+
+	bindings, err := instances.SiteBindings()
+	if err != nil {
+		// IIS may not be installed yet on a fresh server.
+		ctx.Warnf("unable to list IIS bindings: %v", err)
+	} else {
+		conflicts = append(conflicts, instances.BindingConflicts(inst, bindings)...)
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("instance %s conflicts with existing installs:\n  %s", inst.Name, strings.Join(conflicts, "\n  "))
+	}
+	return nil
+}
+
+// instanceRecord captures the context as a registry entry.
+func instanceRecord(ctx *installer.Context) instances.Instance {
+	port := ctx.HTTPPort
+	if port == 0 {
+		port = 80
+	}
+	return instances.Instance{
+		Name:          ctx.InstanceName,
+		SiteName:      iisSiteName(ctx),
+		AppPool:       appPoolName(ctx),
+		RuntimeDir:    ctx.RuntimeDir,
+		HostName:      ctx.HostHeader,
+		Port:          port,
+		AppURL:        ctx.AppURL,
+		DatabaseHost:  ctx.DatabaseHost,
+		DatabasePort:  ctx.DatabasePort,
+		DatabaseName:  ctx.DatabaseName,
+		DatabaseUser:  ctx.DatabaseUser,
+		UserHost:      ctx.DatabaseUserHost,
+		ExternalDB:    ctx.ExternalDatabase,
+		SchedulerTask: ctx.SchedulerTaskName,
+		WorkerTask:    ctx.WorkerTaskName,
+		PhpExePath:    ctx.PhpExePath,
+		PHP:           &ctx.PHP,
+		NodeBinDir:    ctx.NodeBinDir,
+		MariaDBBinDir: ctx.MariaDBBinDir,
+	}
+}
+
+// ApplyInstance loads a registered instance into ctx for commands that act
+// on an existing install.
+func ApplyInstance(ctx *installer.Context, inst instances.Instance) {
+	ctx.InstanceName = inst.Name
+	ctx.SiteName = inst.SiteName
+	ctx.AppPoolName = inst.AppPool
+	ctx.RuntimeDir = inst.RuntimeDir
+	ctx.HostHeader = inst.HostName
+	ctx.HTTPPort = inst.Port
+	ctx.AppURL = inst.AppURL
+	ctx.ExternalDatabase = inst.ExternalDB
+	ctx.DatabaseHost = inst.DatabaseHost
+	ctx.DatabasePort = inst.DatabasePort
+	ctx.DatabaseName = inst.DatabaseName
+	ctx.DatabaseUser = inst.DatabaseUser
+	ctx.DatabaseUserHost = inst.UserHost
+	ctx.DatabaseAdminUser = "root"
+	if ctx.DatabaseHost == "" {
+		ctx.DatabaseHost = "127.0.0.1"
+	}
+	if ctx.DatabasePort == 0 {
+		ctx.DatabasePort = 3306
+	}
+	if ctx.DatabaseUserHost == "" {
+		ctx.DatabaseUserHost = "localhost"
+	}
+	ctx.SchedulerTaskName = inst.SchedulerTask
+	ctx.WorkerTaskName = inst.WorkerTask
+	ctx.PhpExePath = inst.PhpExePath
+	ctx.PhpInstallDir = filepath.Dir(inst.PhpExePath)
+	if inst.PHP != nil {
+		ctx.PHP = *inst.PHP
+	}
+	ctx.NodeBinDir = inst.NodeBinDir
+	ctx.MariaDBBinDir = inst.MariaDBBinDir
+}
+
+func appPoolName(ctx *installer.Context) string {
+	if ctx.AppPoolName != "" {
+		return ctx.AppPoolName
+	}
+	return iisSiteName(ctx)
+}
+
+type RegisterInstance struct{}
+
+func (RegisterInstance) Name() string { return "Register Instance" }
+
+func (s RegisterInstance) Run(ctx *installer.Context) error {
+	if ctx.InstanceName == "" {
+		ctx.InstanceName = instances.DefaultName
+	}
+	registry, err := instances.Load()
+	if err != nil {
+		return err
+	}
+	registry.Put(instanceRecord(ctx))
+	if err := registry.Save(); err != nil {
+		return err
+	}
+	ctx.Logf("Instance %s recorded in %s", ctx.InstanceName, instances.RegistryPath())
+	return nil
+}
+
+// UpgradeInstance returns the steps that deploy new code into an existing
+// instance while keeping its .env, storage and data, then migrate its
+// database.
+func UpgradeInstance() []installer.Step {
+	return []installer.Step{
+		UpgradeFiles{},
+		InstallBackendDependencies{},
+		BuildFrontend{},
+		RunMigrations{},
+		RefreshLaravelCache{},
+		StartQueueWorker{},
+		RegisterInstance{},
+	}
+}
+
+type UpgradeFiles struct{}
+
+func (UpgradeFiles) Name() string { return "Upgrade YachtCRM-DMS Files" }
+
+func (s UpgradeFiles) Run(ctx *installer.Context) error {
+	if !dirExists(ctx.CRMSourceDir) {
+		return fmt.Errorf("CRM_Source directory not found at %s", ctx.CRMSourceDir)
+	}
+	if !dirExists(ctx.RuntimeDir) {
+		return fmt.Errorf("runtime directory not found at %s", ctx.RuntimeDir)
+	}
+
+	if cfg := workerConfig(ctx); worker.Installed(cfg.Name) {
+		ctx.Logf("Stopping queue worker %s before upgrade", cfg.Name)
+		if err := worker.Stop(cfg, 2*time.Minute); err != nil {
+			ctx.Warnf("%v", err)
+		}
+	}
+
+	// Site-specific files survive the upgrade.
+	keep := map[string]bool{
+		filepath.Join("backend", ".env"):              true,
+		filepath.Join("backend", "storage"):           true,
+		filepath.Join("backend", "public", "storage"): true,
+		filepath.Join("frontend", ".env"):             true,
+		filepath.Join("frontend", "node_modules"):     true,
+		"httpdocs": true,
+	}
+	ctx.Logf("Copying %s over %s", ctx.CRMSourceDir, ctx.RuntimeDir)
+	err := filepath.Walk(ctx.CRMSourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(ctx.CRMSourceDir, path)
+		if err != nil {
+			return err
+		}
+		if keep[rel] {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		target := filepath.Join(ctx.RuntimeDir, rel)
+		if info.IsDir() {
+			return ensureDir(target)
+		}
+		return copyFile(path, target)
+	})
+	if err != nil {
+		return fmt.Errorf("copy CRM source: %w", err)
+	}
+	ctx.Logf("Instance %s files upgraded", ctx.InstanceName)
+	return nil
+}
+
+type StartQueueWorker struct{}
+
+func (StartQueueWorker) Name() string { return "Start Queue Worker" }
+
+func (s StartQueueWorker) Run(ctx *installer.Context) error {
+	cfg := workerConfig(ctx)
+	if !worker.Installed(cfg.Name) {
+		return nil
+	}
+	if err := worker.Start(cfg); err != nil {
+		return err
+	}
+	ctx.Logf("Queue worker %s restarted", cfg.Name)
+	return nil
+}
+
+// RemoveInstance returns the steps that uninstall an instance.
+func RemoveInstance() []installer.Step {
+	return []installer.Step{
+		RemoveInstanceServices{},
+		RemoveInstanceSite{},
+		RemoveInstanceData{},
+	}
+}
+
+type RemoveInstanceServices struct{}
+
+func (RemoveInstanceServices) Name() string { return "Remove Scheduler and Worker" }
+
+func (s RemoveInstanceServices) Run(ctx *installer.Context) error {
+	if ctx.WorkerTaskName != "" {
+		if err := worker.Remove(ctx.WorkerTaskName); err != nil {
+			return err
+		}
+		ctx.Logf("Queue worker %s removed", ctx.WorkerTaskName)
+	}
+	if ctx.SchedulerTaskName != "" {
+		if err := scheduler.Remove(ctx.SchedulerTaskName); err != nil {
+			return err
+		}
+		ctx.Logf("Scheduler task %s removed", ctx.SchedulerTaskName)
+	}
+	return nil
+}
+
+type RemoveInstanceSite struct{}
+
+func (RemoveInstanceSite) Name() string { return "Remove IIS Site" }
+
+func (s RemoveInstanceSite) Run(ctx *installer.Context) error {
+	script := fmt.Sprintf(`Import-Module WebAdministration
+$site = %s
+$pool = %s
+if (Get-Website -Name $site) { Remove-Website -Name $site }
+$inUse = Get-Website | Where-Object { $_.applicationPool -eq $pool }
+if ((Test-Path IIS:\AppPools\$pool) -and -not $inUse) { Remove-WebAppPool -Name $pool }`,
+		powershell.Quote(iisSiteName(ctx)), powershell.Quote(appPoolName(ctx)))
+	result := powershell.Run(script)
+	if result.Err != nil {
+		return fmt.Errorf("remove IIS site: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	ctx.Logf("IIS site %s and application pool %s removed", iisSiteName(ctx), appPoolName(ctx))
+	return nil
+}
+
+type RemoveInstanceData struct{}
+
+func (RemoveInstanceData) Name() string { return "Remove Instance Data" }
+
+// Interactive is true because the step asks before dropping the database
+// and deleting the files.
+func (RemoveInstanceData) Interactive() bool { return true }
+
+func (s RemoveInstanceData) Run(ctx *installer.Context) error {
+	dropDB, err := prompts.Confirm(fmt.Sprintf("Drop database %s and user %s? This cannot be undone.", ctx.DatabaseName, ctx.DatabaseUser), false)
+	if err != nil {
+		return err
+	}
+	if dropDB {
+		if ctx.ExternalDatabase {
+			adminUser, err := prompts.AskStringDefault(fmt.Sprintf("Enter admin username for %s", ctx.DatabaseHost), ctx.DatabaseAdminUser, true)
+			if err != nil {
+				return err
+			}
+			ctx.DatabaseAdminUser = adminUser
+		}
+		if ctx.RootMariaDBPassword == "" {
+			pwd, err := prompts.AskPassword(fmt.Sprintf("Enter password for database user %s", ctx.DatabaseAdminUser))
+			if err != nil {
+				return err
+			}
+			ctx.RootMariaDBPassword = pwd
+		}
+		db, err := openDatabase(ctx, "")
+		if err != nil {
+			return err
+		}
+		err = db.DropDatabase(ctx.DatabaseName)
+		if err == nil {
+			err = db.DropUser(ctx.DatabaseUser, ctx.DatabaseUserHost)
+		}
+		db.Close()
+		if err != nil {
+			return fmt.Errorf("drop database: %w", err)
+		}
+		ctx.Logf("Database %s and user %s dropped", ctx.DatabaseName, ctx.DatabaseUser)
+	}
+
+	deleteFiles, err := prompts.Confirm(fmt.Sprintf("Delete runtime directory %s?", ctx.RuntimeDir), false)
+	if err != nil {
+		return err
+	}
+	if deleteFiles {
+		if err := os.RemoveAll(ctx.RuntimeDir); err != nil {
+			return fmt.Errorf("delete runtime directory: %w", err)
+		}
+		ctx.Logf("Runtime directory %s deleted", ctx.RuntimeDir)
+	}
+
+	registry, err := instances.Load()
+	if err != nil {
+		return err
+	}
+	registry.Delete(ctx.InstanceName)
+	if err := registry.Save(); err != nil {
+		return err
+	}
+	ctx.Logf("Instance %s removed from registry", ctx.InstanceName)
+	return nil
+}
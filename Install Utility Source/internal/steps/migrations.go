@@ -0,0 +1,140 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/database"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/migrations"
+)
+
+// LoadEnvDatabase reads the connection settings from the deployed
+// backend/.env so commands run against an installed site use the same
+// database and account as the application.
+func LoadEnvDatabase(ctx *installer.Context) error {
+	envPath := filepath.Join(ctx.RuntimeDir, "backend", ".env")
+	env, err := readEnvFile(envPath)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", envPath, err)
+	}
+	if env["DB_DATABASE"] == "" || env["DB_USERNAME"] == "" {
+		return fmt.Errorf("%s has no DB_DATABASE or DB_USERNAME", envPath)
+	}
+	ctx.DatabaseHost = env["DB_HOST"]
+	if ctx.DatabaseHost == "" {
+		ctx.DatabaseHost = "127.0.0.1"
+	}
+	ctx.DatabasePort = 3306
+	if port := env["DB_PORT"]; port != "" {
+		if ctx.DatabasePort, err = strconv.Atoi(port); err != nil {
+			return fmt.Errorf("invalid DB_PORT %q in %s", port, envPath)
+		}
+	}
+	ctx.DatabaseName = env["DB_DATABASE"]
+	ctx.DatabaseUser = env["DB_USERNAME"]
+	ctx.DatabaseUserPassword = env["DB_PASSWORD"]
+	ctx.DatabaseAdminUser = ""
+	return nil
+}
+
+// MigrationStatus compares the migrations in the deployed code with the
+// database's migrations table.
+func MigrationStatus(ctx *installer.Context) (migrations.Status, error) {
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return migrations.Status{}, err
+	}
+	defer db.Close()
+	return migrations.Check(db, filepath.Join(ctx.RuntimeDir, "backend"))
+}
+
+func backupDir() string {
+	base := os.Getenv("ProgramData")
+	if base == "" {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "YachtCRM-DMS", "backups")
+}
+
+// BackupDatabase writes a SQL backup of the CRM database and returns its
+// path.
+func BackupDatabase(ctx *installer.Context, dir string) (string, error) {
+	if dir == "" {
+		dir = backupDir()
+	}
+	if err := ensureDir(dir); err != nil {
+		return "", fmt.Errorf("create backup directory: %w", err)
+	}
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.sql", ctx.DatabaseName, time.Now().Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("create backup: %w", err)
+	}
+	if err := db.Dump(f, database.DumpOptions{}); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("back up database %s: %w", ctx.DatabaseName, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("write backup: %w", err)
+	}
+	return path, nil
+}
+
+// RunMigrations applies pending Laravel migrations after backing up the
+// database. It refuses to run when the database has migrations the code
+// does not know, since migrating an older codebase over a newer schema can
+// lose data.
+type RunMigrations struct {
+	BackupDir string
+}
+
+func (RunMigrations) Name() string { return "Run Database Migrations" }
+
+func (s RunMigrations) Run(ctx *installer.Context) error {
+	if ctx.DatabaseUserPassword == "" {
+		if err := LoadEnvDatabase(ctx); err != nil {
+			return err
+		}
+	}
+	status, err := MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Unknown) > 0 {
+		return fmt.Errorf("database %s has %d migration(s) missing from the deployed code; deploy the matching release first:\n  %s",
+			ctx.DatabaseName, len(status.Unknown), strings.Join(status.Unknown, "\n  "))
+	}
+	if len(status.Pending) == 0 {
+		ctx.Logf("Database schema is up to date (%d migrations applied)", len(status.Applied))
+		return nil
+	}
+	ctx.Logf("%d pending migration(s):", len(status.Pending))
+	for _, name := range status.Pending {
+		ctx.Logf("  %s", name)
+	}
+
+	backup, err := BackupDatabase(ctx, s.BackupDir)
+	if err != nil {
+		return err
+	}
+	ctx.Logf("Database backed up to %s", backup)
+
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	if err := runLogged(ctx, backendDir, nil, ctx.PhpExePath, "artisan", "migrate", "--force", "--no-interaction"); err != nil {
+		return fmt.Errorf("artisan migrate: %w (restore from %s if needed)", err, backup)
+	}
+	ctx.Logf("Migrations applied")
+	return nil
+}
@@ -0,0 +1,114 @@
+package steps
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"yachtcrm-installer/internal/accounts"
+	"yachtcrm-installer/internal/database"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/modules"
+)
+
+// collectModules takes the module selection from the answer file or asks
+// for it. A blank answer keeps whatever the imported dump contains.
+func collectModules(ctx *installer.Context, in Input) error {
+	if ctx.Answers != nil && ctx.Answers.Modules != nil {
+		if err := modules.Validate(ctx.Answers.Modules); err != nil {
+			return fmt.Errorf("answer file: %w", err)
+		}
+		ctx.EnabledModules = ctx.Answers.Modules
+		return nil
+	}
+	value, err := askInput(ctx, in)
+	if err != nil {
+		return err
+	}
+	if value != "" {
+		ctx.EnabledModules, _ = modules.Parse(value)
+	}
+	return nil
+}
+
+// ListModules returns the modules table of the CRM database.
+func ListModules(ctx *installer.Context) ([]modules.Module, error) {
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return modules.List(db)
+}
+
+// ApplyModules enables exactly the selected modules after the dump is
+// imported.
+type ApplyModules struct{}
+
+func (ApplyModules) Name() string { return "Apply Module Selection" }
+
+func (ApplyModules) Run(ctx *installer.Context) error {
+	if ctx.EnabledModules == nil {
+		ctx.Logf("Keeping the module settings from the database dump")
+		return nil
+	}
+	return setModules(ctx, func(db *database.DB) error { return modules.Apply(db, ctx.EnabledModules) },
+		"Enabled modules: %s", formatModules(ctx.EnabledModules))
+}
+
+// SetModules enables or disables individual modules on an installed site.
+type SetModules struct {
+	Keys    []string
+	Enabled bool
+}
+
+func (SetModules) Name() string { return "Update Modules" }
+
+func (s SetModules) Run(ctx *installer.Context) error {
+	verb := "Disabled"
+	if s.Enabled {
+		verb = "Enabled"
+	}
+	return setModules(ctx, func(db *database.DB) error { return modules.Set(db, s.Keys, s.Enabled) },
+		verb+" modules: %s", formatModules(s.Keys))
+}
+
+func formatModules(keys []string) string {
+	if len(keys) == 0 {
+		return "none"
+	}
+	return strings.Join(keys, ", ")
+}
+
+func setModules(ctx *installer.Context, apply func(*database.DB) error, format string, args ...any) error {
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := apply(db); err != nil {
+		return fmt.Errorf("update modules: %w", err)
+	}
+	ctx.Logf(format, args...)
+	clearModuleCache(ctx)
+	return nil
+}
+
+// clearModuleCache drops the cached module list and per-role navigation
+// the API serves, so the change shows without waiting for the hour-long
+// cache to expire.
+func clearModuleCache(ctx *installer.Context) {
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	if !fileExists(filepath.Join(backendDir, "artisan")) || !fileExists(ctx.PhpExePath) {
+		return
+	}
+	keys := []string{"enabled_modules"}
+	for _, role := range accounts.Roles {
+		keys = append(keys, "nav_order_"+role)
+	}
+	for _, key := range keys {
+		if err := runLogged(ctx, backendDir, nil, ctx.PhpExePath, "artisan", "cache:forget", key); err != nil {
+			ctx.Warnf("could not clear cached %s: %v", key, err)
+		}
+	}
+}
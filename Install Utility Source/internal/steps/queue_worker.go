@@ -0,0 +1,143 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/worker"
+)
+
+// queueTablesSQL creates the tables Laravel's database queue driver uses.
+var queueTablesSQL = []string{
+	"CREATE TABLE IF NOT EXISTS `jobs` (" +
+		"`id` bigint unsigned NOT NULL AUTO_INCREMENT, " +
+		"`queue` varchar(255) NOT NULL, " +
+		"`payload` longtext NOT NULL, " +
+		"`attempts` tinyint unsigned NOT NULL, " +
+		"`reserved_at` int unsigned DEFAULT NULL, " +
+		"`available_at` int unsigned NOT NULL, " +
+		"`created_at` int unsigned NOT NULL, " +
+		"PRIMARY KEY (`id`), KEY `jobs_queue_index` (`queue`)" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci",
+	"CREATE TABLE IF NOT EXISTS `failed_jobs` (" +
+		"`id` bigint unsigned NOT NULL AUTO_INCREMENT, " +
+		"`uuid` varchar(255) NOT NULL, " +
+		"`connection` text NOT NULL, " +
+		"`queue` text NOT NULL, " +
+		"`payload` longtext NOT NULL, " +
+		"`exception` longtext NOT NULL, " +
+		"`failed_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+		"PRIMARY KEY (`id`), UNIQUE KEY `failed_jobs_uuid_unique` (`uuid`)" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci",
+}
+
+type ConfigureQueueWorker struct{}
+
+func (ConfigureQueueWorker) Name() string { return "Configure Queue Worker" }
+
+var queueWorkerInput = Input{Key: "queue_worker", Question: "Run a supervised queue worker (switches QUEUE_CONNECTION to database)?", Default: "yes", YesNo: true}
+
+func (s ConfigureQueueWorker) Run(ctx *installer.Context) error {
+	enable, err := askYesNo(ctx, queueWorkerInput)
+	if err != nil {
+		return err
+	}
+	if !enable {
+		if cfg := workerConfig(ctx); worker.Installed(cfg.Name) {
+			if err := worker.Remove(cfg.Name); err != nil {
+				return err
+			}
+			ctx.Logf("Removed existing queue worker %s", cfg.Name)
+		}
+		ctx.Logf("Queue worker not configured; queued jobs run synchronously")
+		return nil
+	}
+
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	if err := createQueueTables(ctx); err != nil {
+		return fmt.Errorf("create queue tables: %w", err)
+	}
+	if err := updateEnvFile(filepath.Join(backendDir, ".env"), map[string]string{"QUEUE_CONNECTION": "database"}); err != nil {
+		return fmt.Errorf("set QUEUE_CONNECTION: %w", err)
+	}
+	ctx.Logf("Queue driver switched to database")
+
+	cfg := workerConfig(ctx)
+	if worker.Installed(cfg.Name) {
+		ctx.Logf("Stopping existing queue worker %s", cfg.Name)
+		if err := worker.Stop(cfg, 2*time.Minute); err != nil {
+			return err
+		}
+	}
+
+	exePath, err := installWorkerBinary()
+	if err != nil {
+		return err
+	}
+
+	svc := worker.Service{
+		TaskName:      cfg.Name,
+		ExePath:       exePath,
+		Args:          []string{"worker", "--name", cfg.Name, "--php", cfg.PhpExePath, "--backend", cfg.BackendDir, "--log", cfg.LogPath},
+		RunAsUser:     ctx.SchedulerRunAsUser,
+		RunAsPassword: ctx.SchedulerRunAsPassword,
+	}
+	if err := worker.Install(svc); err != nil {
+		return err
+	}
+	if err := worker.Start(cfg); err != nil {
+		return err
+	}
+
+	ctx.Logf("Queue worker %s running; log at %s", cfg.Name, cfg.LogPath)
+	return nil
+}
+
+func workerConfig(ctx *installer.Context) worker.Config {
+	name := ctx.WorkerTaskName
+	if name == "" {
+		name = worker.DefaultTaskName
+	}
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	return worker.Config{
+		Name:       name,
+		PhpExePath: ctx.PhpExePath,
+		BackendDir: backendDir,
+		LogPath:    filepath.Join(backendDir, "storage", "logs", "queue-worker.log"),
+	}
+}
+
+// installWorkerBinary copies the running installer to a stable location so
+// the worker service does not depend on the extracted installer package.
+func installWorkerBinary() (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("determine executable: %w", err)
+	}
+	dest := filepath.Join(filepath.Dir(worker.DefaultStateDir()), "bin", "yachtcrm-installer.exe")
+	if strings.EqualFold(filepath.Clean(self), filepath.Clean(dest)) {
+		return dest, nil
+	}
+	if err := copyFile(self, dest); err != nil {
+		return "", fmt.Errorf("install worker binary: %w", err)
+	}
+	return dest, nil
+}
+
+func createQueueTables(ctx *installer.Context) error {
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	for _, stmt := range queueTablesSQL {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
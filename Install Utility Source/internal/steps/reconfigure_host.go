@@ -0,0 +1,321 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/instances"
+	"yachtcrm-installer/internal/powershell"
+)
+
+// DefaultSiteName is the IIS site and application pool created by
+// ConfigureIIS when no other name is given.
+const DefaultSiteName = "YachtCRM-DMS"
+
+// ReconfigureHost returns the steps that move an installed site to a new
+// URL: a check that the new bindings are free, .env, IIS bindings, the
+// frontend build and Laravel's caches.
+func ReconfigureHost() []installer.Step {
+	return []installer.Step{
+		CheckHostBindings{},
+		UpdateHostEnv{},
+		ConfigureBindings{},
+		BuildFrontend{},
+		RefreshLaravelCache{},
+		AddHostsEntry{},
+	}
+}
+
+func iisSiteName(ctx *installer.Context) string {
+	if ctx.SiteName != "" {
+		return ctx.SiteName
+	}
+	return DefaultSiteName
+}
+
+// hostEnvValues derives every host-dependent .env key from the public URL
+// so they cannot drift apart.
+func hostEnvValues(appURL string) (map[string]string, error) {
+	u, err := url.Parse(strings.TrimSpace(appURL))
+	if err != nil {
+		return nil, fmt.Errorf("parse application URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("application URL must start with http:// or https://")
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("application URL has no host")
+	}
+
+	base := u.Scheme + "://" + u.Host + strings.TrimRight(u.Path, "/")
+	stateful := u.Host
+	if u.Hostname() != "localhost" {
+		stateful += ",localhost,127.0.0.1"
+	} else {
+		stateful += ",127.0.0.1"
+	}
+	return map[string]string{
+		"APP_URL":                  base,
+		"FRONTEND_URL":             base + "/frontend",
+		"SANCTUM_STATEFUL_DOMAINS": stateful,
+		"SESSION_DOMAIN":           u.Hostname(),
+	}, nil
+}
+
+func validateAppURL(value string) error {
+	_, err := hostEnvValues(value)
+	return err
+}
+
+type UpdateHostEnv struct{}
+
+func (UpdateHostEnv) Name() string { return "Update Host in .env" }
+
+func (s UpdateHostEnv) Run(ctx *installer.Context) error {
+	values, err := hostEnvValues(ctx.AppURL)
+	if err != nil {
+		return err
+	}
+	envPath := filepath.Join(ctx.RuntimeDir, "backend", ".env")
+	if err := updateEnvFile(envPath, values); err != nil {
+		return fmt.Errorf("update .env: %w", err)
+	}
+	ctx.AppURL = values["APP_URL"]
+	ctx.FrontendURL = values["FRONTEND_URL"]
+	for _, key := range []string{"APP_URL", "FRONTEND_URL", "SANCTUM_STATEFUL_DOMAINS", "SESSION_DOMAIN"} {
+		ctx.Logf("%s=%s", key, values[key])
+	}
+	return nil
+}
+
+// bindingPorts returns the ports the site binds for u. An http URL binds
+// its own port; without one it keeps httpPort, the instance's current HTTP
+// port, or 80 for none. An https URL binds its port, 443 by default, and
+// keeps plain HTTP on httpPort so the site can redirect to HTTPS.
+func bindingPorts(u *url.URL, httpPort int) (int, int, error) {
+	if httpPort == 0 {
+		httpPort = 80
+	}
+	port := 0
+	if p := u.Port(); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return 0, 0, fmt.Errorf("invalid port %q", p)
+		}
+		port = n
+	}
+	if u.Scheme == "http" {
+		if port != 0 {
+			httpPort = port
+		}
+		return httpPort, 0, nil
+	}
+	if port == 0 {
+		port = 443
+	}
+	if port == httpPort {
+		return 0, 0, fmt.Errorf("HTTPS port %d is already the site's HTTP port", port)
+	}
+	return httpPort, port, nil
+}
+
+// CheckHostBindings works out the host header and ports for the new URL
+// and stops the move before anything changes when another IIS site or
+// registered instance already answers on them. It records the binding in
+// ctx so the instance registry matches what IIS ends up with.
+type CheckHostBindings struct{}
+
+func (CheckHostBindings) Name() string { return "Check Host Bindings" }
+
+func (s CheckHostBindings) Run(ctx *installer.Context) error {
+	u, err := url.Parse(ctx.AppURL)
+	if err != nil {
+		return fmt.Errorf("parse application URL: %w", err)
+	}
+	httpPort, httpsPort, err := bindingPorts(u, ctx.HTTPPort)
+	if err != nil {
+		return err
+	}
+	ctx.HostHeader, ctx.HTTPPort = u.Hostname(), httpPort
+
+	registry, err := instances.Load()
+	if err != nil {
+		return err
+	}
+	bindings, err := instances.SiteBindings()
+	if err != nil {
+		return err
+	}
+	ports := []int{httpPort}
+	if httpsPort != 0 {
+		ports = append(ports, httpsPort)
+	}
+	if conflicts := hostBindingConflicts(ctx, registry.List(), bindings, ports); len(conflicts) > 0 {
+		return fmt.Errorf("%s conflicts with existing sites:\n  %s", ctx.AppURL, strings.Join(conflicts, "\n  "))
+	}
+	ctx.Logf("No other IIS site or instance uses the bindings for %s", ctx.AppURL)
+	return nil
+}
+
+// hostBindingConflicts reports IIS bindings and registered instances that
+// already use ctx.HostHeader on one of ports. Bindings of the
+// instance's own site do not count; they are replaced.
+func hostBindingConflicts(ctx *installer.Context, registered []instances.Instance, bindings []instances.Binding, ports []int) []string {
+	inst := instanceRecord(ctx)
+	var conflicts []string
+	for _, port := range ports {
+		inst.Port = port
+		conflicts = append(conflicts, instances.BindingConflicts(inst, bindings)...)
+		for _, other := range registered {
+			if other.Name == inst.Name || strings.EqualFold(other.SiteName, inst.SiteName) {
+				continue
+			}
+			if other.Port == port && strings.EqualFold(other.HostName, inst.HostName) {
+				conflicts = append(conflicts, fmt.Sprintf("port %d with host header %q is used by instance %s", port, inst.HostName, other.Name))
+			}
+		}
+	}
+	return conflicts
+}
+
+type ConfigureBindings struct{}
+
+func (ConfigureBindings) Name() string { return "Configure IIS Bindings" }
+
+func (s ConfigureBindings) Run(ctx *installer.Context) error {
+	u, err := url.Parse(ctx.AppURL)
+	if err != nil {
+		return fmt.Errorf("parse application URL: %w", err)
+	}
+	hostName := u.Hostname()
+	httpPort, httpsPort, err := bindingPorts(u, ctx.HTTPPort)
+	if err != nil {
+		return err
+	}
+
+	script := strings.Builder{}
+	script.WriteString(fmt.Sprintf(`Import-Module WebAdministration
+$site = %s
+$hostName = %s
+if (-not (Get-Website -Name $site)) { throw "IIS site $site not found" }
+Get-WebBinding -Name $site | Where-Object { $_.protocol -in @('http','https') } | ForEach-Object {
+    Remove-WebBinding -Name $site -BindingInformation $_.bindingInformation -Protocol $_.protocol
+}
+`, powershell.Quote(iisSiteName(ctx)), powershell.Quote(hostName)))
+
+	script.WriteString(fmt.Sprintf("New-WebBinding -Name $site -Protocol http -Port %d -HostHeader $hostName\n", httpPort))
+	if httpsPort != 0 {
+		thumbprint := ctx.TLSCertThumbprint
+		if thumbprint == "" {
+			thumbprint, err = findCertificate(hostName)
+			if err != nil {
+				return err
+			}
+			ctx.Logf("Using certificate %s for %s", thumbprint, hostName)
+		}
+		script.WriteString(fmt.Sprintf("New-WebBinding -Name $site -Protocol https -Port %d -HostHeader $hostName -SslFlags 1\n", httpsPort))
+		script.WriteString(fmt.Sprintf("(Get-WebBinding -Name $site -Protocol https -Port %d -HostHeader $hostName).AddSslCertificate(%s, 'My')\n",
+			httpsPort, powershell.Quote(thumbprint)))
+		ctx.TLSCertThumbprint = thumbprint
+	}
+
+	result := powershell.Run(script.String())
+	if result.Err != nil {
+		return fmt.Errorf("configure IIS bindings: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	ctx.HostHeader, ctx.HTTPPort = hostName, httpPort
+	ctx.Logf("IIS site %s bound to http://%s:%d", iisSiteName(ctx), hostName, httpPort)
+	if httpsPort != 0 {
+		ctx.Logf("IIS site %s bound to https://%s:%d", iisSiteName(ctx), hostName, httpsPort)
+	}
+	return nil
+}
+
+// findCertificate returns the thumbprint of the newest valid certificate in
+// LocalMachine\My that covers hostName.
+func findCertificate(hostName string) (string, error) {
+	script := fmt.Sprintf(`Get-ChildItem Cert:\LocalMachine\My | Where-Object { $_.DnsNameList.Unicode -contains %s -and $_.NotAfter -gt (Get-Date) } | Sort-Object NotAfter -Descending | Select-Object -First 1 -ExpandProperty Thumbprint`,
+		powershell.Quote(hostName))
+	result := powershell.Run(script)
+	if result.Err != nil {
+		return "", fmt.Errorf("search certificate store: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	if result.Stdout == "" {
+		return "", fmt.Errorf("no valid certificate for %s in LocalMachine\\My; import one or pass its thumbprint", hostName)
+	}
+	return result.Stdout, nil
+}
+
+type RefreshLaravelCache struct{}
+
+func (RefreshLaravelCache) Name() string { return "Refresh Laravel Caches" }
+
+func (s RefreshLaravelCache) Run(ctx *installer.Context) error {
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	for _, args := range [][]string{
+		{"artisan", "optimize:clear"},
+		{"artisan", "config:cache"},
+		{"artisan", "route:cache"},
+	} {
+		if err := runLogged(ctx, backendDir, nil, ctx.PhpExePath, args...); err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+		}
+	}
+	ctx.Logf("Laravel caches rebuilt")
+	return nil
+}
+
+type AddHostsEntry struct{}
+
+func (AddHostsEntry) Name() string { return "Add Hosts Entry" }
+
+func (s AddHostsEntry) Run(ctx *installer.Context) error {
+	if !ctx.AddHostsEntry {
+		return nil
+	}
+	u, err := url.Parse(ctx.AppURL)
+	if err != nil {
+		return fmt.Errorf("parse application URL: %w", err)
+	}
+	hostName := u.Hostname()
+	if hostName == "localhost" || net.ParseIP(hostName) != nil {
+		ctx.Logf("%s needs no hosts entry", hostName)
+		return nil
+	}
+
+	hostsPath := filepath.Join(os.Getenv("SystemRoot"), "System32", "drivers", "etc", "hosts")
+	data, err := os.ReadFile(hostsPath)
+	if err != nil {
+		return fmt.Errorf("read hosts file: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		for _, name := range fields[min(1, len(fields)):] {
+			if strings.EqualFold(name, hostName) {
+				ctx.Logf("Hosts file already maps %s", hostName)
+				return nil
+			}
+		}
+	}
+
+	contents := string(data)
+	if contents != "" && !strings.HasSuffix(contents, "\n") {
+		contents += "\r\n"
+	}
+	contents += fmt.Sprintf("127.0.0.1\t%s\t# YachtCRM-DMS\r\n", hostName)
+	if err := os.WriteFile(hostsPath, []byte(contents), 0o644); err != nil {
+		return fmt.Errorf("write hosts file: %w", err)
+	}
+	ctx.Logf("Added hosts entry 127.0.0.1 %s", hostName)
+	return nil
+}
@@ -0,0 +1,81 @@
+package steps
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/instances"
+)
+
+func TestBindingPorts(t *testing.T) {
+	tests := []struct {
+		url       string
+		current   int
+		http      int
+		https     int
+		wantError bool
+	}{
+		{url: "http://crm.example.com", http: 80},
+		{url: "http://crm.example.com", current: 8081, http: 8081},
+		{url: "http://crm.example.com:8090", current: 8081, http: 8090},
+		{url: "https://crm.example.com", http: 80, https: 443},
+		{url: "https://crm.example.com", current: 8081, http: 8081, https: 443},
+		{url: "https://crm.example.com:8443", current: 8081, http: 8081, https: 8443},
+		{url: "https://crm.example.com:8081", current: 8081, wantError: true},
+		{url: "http://crm.example.com:0", wantError: true},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		http, https, err := bindingPorts(u, tt.current)
+		if tt.wantError {
+			if err == nil {
+				t.Errorf("bindingPorts(%s, %d) = %d, %d, want an error", tt.url, tt.current, http, https)
+			}
+			continue
+		}
+		if err != nil || http != tt.http || https != tt.https {
+			t.Errorf("bindingPorts(%s, %d) = %d, %d, %v, want %d, %d", tt.url, tt.current, http, https, err, tt.http, tt.https)
+		}
+	}
+}
+
+func TestHostBindingConflicts(t *testing.T) {
+	ctx := &installer.Context{InstanceName: "staging", SiteName: "YachtCRM-DMS-staging", HostHeader: "crm.example.com", HTTPPort: 8081}
+	registered := []instances.Instance{
+		// The instance itself, still registered under its old host name.
+		{Name: "staging", SiteName: "YachtCRM-DMS-staging", HostName: "crm.example.com", Port: 8081},
+		{Name: "default", SiteName: "YachtCRM-DMS", HostName: "crm.example.com", Port: 80},
+		{Name: "training", SiteName: "YachtCRM-DMS-training", HostName: "training.example.com", Port: 8081},
+	}
+	bindings := []instances.Binding{
+		{Site: "YachtCRM-DMS-staging", Protocol: "http", Port: 8081, Host: "crm.example.com"},
+		{Site: "Intranet", Protocol: "https", Port: 443, Host: "crm.example.com"},
+		{Site: "Default Web Site", Protocol: "http", Port: 80, Host: ""},
+	}
+
+	tests := []struct {
+		ports []int
+		want  []string
+	}{
+		{ports: []int{8081}},
+		{ports: []int{80}, want: []string{"instance default"}},
+		{ports: []int{8081, 443}, want: []string{"IIS site Intranet already binds https port 443"}},
+	}
+	for _, tt := range tests {
+		got := hostBindingConflicts(ctx, registered, bindings, tt.ports)
+		if len(got) != len(tt.want) {
+			t.Errorf("ports %v: conflicts %q, want %d matching %q", tt.ports, got, len(tt.want), tt.want)
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(got[i], want) {
+				t.Errorf("ports %v: conflict %q does not mention %q", tt.ports, got[i], want)
+			}
+		}
+	}
+}
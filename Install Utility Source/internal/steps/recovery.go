@@ -0,0 +1,122 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"yachtcrm-installer/internal/accounts"
+	"yachtcrm-installer/internal/installer"
+)
+
+// DefaultAuditLog is where account recovery actions are recorded.
+func DefaultAuditLog() string {
+	base := os.Getenv("ProgramData")
+	if base == "" {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "YachtCRM-DMS", "audit", "accounts.log")
+}
+
+// RecoverAccount performs break-glass changes to one CRM account directly in
+// the database. Each action is recorded in the audit log whether or not it
+// succeeds.
+type RecoverAccount struct {
+	Email            string
+	Password         string
+	DisableMFA       bool
+	NewRecoveryCodes bool
+	Activate         bool
+	RevokeSessions   bool
+	AuditLog         string
+}
+
+func (RecoverAccount) Name() string { return "Recover Account" }
+
+func (s RecoverAccount) Run(ctx *installer.Context) error {
+	if s.Password == "" && !s.DisableMFA && !s.NewRecoveryCodes && !s.Activate && !s.RevokeSessions {
+		return fmt.Errorf("no recovery action requested")
+	}
+	if s.DisableMFA && s.NewRecoveryCodes {
+		return fmt.Errorf("disabling MFA and reissuing recovery codes are mutually exclusive")
+	}
+	auditLog := s.AuditLog
+	if auditLog == "" {
+		auditLog = DefaultAuditLog()
+	}
+
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	user, err := accounts.Find(db, s.Email)
+	if err != nil {
+		return err
+	}
+	ctx.Logf("Recovering %s (id %d, role %s, status %s)", user.Email, user.ID, user.Role, user.Status)
+
+	var failed []string
+	record := func(action, detail string, err error) {
+		entry := accounts.AuditEntry{Database: ctx.DatabaseName, UserID: user.ID, Email: user.Email, Action: action, Detail: detail}
+		if err != nil {
+			entry.Error = err.Error()
+			failed = append(failed, action)
+			ctx.Warnf("%s failed: %v", action, err)
+		} else {
+			ctx.Logf("%s: %s", action, detail)
+		}
+		if err := accounts.AppendAudit(auditLog, entry); err != nil {
+			ctx.Warnf("could not write audit log %s: %v", auditLog, err)
+		}
+	}
+
+	if s.Password != "" {
+		hash, err := accounts.HashPassword(s.Password)
+		if err == nil {
+			err = accounts.SetPassword(db, user.ID, hash)
+		}
+		if err == nil {
+			err = checkLogin(ctx, user.Email, s.Password)
+		}
+		record("reset-password", "password replaced and remember-me token cleared", err)
+	}
+	if s.DisableMFA {
+		record("disable-mfa", "TOTP, email codes and recovery codes removed", accounts.DisableMFA(db, user.ID))
+	}
+	if s.NewRecoveryCodes {
+		codes, err := accounts.NewRecoveryCodes(db, user.ID)
+		if errors.Is(err, accounts.ErrNoMFA) {
+			err = fmt.Errorf("%w; use --disable-mfa instead", err)
+		}
+		// The codes themselves are shown to the operator only, never logged
+		// to the audit file.
+		record("recovery-codes", fmt.Sprintf("%d new recovery codes issued", len(codes)), err)
+		if err == nil {
+			fmt.Printf("\nNew recovery codes for %s (each works once):\n  %s\n\n", user.Email, strings.Join(codes, "\n  "))
+		}
+	}
+	if s.Activate {
+		changed, err := accounts.Activate(db, user.ID)
+		detail := "status set to active"
+		if err == nil && !changed {
+			detail = "users table has no status column; account is already usable"
+		}
+		record("activate", detail, err)
+	}
+	if s.RevokeSessions {
+		tokens, err := accounts.RevokeTokens(db, user.ID)
+		record("revoke-tokens", fmt.Sprintf("%d API token(s) deleted", tokens), err)
+		sessions, err := accounts.RevokeSessions(db, user.ID)
+		record("revoke-sessions", fmt.Sprintf("%d session(s) deleted", sessions), err)
+	}
+
+	ctx.Logf("Audit log: %s", auditLog)
+	if len(failed) > 0 {
+		return fmt.Errorf("%s failed for %s", strings.Join(failed, ", "), user.Email)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"yachtcrm-installer/internal/database"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/sanitize"
+)
+
+// SanitizeDump exports the CRM database with personal data replaced by
+// consistent fake values, producing the sanitized dump the installer
+// imports.
+type SanitizeDump struct {
+	Output    string
+	RulesPath string
+	Salt      string
+}
+
+func (SanitizeDump) Name() string { return "Export Sanitized Database Dump" }
+
+func (s SanitizeDump) Run(ctx *installer.Context) error {
+	rules, err := sanitize.LoadRules(s.RulesPath)
+	if err != nil {
+		return err
+	}
+	sanitizer, err := sanitize.New(rules, s.Salt)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	warnings, err := sanitizer.Check(db)
+	for _, w := range warnings {
+		ctx.Warnf("%s", w)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := ensureDir(filepath.Dir(s.Output)); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	// Write next to the target and rename, so a failed export never leaves
+	// a half-sanitized file where a complete one is expected.
+	tmp := s.Output + ".partial"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create dump: %w", err)
+	}
+	err = db.Dump(f, database.DumpOptions{SchemaOnly: sanitizer.SchemaOnly(), Transform: sanitizer.Transform})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("export %s: %w", ctx.DatabaseName, err)
+	}
+	if err := os.Rename(tmp, s.Output); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write dump: %w", err)
+	}
+	ctx.Logf("Sanitized dump of %s written to %s", ctx.DatabaseName, s.Output)
+	return nil
+}
@@ -0,0 +1,124 @@
+package steps
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/scheduler"
+)
+
+type RegisterScheduler struct{}
+
+func (RegisterScheduler) Name() string { return "Register Laravel Scheduler" }
+
+// schedulerInputs are asked by RegisterScheduler. The password is only
+// asked for an account that is not a built-in service account.
+func schedulerInputs() []Input {
+	install := map[string]string{"scheduler_action": "install"}
+	return []Input{
+		{Key: "scheduler_action", Question: "Scheduler task action (install, remove, skip)", Default: "install", Required: true, Validate: oneOf("install", "remove", "skip")},
+		{Key: "scheduler_user", Question: "Run the scheduler as account", Default: "SYSTEM", Required: true, When: install},
+		{Key: "scheduler_password", Question: "Password for the scheduler account (blank for SYSTEM, LOCAL SERVICE or NETWORK SERVICE)", Secret: true, When: install},
+	}
+}
+
+func (s RegisterScheduler) Run(ctx *installer.Context) error {
+	if ctx.SchedulerTaskName == "" {
+		ctx.SchedulerTaskName = scheduler.DefaultTaskName
+	}
+	in := inputsByKey([]InputPage{{Inputs: schedulerInputs()}})
+
+	action, err := askInput(ctx, in["scheduler_action"])
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "skip":
+		ctx.Warnf("scheduler task registration skipped; scheduled jobs will not run")
+		return nil
+	case "remove":
+		if err := scheduler.Remove(ctx.SchedulerTaskName); err != nil {
+			return err
+		}
+		ctx.Logf("Scheduler task %s removed", ctx.SchedulerTaskName)
+		return nil
+	}
+
+	if ctx.SchedulerRunAsUser == "" {
+		user, err := askInput(ctx, in["scheduler_user"])
+		if err != nil {
+			return err
+		}
+		ctx.SchedulerRunAsUser = user
+	}
+	if !scheduler.IsServiceAccount(ctx.SchedulerRunAsUser) && ctx.SchedulerRunAsPassword == "" {
+		pwdInput := in["scheduler_password"]
+		pwdInput.Question = fmt.Sprintf("Enter password for %s", ctx.SchedulerRunAsUser)
+		pwdInput.Required = true
+		pwd, err := askInput(ctx, pwdInput)
+		if err != nil {
+			return err
+		}
+		ctx.SchedulerRunAsPassword = pwd
+	}
+
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	if !fileExists(filepath.Join(backendDir, "artisan")) {
+		return fmt.Errorf("artisan not found in %s", backendDir)
+	}
+
+	task := scheduler.Task{
+		Name:          ctx.SchedulerTaskName,
+		PhpExePath:    ctx.PhpExePath,
+		BackendDir:    backendDir,
+		RunAsUser:     ctx.SchedulerRunAsUser,
+		RunAsPassword: ctx.SchedulerRunAsPassword,
+	}
+	if err := scheduler.Register(task); err != nil {
+		return err
+	}
+	ctx.Logf("Scheduler task %s registered to run every minute as %s", task.Name, ctx.SchedulerRunAsUser)
+
+	return verifySchedulerTask(ctx, task.Name)
+}
+
+// verifySchedulerTask starts the task once and waits for its result so a
+// broken PHP path or account surfaces during install instead of silently.
+// A task that already existed still reports its previous run, so the result
+// only counts once LastRunTime has moved past the one seen before the start
+// and the run is no longer in progress.
+func verifySchedulerTask(ctx *installer.Context, name string) error {
+	before, err := scheduler.Query(name)
+	if err != nil {
+		return err
+	}
+	if err := scheduler.Start(name); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(90 * time.Second)
+	for {
+		status, err := scheduler.Query(name)
+		if err != nil {
+			return err
+		}
+		finished := status.LastRunTime != before.LastRunTime && status.State != "Running" &&
+			status.LastResult != scheduler.ResultRunning && status.LastResult != scheduler.ResultNeverRun && status.LastResult != scheduler.ResultNotStarted
+		if finished {
+			if status.LastResult != scheduler.ResultSuccess {
+				return fmt.Errorf("scheduler task %s last run failed with result 0x%X", name, status.LastResult)
+			}
+			ctx.Logf("Scheduler task %s ran successfully at %s", name, strings.TrimSpace(status.LastRunTime))
+			return nil
+		}
+		if time.Now().After(deadline) {
+			ctx.Warnf("scheduler task %s has not completed a run yet (state %s)", name, status.State)
+			return nil
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
@@ -0,0 +1,186 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"yachtcrm-installer/internal/database"
+	"yachtcrm-installer/internal/installer"
+)
+
+// importCheckpoint is saved while a dump is imported so a failed or
+// interrupted import can continue where it stopped.
+type importCheckpoint struct {
+	Dump     string               `json:"dump"`
+	Size     int64                `json:"size"`
+	Modified time.Time            `json:"modified"`
+	Database string               `json:"database"`
+	State    database.ImportState `json:"state"`
+}
+
+func importCheckpointPath(dbName string) string {
+	base := os.Getenv("ProgramData")
+	if base == "" {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "YachtCRM-DMS", "import", dbName+".json")
+}
+
+// loadImportCheckpoint returns the saved position for this dump and
+// database, or nil if there is none or the dump has changed since.
+func loadImportCheckpoint(path string, dump os.FileInfo, dumpPath, dbName string) *importCheckpoint {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cp importCheckpoint
+	if json.Unmarshal(data, &cp) != nil {
+		return nil
+	}
+	if cp.Dump != dumpPath || cp.Database != dbName || cp.Size != dump.Size() || !cp.Modified.Equal(dump.ModTime()) {
+		return nil
+	}
+	return &cp
+}
+
+func (cp *importCheckpoint) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+type SeedDatabase struct{}
+
+// seedInputs are asked by SeedDatabase when there is a saved import
+// position for the dump, or when the database already has tables.
+// "overwrite" drops every table and view before importing.
+func seedInputs() []Input {
+	return []Input{
+		{Key: "seed_resume", Question: "Resume an interrupted import of the SQL dump where it stopped?", Default: "yes", YesNo: true},
+		{Key: "seed_existing", Question: "Import action when the CRM database already has tables (skip, overwrite, abort)", Default: "skip", Required: true, Validate: oneOf("skip", "overwrite", "abort")},
+	}
+}
+
+func (SeedDatabase) Name() string { return "Seed Database" }
+
+func (s SeedDatabase) Run(ctx *installer.Context) error {
+	if ctx.SqlDumpPath == "" || !fileExists(ctx.SqlDumpPath) {
+		return fmt.Errorf("SQL dump not found at %s", ctx.SqlDumpPath)
+	}
+	dump, err := os.Open(ctx.SqlDumpPath)
+	if err != nil {
+		return fmt.Errorf("open SQL dump: %w", err)
+	}
+	defer dump.Close()
+	info, err := dump.Stat()
+	if err != nil {
+		return fmt.Errorf("stat SQL dump: %w", err)
+	}
+
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	in := inputsByKey([]InputPage{{Inputs: seedInputs()}})
+	cpPath := importCheckpointPath(ctx.DatabaseName)
+	cp := loadImportCheckpoint(cpPath, info, ctx.SqlDumpPath, ctx.DatabaseName)
+	var resume *database.ImportState
+	if cp != nil {
+		resumeInput := in["seed_resume"]
+		resumeInput.Question = fmt.Sprintf("A previous import of this dump stopped after line %d (%d statements). Resume it?", cp.State.Line, cp.State.Statements)
+		ok, err := askYesNo(ctx, resumeInput)
+		if err != nil {
+			return err
+		}
+		if ok {
+			resume = &cp.State
+		}
+	}
+
+	if resume == nil {
+		tables, err := db.TableCount()
+		if err != nil {
+			return fmt.Errorf("inspect database %s: %w", ctx.DatabaseName, err)
+		}
+		if tables > 0 {
+			existingInput := in["seed_existing"]
+			existingInput.Question = fmt.Sprintf("Database %s already has %d tables. Import action (skip, overwrite, abort)", ctx.DatabaseName, tables)
+			action, err := askInput(ctx, existingInput)
+			if err != nil {
+				return err
+			}
+			switch action {
+			case "skip":
+				ctx.Logf("Keeping existing data in %s; dump not imported", ctx.DatabaseName)
+				return nil
+			case "abort":
+				return fmt.Errorf("database %s is not empty", ctx.DatabaseName)
+			}
+			// Tables the dump does not create would otherwise survive, and
+			// a dump without DROP TABLE statements would fail on the first
+			// CREATE TABLE.
+			dropped, err := db.DropTables()
+			if err != nil {
+				return fmt.Errorf("empty database %s: %w", ctx.DatabaseName, err)
+			}
+			ctx.Logf("Dropped %d tables and views from %s", dropped, ctx.DatabaseName)
+		}
+		cp = &importCheckpoint{Dump: ctx.SqlDumpPath, Size: info.Size(), Modified: info.ModTime(), Database: ctx.DatabaseName}
+	} else {
+		if _, err := dump.Seek(resume.Offset, io.SeekStart); err != nil {
+			return fmt.Errorf("seek SQL dump: %w", err)
+		}
+		ctx.Logf("Resuming import at line %d", resume.Line)
+	}
+
+	ctx.Logf("Importing SQL dump %s (%d MB)", ctx.SqlDumpPath, info.Size()>>20)
+	var lastSave, lastLog time.Time
+	lastPercent := -1
+	state, err := db.Import(dump, database.ImportOptions{
+		Resume: resume,
+		Progress: func(st database.ImportState) {
+			cp.State = st
+			now := time.Now()
+			if now.Sub(lastSave) >= 2*time.Second {
+				if err := cp.save(cpPath); err != nil && lastSave.IsZero() {
+					ctx.Warnf("unable to save import checkpoint: %v", err)
+				}
+				lastSave = now
+			}
+			percent := 100
+			if info.Size() > 0 {
+				percent = int(st.Offset * 100 / info.Size())
+			}
+			if percent/5 != lastPercent/5 || now.Sub(lastLog) >= 30*time.Second {
+				ctx.Logf("  %3d%%  %d/%d MB, %d statements, line %d", percent, st.Offset>>20, info.Size()>>20, st.Statements, st.Line)
+				lastPercent, lastLog = percent, now
+			}
+		},
+	})
+	if err != nil {
+		cp.State = state
+		if saveErr := cp.save(cpPath); saveErr != nil {
+			ctx.Warnf("unable to save import checkpoint: %v", saveErr)
+		} else {
+			ctx.Logf("Import position saved; rerun the installer to resume after line %d", state.Line)
+		}
+		return fmt.Errorf("import %s: %w", filepath.Base(ctx.SqlDumpPath), err)
+	}
+
+	if err := os.Remove(cpPath); err != nil && !os.IsNotExist(err) {
+		ctx.Warnf("unable to remove import checkpoint: %v", err)
+	}
+	ctx.Logf("Database seeded successfully (%d statements)", state.Statements)
+	return nil
+}
@@ -2,6 +2,10 @@ package steps
 
 import "yachtcrm-installer/internal/installer"
 
+// All returns the install steps. The runner starts each step once the steps
+// it depends on have completed, so the prerequisites install side by side.
+// Steps without declared dependencies wait for every step listed before
+// them.
 func All() []installer.Step {
 	return []installer.Step{
 		CollectInputs{},
@@ -10,14 +14,255 @@ func All() []installer.Step {
 		InstallPHP{},
 		InstallComposer{},
 		InstallMariaDB{},
+		ValidateDatabaseServer{},
 		ConfigureMariaDB{},
 		InstallPhpMyAdmin{},
 		InstallNode{},
-		DeployYachtCRM-DMS{},
-		ConfigureIIS{},
+		DeployYachtCRMDMS{},
+		InstallBackendDependencies{},
 		ConfigureEnv{},
+		BuildFrontend{},
+		ConfigureIIS{},
 		SeedDatabase{},
 		CreateAdminUser{},
+		ApplyModules{},
+		ApplyBranding{},
+		RegisterScheduler{},
+		ConfigureQueueWorker{},
 		ConfigureFirewall{},
+		RegisterInstance{},
 	}
 }
+
+// IDs select steps for install --only, --skip, --from and --to and are
+// listed by the steps command; they stay stable when step names change.
+
+func (CollectInputs) ID() string { return "inputs" }
+func (CollectInputs) Description() string {
+	return "Ask for the instance, software paths, database, administrator, modules and branding"
+}
+
+// Mandatory is true because every other step reads the settings.
+func (CollectInputs) Mandatory() bool { return true }
+
+func (CheckPrerequisites) ID() string { return "prerequisites" }
+func (CheckPrerequisites) Description() string {
+	return "Locate the Prerequisites and CRM_Source directories and the installer archives"
+}
+
+func (InstallIISFeatures) ID() string { return "iis-features" }
+func (InstallIISFeatures) Description() string {
+	return "Enable the IIS features, CGI and URL Rewrite"
+}
+
+func (InstallPHP) ID() string { return "php" }
+func (InstallPHP) Description() string {
+	return "Extract PHP, create php.ini with the required extensions and add PHP to PATH"
+}
+
+func (InstallComposer) ID() string { return "composer" }
+func (InstallComposer) Description() string {
+	return "Download composer.phar next to PHP and write composer.bat"
+}
+
+func (InstallMariaDB) ID() string { return "mariadb" }
+func (InstallMariaDB) Description() string {
+	return "Install the local MariaDB server (nothing to do for an external server)"
+}
+
+func (ValidateDatabaseServer) ID() string { return "database-check" }
+func (ValidateDatabaseServer) Description() string {
+	return "Check the database server version, character set and account privileges"
+}
+
+func (ConfigureMariaDB) ID() string { return "database" }
+func (ConfigureMariaDB) Description() string {
+	return "Tune my.ini and create the CRM database and user"
+}
+
+func (InstallPhpMyAdmin) ID() string { return "phpmyadmin" }
+func (InstallPhpMyAdmin) Description() string {
+	return "Extract phpMyAdmin and write config.inc.php"
+}
+
+func (InstallNode) ID() string { return "node" }
+func (InstallNode) Description() string {
+	return "Extract Node.js and add it to PATH"
+}
+
+func (DeployYachtCRMDMS) ID() string { return "deploy" }
+func (DeployYachtCRMDMS) Description() string {
+	return "Copy CRM_Source into the runtime directory"
+}
+
+func (InstallBackendDependencies) ID() string { return "backend" }
+func (InstallBackendDependencies) Description() string {
+	return "Run composer install for the Laravel backend"
+}
+
+func (ConfigureEnv) ID() string { return "env" }
+func (ConfigureEnv) Description() string {
+	return "Write backend/.env and generate the application key"
+}
+
+func (BuildFrontend) ID() string { return "frontend" }
+func (BuildFrontend) Description() string {
+	return "Build the frontend with npm against the application URL"
+}
+
+func (ConfigureIIS) ID() string { return "iis" }
+func (ConfigureIIS) Description() string {
+	return "Create the IIS site, app pool and PHP FastCGI handler and write web.config"
+}
+
+func (SeedDatabase) ID() string { return "seed" }
+func (SeedDatabase) Description() string {
+	return "Import the SQL dump, resuming an interrupted import"
+}
+
+func (CreateAdminUser) ID() string { return "admin-user" }
+func (CreateAdminUser) Description() string {
+	return "Create or update the initial administrator and check the login"
+}
+
+func (ApplyModules) ID() string { return "modules" }
+func (ApplyModules) Description() string {
+	return "Enable the selected product modules"
+}
+
+func (ApplyBranding) ID() string { return "branding" }
+func (ApplyBranding) Description() string {
+	return "Install the logos and save the CRM name and company profile"
+}
+
+func (RegisterScheduler) ID() string { return "scheduler" }
+func (RegisterScheduler) Description() string {
+	return "Register the Laravel scheduler as a Windows scheduled task"
+}
+
+func (ConfigureQueueWorker) ID() string { return "queue-worker" }
+func (ConfigureQueueWorker) Description() string {
+	return "Set up the supervised queue worker"
+}
+
+func (ConfigureFirewall) ID() string { return "firewall" }
+func (ConfigureFirewall) Description() string {
+	return "Add Windows Firewall rules for the site (not implemented yet)"
+}
+
+func (RegisterInstance) ID() string { return "register" }
+func (RegisterInstance) Description() string {
+	return "Record the instance in the instance registry"
+}
+
+// Interactive steps ask questions the answer file does not answer. The
+// runner runs one at a time so their dialogs do not interleave.
+
+func (CollectInputs) Interactive() bool        { return true }
+func (CheckPrerequisites) Interactive() bool   { return true }
+func (ConfigureEnv) Interactive() bool         { return true }
+func (SeedDatabase) Interactive() bool         { return true }
+func (RegisterScheduler) Interactive() bool    { return true }
+func (ConfigureQueueWorker) Interactive() bool { return true }
+
+func (CheckPrerequisites) Outputs() []string {
+	return []string{"PrerequisitesDir", "CRMSourceDir", "DownloadsDir", "ComposerInstallerPath", "MariaDBInstallerPath", "NodeZipPath", "PhpNtsZipPath", "PhpTsZipPath", "PhpMyAdminZipPath"}
+}
+
+func (InstallIISFeatures) DependsOn() []installer.Step {
+	return []installer.Step{CheckPrerequisites{}}
+}
+
+func (InstallPHP) DependsOn() []installer.Step {
+	return []installer.Step{CheckPrerequisites{}}
+}
+
+func (InstallPHP) Outputs() []string { return []string{"PhpExePath"} }
+
+func (InstallComposer) DependsOn() []installer.Step {
+	return []installer.Step{InstallPHP{}}
+}
+
+func (InstallComposer) Outputs() []string { return []string{"ComposerPath"} }
+
+func (InstallMariaDB) DependsOn() []installer.Step {
+	return []installer.Step{CheckPrerequisites{}}
+}
+
+func (InstallMariaDB) Outputs() []string { return []string{"MariaDBBinDir"} }
+
+func (ValidateDatabaseServer) DependsOn() []installer.Step {
+	return []installer.Step{InstallMariaDB{}}
+}
+
+func (ConfigureMariaDB) DependsOn() []installer.Step {
+	return []installer.Step{ValidateDatabaseServer{}}
+}
+
+func (InstallPhpMyAdmin) DependsOn() []installer.Step {
+	return []installer.Step{CheckPrerequisites{}}
+}
+
+func (InstallNode) DependsOn() []installer.Step {
+	return []installer.Step{CheckPrerequisites{}}
+}
+
+func (InstallNode) Outputs() []string { return []string{"NodeBinDir"} }
+
+// DependsOn includes InstallPHP because a running queue worker is stopped
+// through php artisan before the files are replaced.
+func (DeployYachtCRMDMS) DependsOn() []installer.Step {
+	return []installer.Step{CheckPrerequisites{}, InstallPHP{}}
+}
+
+func (InstallBackendDependencies) DependsOn() []installer.Step {
+	return []installer.Step{InstallComposer{}, DeployYachtCRMDMS{}}
+}
+
+// DependsOn includes InstallBackendDependencies because artisan
+// key:generate needs vendor/.
+func (ConfigureEnv) DependsOn() []installer.Step {
+	return []installer.Step{InstallBackendDependencies{}}
+}
+
+func (ConfigureEnv) Outputs() []string { return []string{"AppURL", "FrontendURL"} }
+
+func (BuildFrontend) DependsOn() []installer.Step {
+	return []installer.Step{InstallNode{}, ConfigureEnv{}}
+}
+
+// DependsOn includes BuildFrontend because the frontend web.config is
+// written into dist/.
+func (ConfigureIIS) DependsOn() []installer.Step {
+	return []installer.Step{InstallIISFeatures{}, InstallPHP{}, DeployYachtCRMDMS{}, BuildFrontend{}}
+}
+
+func (SeedDatabase) DependsOn() []installer.Step {
+	return []installer.Step{ConfigureMariaDB{}}
+}
+
+func (CreateAdminUser) DependsOn() []installer.Step {
+	return []installer.Step{SeedDatabase{}, ConfigureEnv{}}
+}
+
+func (ApplyModules) DependsOn() []installer.Step {
+	return []installer.Step{SeedDatabase{}, ConfigureEnv{}}
+}
+
+func (ApplyBranding) DependsOn() []installer.Step {
+	return []installer.Step{SeedDatabase{}, DeployYachtCRMDMS{}}
+}
+
+func (RegisterScheduler) DependsOn() []installer.Step {
+	return []installer.Step{ConfigureEnv{}}
+}
+
+// DependsOn lists the steps that read .env through artisan, since the
+// worker setup rewrites it.
+func (ConfigureQueueWorker) DependsOn() []installer.Step {
+	return []installer.Step{CreateAdminUser{}, ApplyModules{}, ApplyBranding{}, RegisterScheduler{}}
+}
+
+func (ConfigureFirewall) DependsOn() []installer.Step {
+	return []installer.Step{ConfigureIIS{}}
+}
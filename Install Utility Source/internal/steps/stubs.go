@@ -1,19 +1,18 @@
 package steps
 
 import (
-	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
-
-	"golang.org/x/crypto/bcrypt"
+	"time"
 
 	"yachtcrm-installer/internal/installer"
 	"yachtcrm-installer/internal/powershell"
 	"yachtcrm-installer/internal/prompts"
 	"yachtcrm-installer/internal/templates"
+	"yachtcrm-installer/internal/worker"
 )
 
 type CheckPrerequisites struct{}
@@ -40,17 +39,8 @@ func (s CheckPrerequisites) Run(ctx *installer.Context) error {
 
 	ctx.Logf("Using prerequisites directory %s", ctx.PrerequisitesDir)
 
-	requiredFiles := map[string]*string{
-		"Composer-Setup.exe":                 &ctx.ComposerInstallerPath,
-		"mariadb-11.8.4-winx64.msi":          &ctx.MariaDBInstallerPath,
-		"node-v22.21.1-win-x64.zip":          &ctx.NodeZipPath,
-		"php-8.3.27-nts-Win32-vs16-x64.zip":  &ctx.PhpNtsZipPath,
-		"php-8.3.27-Win32-vs16-x64.zip":      &ctx.PhpTsZipPath,
-		"phpMyAdmin-5.2.3-all-languages.zip": &ctx.PhpMyAdminZipPath,
-	}
-
 	missing := []string{}
-	for fileName, dest := range requiredFiles {
+	for fileName, dest := range prerequisiteFiles(ctx) {
 		fullPath := filepath.Join(ctx.PrerequisitesDir, fileName)
 		if !fileExists(fullPath) {
 			missing = append(missing, fileName)
@@ -82,23 +72,44 @@ func (s CheckPrerequisites) Run(ctx *installer.Context) error {
 
 	ctx.Logf("Using CRM_Source directory %s", ctx.CRMSourceDir)
 
-	if ctx.DownloadsDir != "" {
-		if err := ensureDir(ctx.DownloadsDir); err != nil {
-			return fmt.Errorf("prepare downloads directory: %w", err)
+	return prepareDownloadsDir(ctx)
+}
+
+// Skip picks up the prerequisite files that are present without asking for
+// missing directories. A selected install step whose archive is missing
+// fails on its own.
+func (CheckPrerequisites) Skip(ctx *installer.Context) error {
+	for fileName, dest := range prerequisiteFiles(ctx) {
+		if fullPath := filepath.Join(ctx.PrerequisitesDir, fileName); fileExists(fullPath) {
+			*dest = fullPath
 		}
-	} else {
+	}
+	return prepareDownloadsDir(ctx)
+}
+
+func prerequisiteFiles(ctx *installer.Context) map[string]*string {
+	return map[string]*string{
+		"Composer-Setup.exe":                 &ctx.ComposerInstallerPath,
+		"mariadb-11.8.4-winx64.msi":          &ctx.MariaDBInstallerPath,
+		"node-v22.21.1-win-x64.zip":          &ctx.NodeZipPath,
+		"php-8.3.27-nts-Win32-vs16-x64.zip":  &ctx.PhpNtsZipPath,
+		"php-8.3.27-Win32-vs16-x64.zip":      &ctx.PhpTsZipPath,
+		"phpMyAdmin-5.2.3-all-languages.zip": &ctx.PhpMyAdminZipPath,
+	}
+}
+
+func prepareDownloadsDir(ctx *installer.Context) error {
+	if ctx.DownloadsDir == "" {
 		exePath, err := os.Executable()
 		if err != nil {
 			return fmt.Errorf("determine executable: %w", err)
 		}
-		exeDir := filepath.Dir(exePath)
-		ctx.DownloadsDir = filepath.Join(exeDir, "downloads")
-		if err := ensureDir(ctx.DownloadsDir); err != nil {
-			return fmt.Errorf("prepare downloads directory: %w", err)
-		}
+		ctx.DownloadsDir = filepath.Join(filepath.Dir(exePath), "downloads")
+	}
+	if err := ensureDir(ctx.DownloadsDir); err != nil {
+		return fmt.Errorf("prepare downloads directory: %w", err)
 	}
 	ctx.Logf("Downloads directory available at %s", ctx.DownloadsDir)
-
 	return nil
 }
 
@@ -138,7 +149,7 @@ func (s InstallIISFeatures) Run(ctx *installer.Context) error {
 	enableScript.WriteString("    }\n")
 	enableScript.WriteString("}\n")
 
-	result := powershell.Run(enableScript.String())
+	result := runSystemInstall(enableScript.String())
 	if result.Err != nil {
 		return fmt.Errorf("enable IIS features: %w (stderr: %s)", result.Err, result.Stderr)
 	}
@@ -166,7 +177,7 @@ func (s InstallIISFeatures) Run(ctx *installer.Context) error {
 
 	escaped := strings.ReplaceAll(rewritePath, "'", "''")
 	installRewrite := fmt.Sprintf("Start-Process msiexec.exe -ArgumentList '/i','%s','/quiet','/norestart' -Wait", escaped)
-	result = powershell.Run(installRewrite)
+	result = runSystemInstall(installRewrite)
 	if result.Err != nil {
 		return fmt.Errorf("install URL Rewrite: %w (stderr: %s)", result.Err, result.Stderr)
 	}
@@ -225,23 +236,15 @@ func (s InstallPHP) Run(ctx *installer.Context) error {
 	if err != nil {
 		return fmt.Errorf("read php.ini: %w", err)
 	}
-	ini := string(iniContents)
-
-	for _, ext := range []string{"curl", "fileinfo", "gd", "mbstring", "openssl", "pdo_mysql", "zip", "bcmath"} {
-		with, replaced := replaceFirst(ini, ";extension="+ext, "extension="+ext)
-		if replaced {
-			ini = with
-		} else if !strings.Contains(ini, "extension="+ext) {
-			ini += "\nextension=" + ext
-		}
+	cfg, err := ctx.PHP.Resolve()
+	if err != nil {
+		return err
+	}
+	ctx.Logf("Applying the %s php.ini profile", ctx.PHP.ProfileName())
+	ini, err := applyPhpIni(ctx, string(iniContents), cfg)
+	if err != nil {
+		return err
 	}
-
-	ini = setIniValue(ini, "memory_limit", "256M")
-	ini = setIniValue(ini, "max_execution_time", "300")
-	ini = setIniValue(ini, "upload_max_filesize", "20M")
-	ini = setIniValue(ini, "post_max_size", "20M")
-	ini = setIniValue(ini, "max_input_vars", "3000")
-
 	if err := os.WriteFile(ctx.PhpIniPath, []byte(ini), 0o644); err != nil {
 		return fmt.Errorf("write php.ini: %w", err)
 	}
@@ -250,9 +253,9 @@ func (s InstallPHP) Run(ctx *installer.Context) error {
 	escaped := strings.ReplaceAll(ctx.PhpInstallDir, `\`, `\\`)
 	escaped = strings.ReplaceAll(escaped, "'", "''")
 	ps := fmt.Sprintf(`$path = [Environment]::GetEnvironmentVariable('Path','Machine'); if (-not $path.Split(';') -contains '%s') { [Environment]::SetEnvironmentVariable('Path',$path+';%s','Machine') }`, ctx.PhpInstallDir, ctx.PhpInstallDir)
-	result := powershell.Run(ps)
+	result := updateMachinePath(ps)
 	if result.Err != nil {
-		ctx.Logf("Warning: failed to append PHP to PATH automatically: %v", result.Err)
+		ctx.Warnf("failed to append PHP to PATH automatically: %v", result.Err)
 	}
 
 	ctx.PhpExePath = filepath.Join(ctx.PhpInstallDir, "php.exe")
@@ -262,7 +265,7 @@ func (s InstallPHP) Run(ctx *installer.Context) error {
 
 	versionResult := powershell.Run(fmt.Sprintf(`"%s" -v`, ctx.PhpExePath))
 	if versionResult.Err != nil {
-		ctx.Logf("Warning: php.exe -v failed: %v", versionResult.Err)
+		ctx.Warnf("php.exe -v failed: %v", versionResult.Err)
 	} else {
 		ctx.Logf("PHP installed: %s", versionResult.Stdout)
 	}
@@ -270,6 +273,17 @@ func (s InstallPHP) Run(ctx *installer.Context) error {
 	return nil
 }
 
+// Skip uses the PHP already installed in PhpInstallDir.
+func (InstallPHP) Skip(ctx *installer.Context) error {
+	exe := filepath.Join(ctx.PhpInstallDir, "php.exe")
+	if !fileExists(exe) {
+		return fmt.Errorf("php.exe not found at %s; run the php step", exe)
+	}
+	ctx.PhpExePath = exe
+	ctx.Logf("Using PHP at %s", exe)
+	return nil
+}
+
 type InstallComposer struct{}
 
 func (InstallComposer) Name() string { return "Install Composer" }
@@ -296,11 +310,26 @@ func (s InstallComposer) Run(ctx *installer.Context) error {
 	return nil
 }
 
+// Skip uses the Composer wrapper already written next to PHP.
+func (InstallComposer) Skip(ctx *installer.Context) error {
+	wrapper := filepath.Join(ctx.PhpInstallDir, "composer.bat")
+	if !fileExists(wrapper) {
+		return fmt.Errorf("composer.bat not found at %s; run the composer step", wrapper)
+	}
+	ctx.ComposerPath = wrapper
+	ctx.Logf("Using Composer at %s", wrapper)
+	return nil
+}
+
 type InstallMariaDB struct{}
 
 func (InstallMariaDB) Name() string { return "Install MariaDB" }
 
 func (s InstallMariaDB) Run(ctx *installer.Context) error {
+	if ctx.ExternalDatabase {
+		ctx.Logf("Using existing database server %s:%d", ctx.DatabaseHost, ctx.DatabasePort)
+		return nil
+	}
 	if ctx.MariaDBInstallerPath == "" {
 		return fmt.Errorf("MariaDB installer not located")
 	}
@@ -313,7 +342,7 @@ func (s InstallMariaDB) Run(ctx *installer.Context) error {
 		escaped := strings.ReplaceAll(ctx.MariaDBInstallerPath, "'", "''")
 		password := strings.ReplaceAll(ctx.RootMariaDBPassword, "'", "''")
 		script := fmt.Sprintf(`$args = @('/i','%s','/qn','/norestart','SERVICENAME=MariaDB','ADDLOCAL=ALL','ENABLETCPIP=1','TCPPORT=3306','ALLOWREMOTEROOTACCESS=1','PASSWORD=%s'); Start-Process msiexec.exe -ArgumentList $args -Wait`, escaped, password)
-		result := powershell.Run(script)
+		result := runSystemInstall(script)
 		if result.Err != nil {
 			return fmt.Errorf("install MariaDB: %w (stderr: %s)", result.Err, result.Stderr)
 		}
@@ -333,6 +362,21 @@ func (s InstallMariaDB) Run(ctx *installer.Context) error {
 	return nil
 }
 
+// Skip locates an existing MariaDB installation. External servers need
+// nothing from this step.
+func (InstallMariaDB) Skip(ctx *installer.Context) error {
+	if ctx.ExternalDatabase {
+		return nil
+	}
+	binDir, err := findMariaDBBinDir()
+	if err != nil {
+		return fmt.Errorf("locate MariaDB bin directory: %w; run the mariadb step", err)
+	}
+	ctx.MariaDBBinDir = binDir
+	ctx.Logf("Using MariaDB binaries at %s", binDir)
+	return nil
+}
+
 type ConfigureMariaDB struct{}
 
 func (ConfigureMariaDB) Name() string { return "Configure MariaDB" }
@@ -341,51 +385,32 @@ func (s ConfigureMariaDB) Run(ctx *installer.Context) error {
 	if ctx.MariaDBBinDir == "" {
 		return fmt.Errorf("MariaDB bin directory not known; ensure Install MariaDB step ran")
 	}
+	if ctx.ExternalDatabase {
+		return provisionDatabase(ctx)
+	}
 
 	configPath, err := findMariaDBConfig(ctx.MariaDBBinDir)
 	if err != nil {
-		ctx.Logf("Warning: %v", err)
+		ctx.Warnf("%v", err)
 	} else {
 		contents, readErr := os.ReadFile(configPath)
 		if readErr == nil {
-			ini := string(contents)
-			ini = setIniValue(ini, "innodb_buffer_pool_size", "1G")
-			ini = setIniValue(ini, "max_connections", "150")
-			ini = setIniValue(ini, "query_cache_size", "64M")
-			ini = setIniValue(ini, "innodb_log_file_size", "256M")
-			if writeErr := os.WriteFile(configPath, []byte(ini), 0o644); writeErr != nil {
-				ctx.Logf("Warning: unable to update %s: %v", configPath, writeErr)
+			ini, updateErr := applyMariaDBIni(string(contents))
+			if updateErr == nil {
+				updateErr = os.WriteFile(configPath, []byte(ini), 0o644)
+			}
+			if updateErr != nil {
+				ctx.Warnf("unable to update %s: %v", configPath, updateErr)
 			} else {
 				ctx.Logf("Updated MariaDB configuration at %s", configPath)
-				powershell.Run(`Get-Service -Name "MariaDB*" -ErrorAction SilentlyContinue | ForEach-Object { Restart-Service -Name $_.Name -Force }`)
+				restartMariaDB()
 			}
 		} else {
-			ctx.Logf("Warning: unable to read %s: %v", configPath, readErr)
+			ctx.Warnf("unable to read %s: %v", configPath, readErr)
 		}
 	}
 
-	mysqlExe := filepath.Join(ctx.MariaDBBinDir, "mysql.exe")
-	if !fileExists(mysqlExe) {
-		return fmt.Errorf("mysql.exe not found at %s", mysqlExe)
-	}
-
-	db := ctx.DatabaseName
-	user := ctx.DatabaseUser
-	userPwd := ctx.DatabaseUserPassword
-
-	sql := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;\nCREATE USER IF NOT EXISTS '%s'@'localhost' IDENTIFIED BY '%s';\nGRANT ALL PRIVILEGES ON `%s`.* TO '%s'@'localhost';\nFLUSH PRIVILEGES;", db, user, userPwd, db, user)
-
-	script := fmt.Sprintf(`$sql = @'
-%s
-'@
-& '%s' -u root --password='%s' -e $sql`, sql, mysqlExe, escapeSingleQuotes(ctx.RootMariaDBPassword))
-	result := powershell.Run(script)
-	if result.Err != nil {
-		return fmt.Errorf("configure database: %w (stderr: %s)", result.Err, result.Stderr)
-	}
-
-	ctx.Logf("Database %s and user %s configured", db, user)
-	return nil
+	return provisionDatabase(ctx)
 }
 
 type InstallPhpMyAdmin struct{}
@@ -488,9 +513,9 @@ func (s InstallNode) Run(ctx *installer.Context) error {
 	}
 
 	ps := fmt.Sprintf(`[Environment]::SetEnvironmentVariable('Path',[Environment]::GetEnvironmentVariable('Path','Machine')+';%s','Machine')`, ctx.NodeBinDir)
-	result := powershell.Run(ps)
+	result := updateMachinePath(ps)
 	if result.Err != nil {
-		ctx.Logf("Warning: failed to add Node.js to PATH automatically: %v", result.Err)
+		ctx.Warnf("failed to add Node.js to PATH automatically: %v", result.Err)
 	}
 
 	version := powershell.Run(fmt.Sprintf(`"%s" -v`, nodeExe))
@@ -501,15 +526,34 @@ func (s InstallNode) Run(ctx *installer.Context) error {
 	return nil
 }
 
-type DeployYachtCRM-DMS struct{}
+// Skip uses the Node.js already installed in NodeInstallDir.
+func (InstallNode) Skip(ctx *installer.Context) error {
+	if nodeExe := filepath.Join(ctx.NodeInstallDir, "node.exe"); !fileExists(nodeExe) {
+		return fmt.Errorf("node.exe not found at %s; run the node step", nodeExe)
+	}
+	ctx.NodeBinDir = ctx.NodeInstallDir
+	ctx.Logf("Using Node.js in %s", ctx.NodeBinDir)
+	return nil
+}
+
+type DeployYachtCRMDMS struct{}
 
-func (DeployYachtCRM-DMS) Name() string { return "Deploy YachtCRM-DMS Files" }
+func (DeployYachtCRMDMS) Name() string { return "Deploy YachtCRM-DMS Files" }
 
-func (s DeployYachtCRM-DMS) Run(ctx *installer.Context) error {
+func (s DeployYachtCRMDMS) Run(ctx *installer.Context) error {
 	if ctx.CRMSourceDir == "" {
 		return fmt.Errorf("CRM source directory not set")
 	}
 
+	// A running queue worker holds files open under the runtime directory;
+	// let it finish its current job before the old files are removed.
+	if cfg := workerConfig(ctx); worker.Installed(cfg.Name) {
+		ctx.Logf("Stopping queue worker %s before deployment", cfg.Name)
+		if err := worker.Stop(cfg, 2*time.Minute); err != nil {
+			ctx.Warnf("%v", err)
+		}
+	}
+
 	ctx.Logf("Deploying YachtCRM-DMS from %s to %s", ctx.CRMSourceDir, ctx.RuntimeDir)
 	if err := os.RemoveAll(ctx.RuntimeDir); err != nil {
 		return fmt.Errorf("clear runtime directory: %w", err)
@@ -556,49 +600,18 @@ type ConfigureIIS struct{}
 func (ConfigureIIS) Name() string { return "Configure IIS" }
 
 func (s ConfigureIIS) Run(ctx *installer.Context) error {
-	backendPath := filepath.Join(ctx.RuntimeDir, "backend", "public")
-	frontendPath := filepath.Join(ctx.RuntimeDir, "frontend", "dist")
+	backendPath, frontendPath := sitePaths(ctx)
 	if !dirExists(backendPath) {
 		return fmt.Errorf("backend public directory not found at %s", backendPath)
 	}
 	if !dirExists(frontendPath) {
 		return fmt.Errorf("frontend dist directory not found at %s", frontendPath)
 	}
-
-	poolName := "YachtCRM-DMS"
-	siteName := "YachtCRM-DMS"
-	phpCgi := filepath.Join(ctx.PhpInstallDir, "php-cgi.exe")
-	if !fileExists(phpCgi) {
+	if phpCgi := phpCgiPath(ctx); !fileExists(phpCgi) {
 		return fmt.Errorf("php-cgi.exe not found at %s", phpCgi)
 	}
 
-	script := fmt.Sprintf(`Import-Module WebAdministration
-$pool = '%s'
-if (-not (Test-Path IIS:\AppPools\$pool)) { New-WebAppPool -Name $pool | Out-Null }
-Set-ItemProperty IIS:\AppPools\$pool managedRuntimeVersion ""
-Set-ItemProperty IIS:\AppPools\$pool managedPipelineMode "Integrated"
-Set-ItemProperty IIS:\AppPools\$pool enable32BitAppOnWin64 0
-$site = '%s'
-$physical = '%s'
-if (Get-Website $site -ErrorAction SilentlyContinue) {
-    Set-ItemProperty IIS:\Sites\$site physicalPath $physical
-    Set-ItemProperty IIS:\Sites\$site applicationPool $pool
-} else {
-    New-Website -Name $site -Port 80 -PhysicalPath $physical -ApplicationPool $pool | Out-Null
-}
-$frontendPath = '%s'
-if (Get-WebVirtualDirectory -Site $site -Name 'frontend' -ErrorAction SilentlyContinue) {
-    Remove-WebVirtualDirectory -Site $site -Name 'frontend'
-}
-New-WebVirtualDirectory -Site $site -Name 'frontend' -PhysicalPath $frontendPath | Out-Null
-$appcmd = Join-Path $env:windir 'system32\\inetsrv\\appcmd.exe'
-& $appcmd set config -section:system.webServer/handlers /-"[name='PHP_via_FastCGI']" 2>$null
-& $appcmd set config -section:system.webServer/fastCgi /-"[fullPath='%s']" 2>$null
-& $appcmd set config -section:system.webServer/fastCgi /+"[fullPath='%s']" /commit:apphost | Out-Null
-& $appcmd set config -section:system.webServer/handlers /+"[name='PHP_via_FastCGI',path='*.php',verb='GET,HEAD,POST,PUT,DELETE,PATCH,OPTIONS',modules='FastCgiModule',scriptProcessor='%s',resourceType='Either',requireAccess='Script']" /commit:apphost | Out-Null
-`, poolName, siteName, backendPath, frontendPath, phpCgi, phpCgi, phpCgi)
-
-	result := powershell.Run(script)
+	result := powershell.Run(iisScript(ctx))
 	if result.Err != nil {
 		return fmt.Errorf("configure IIS: %w (stderr: %s)", result.Err, result.Stderr)
 	}
@@ -614,22 +627,19 @@ $appcmd = Join-Path $env:windir 'system32\\inetsrv\\appcmd.exe'
 	}
 
 	// Ensure IIS user has write permissions to storage directories.
-	storageDirs := []string{
-		filepath.Join(ctx.RuntimeDir, "backend", "storage"),
-		filepath.Join(ctx.RuntimeDir, "backend", "bootstrap", "cache"),
-		filepath.Join(ctx.RuntimeDir, "backend", "storage", "app", "public"),
-	}
-	for _, dir := range storageDirs {
+	for _, dir := range writableDirs(ctx) {
 		if dirExists(dir) {
-			cmd := fmt.Sprintf(`icacls "%s" /grant "IIS_IUSRS:(OI)(CI)F" /T`, dir)
-			result := powershell.Run(cmd)
-			if result.Err != nil {
-				ctx.Logf("Warning: failed to set IIS permissions on %s: %v", dir, result.Err)
+			if result := grantIISAccess(dir); result.Err != nil {
+				ctx.Warnf("failed to set IIS permissions on %s: %v", dir, result.Err)
 			}
 		}
 	}
 
-	ctx.Logf("IIS configured for YachtCRM-DMS")
+	port := ctx.HTTPPort
+	if port == 0 {
+		port = 80
+	}
+	ctx.Logf("IIS site %s configured on port %d", iisSiteName(ctx), port)
 	return nil
 }
 
@@ -662,50 +672,56 @@ func (s ConfigureEnv) Run(ctx *installer.Context) error {
 		}
 	}
 
-	appURL, err := prompts.AskStringDefault("Application URL", "http://localhost", true)
-	if err != nil {
-		return err
-	}
-	frontendURL, err := prompts.AskStringDefault("Frontend URL", "http://localhost/frontend", true)
+	site := siteInputs()
+	appURL, err := askInput(ctx, site[0])
 	if err != nil {
 		return err
 	}
-	dbHost, err := prompts.AskStringDefault("Database host", "127.0.0.1", true)
+	derived, err := hostEnvValues(appURL)
 	if err != nil {
 		return err
 	}
-	dbPort, err := prompts.AskStringDefault("Database port", "3306", true)
-	if err != nil {
-		return err
-	}
-	sanctum, err := prompts.AskStringDefault("SANCTUM_STATEFUL_DOMAINS", "localhost,127.0.0.1", true)
-	if err != nil {
-		return err
-	}
-	sessionDomain, err := prompts.AskStringDefault("SESSION_DOMAIN", "localhost", true)
-	if err != nil {
-		return err
+	appURL = derived["APP_URL"]
+	values := map[string]string{}
+	for _, in := range site[1:] {
+		in.Default = derived[strings.ToUpper(in.Key)]
+		in.Required = true
+		if values[in.Key], err = askInput(ctx, in); err != nil {
+			return err
+		}
 	}
+	frontendURL := values["frontend_url"]
+	sanctum := values["sanctum_stateful_domains"]
+	sessionDomain := values["session_domain"]
 
+	ctx.AppURL = appURL
+	ctx.FrontendURL = frontendURL
+	if ctx.Branding.CRMName != "" {
+		envMap["APP_NAME"] = ctx.Branding.CRMName
+	}
 	envMap["APP_URL"] = appURL
 	envMap["FRONTEND_URL"] = frontendURL
-	envMap["DB_HOST"] = dbHost
-	envMap["DB_PORT"] = dbPort
+	envMap["DB_HOST"] = ctx.DatabaseHost
+	envMap["DB_PORT"] = strconv.Itoa(ctx.DatabasePort)
 	envMap["DB_DATABASE"] = ctx.DatabaseName
 	envMap["DB_USERNAME"] = ctx.DatabaseUser
 	envMap["DB_PASSWORD"] = ctx.DatabaseUserPassword
 	envMap["SANCTUM_STATEFUL_DOMAINS"] = sanctum
 	envMap["SESSION_DOMAIN"] = sessionDomain
 
+	if err := collectEnvSections(ctx, envMap); err != nil {
+		return err
+	}
+
 	builder := &strings.Builder{}
 	for _, key := range order {
 		if val, ok := envMap[key]; ok {
-			builder.WriteString(fmt.Sprintf("%s=%s\n", key, val))
+			builder.WriteString(fmt.Sprintf("%s=%s\n", key, formatEnvValue(val)))
 			delete(envMap, key)
 		}
 	}
 	for key, val := range envMap {
-		builder.WriteString(fmt.Sprintf("%s=%s\n", key, val))
+		builder.WriteString(fmt.Sprintf("%s=%s\n", key, formatEnvValue(val)))
 	}
 
 	if err := os.WriteFile(envPath, []byte(builder.String()), 0o644); err != nil {
@@ -715,7 +731,7 @@ func (s ConfigureEnv) Run(ctx *installer.Context) error {
 	artisanCmd := fmt.Sprintf(`Set-Location '%s'; & '%s' artisan key:generate --force`, filepath.Join(ctx.RuntimeDir, "backend"), ctx.PhpExePath)
 	result := powershell.Run(artisanCmd)
 	if result.Err != nil {
-		ctx.Logf("Warning: artisan key:generate failed: %v", result.Err)
+		ctx.Warnf("artisan key:generate failed: %v", result.Err)
 	} else {
 		ctx.Logf("Application key generated")
 	}
@@ -723,77 +739,18 @@ func (s ConfigureEnv) Run(ctx *installer.Context) error {
 	return nil
 }
 
-type SeedDatabase struct{}
-
-func (SeedDatabase) Name() string { return "Seed Database" }
-
-func (s SeedDatabase) Run(ctx *installer.Context) error {
-	if ctx.MariaDBBinDir == "" {
-		return fmt.Errorf("MariaDB bin directory not known")
-	}
-	if ctx.SqlDumpPath == "" || !fileExists(ctx.SqlDumpPath) {
-		return fmt.Errorf("SQL dump not found at %s", ctx.SqlDumpPath)
-	}
-
-	mysqlExe := filepath.Join(ctx.MariaDBBinDir, "mysql.exe")
-	if !fileExists(mysqlExe) {
-		return fmt.Errorf("mysql.exe not found at %s", mysqlExe)
-	}
-
-	ctx.Logf("Importing SQL dump %s", ctx.SqlDumpPath)
-	dump, err := os.Open(ctx.SqlDumpPath)
-	if err != nil {
-		return fmt.Errorf("open SQL dump: %w", err)
-	}
-	defer dump.Close()
-
-	cmd := exec.Command(mysqlExe, "-u", "root", fmt.Sprintf("--password=%s", ctx.RootMariaDBPassword), ctx.DatabaseName)
-	cmd.Stdin = dump
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("mysql import failed: %w (stderr: %s)", err, stderr.String())
-	}
-
-	ctx.Logf("Database seeded successfully")
-	return nil
-}
-
-type CreateAdminUser struct{}
-
-func (CreateAdminUser) Name() string { return "Create Admin User" }
-
-func (s CreateAdminUser) Run(ctx *installer.Context) error {
-	if ctx.MariaDBBinDir == "" {
-		return fmt.Errorf("MariaDB bin directory not known")
-	}
-	mysqlExe := filepath.Join(ctx.MariaDBBinDir, "mysql.exe")
-	if !fileExists(mysqlExe) {
-		return fmt.Errorf("mysql.exe not found at %s", mysqlExe)
-	}
-
-	hash, err := bcrypt.GenerateFromPassword([]byte(ctx.AdminPassword), bcrypt.DefaultCost)
+// Skip reads the site URLs from the existing backend/.env.
+func (ConfigureEnv) Skip(ctx *installer.Context) error {
+	envPath := filepath.Join(ctx.RuntimeDir, "backend", ".env")
+	env, err := readEnvFile(envPath)
 	if err != nil {
-		return fmt.Errorf("hash admin password: %w", err)
+		return fmt.Errorf("read %s: %w; run the env step", envPath, err)
 	}
-
-	name := escapeSQLString(ctx.AdminName)
-	email := escapeSQLString(ctx.AdminEmail)
-	password := escapeSQLString(string(hash))
-
-	sql := fmt.Sprintf("INSERT INTO users (name,email,password,email_verified_at,remember_token,created_at,updated_at) VALUES ('%s','%s','%s',NOW(),NULL,NOW(),NOW()) ON DUPLICATE KEY UPDATE name=VALUES(name), password=VALUES(password), updated_at=NOW();", name, email, password)
-
-	cmd := exec.Command(mysqlExe, "-u", "root", fmt.Sprintf("--password=%s", ctx.RootMariaDBPassword), ctx.DatabaseName, "-e", sql)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("insert admin user failed: %w (stderr: %s)", err, stderr.String())
+	if env["APP_URL"] == "" {
+		return fmt.Errorf("APP_URL is not set in %s; run the env step", envPath)
 	}
-
-	ctx.Logf("Admin user %s created/updated", ctx.AdminEmail)
+	ctx.AppURL, ctx.FrontendURL = env["APP_URL"], env["FRONTEND_URL"]
+	ctx.Logf("Using APP_URL %s from %s", ctx.AppURL, envPath)
 	return nil
 }
 
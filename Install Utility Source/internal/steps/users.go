@@ -0,0 +1,113 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"yachtcrm-installer/internal/accounts"
+	"yachtcrm-installer/internal/installer"
+)
+
+// loginCheckScript boots the deployed Laravel application and validates the
+// credentials against the web guard, the same check the login endpoint
+// makes. The credentials come from the environment so they never appear on
+// a command line.
+const loginCheckScript = `require 'vendor/autoload.php';
+$app = require 'bootstrap/app.php';
+$app->make(Illuminate\Contracts\Console\Kernel::class)->bootstrap();
+$ok = Illuminate\Support\Facades\Auth::guard('web')->validate([
+    'email' => getenv('YCRM_LOGIN_EMAIL'),
+    'password' => getenv('YCRM_LOGIN_PASSWORD'),
+]);
+fwrite(STDOUT, $ok ? "login ok\n" : "login rejected\n");
+exit($ok ? 0 : 1);`
+
+type CreateAdminUser struct{}
+
+func (CreateAdminUser) Name() string { return "Create Admin User" }
+
+func (s CreateAdminUser) Run(ctx *installer.Context) error {
+	return createUser(ctx, ctx.AdminName, ctx.AdminEmail, ctx.AdminPassword, accounts.RoleAdmin)
+}
+
+// CreateUser creates or updates a single CRM account. It backs the
+// create-user command.
+type CreateUser struct {
+	FullName string
+	Email    string
+	Password string
+	Role     string
+}
+
+func (CreateUser) Name() string { return "Create User" }
+
+func (s CreateUser) Run(ctx *installer.Context) error {
+	return createUser(ctx, s.FullName, s.Email, s.Password, s.Role)
+}
+
+// createUser writes the account, reads it back to confirm the role, status
+// and password hash, and then checks that Laravel itself accepts the login.
+func createUser(ctx *installer.Context, name, email, password, role string) error {
+	if err := accounts.ValidateRole(role); err != nil {
+		return err
+	}
+	hash, err := accounts.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
+	db, err := openDatabase(ctx, ctx.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := accounts.Upsert(db, name, email, hash, role); err != nil {
+		return fmt.Errorf("save user %s: %w", email, err)
+	}
+
+	user, err := accounts.Find(db, email)
+	if err != nil {
+		return fmt.Errorf("read back user %s: %w", email, err)
+	}
+	switch {
+	case user.Role != role:
+		return fmt.Errorf("user %s has role %q after update, expected %q", email, user.Role, role)
+	case user.Status != "active":
+		return fmt.Errorf("user %s has status %q after update, expected active", email, user.Status)
+	case user.EmailVerifiedAt == nil:
+		return fmt.Errorf("user %s has no email_verified_at after update", email)
+	case !accounts.CheckPassword(user.PasswordHash, password):
+		return fmt.Errorf("stored password hash for %s does not match", email)
+	}
+	ctx.Logf("User %s (id %d) saved with role %s", email, user.ID, user.Role)
+
+	if err := checkLogin(ctx, email, password); err != nil {
+		return err
+	}
+
+	if user.MFAEnabled {
+		method := user.MFAMethod
+		if method == "" {
+			method = "totp"
+		}
+		ctx.Logf("Existing MFA settings for %s were kept (method %s); the code will be required at sign-in", email, method)
+	}
+	return nil
+}
+
+// checkLogin runs loginCheckScript with the deployed backend. It is skipped
+// when the backend or php.exe is not in place yet.
+func checkLogin(ctx *installer.Context, email, password string) error {
+	backendDir := filepath.Join(ctx.RuntimeDir, "backend")
+	if !fileExists(filepath.Join(backendDir, "vendor", "autoload.php")) || !fileExists(ctx.PhpExePath) {
+		ctx.Warnf("backend not deployed, skipping Laravel login check")
+		return nil
+	}
+	env := append(os.Environ(), "YCRM_LOGIN_EMAIL="+email, "YCRM_LOGIN_PASSWORD="+password)
+	if err := runLogged(ctx, backendDir, env, ctx.PhpExePath, "-r", loginCheckScript); err != nil {
+		return fmt.Errorf("Laravel rejected the login for %s: %w", email, err)
+	}
+	return nil
+}
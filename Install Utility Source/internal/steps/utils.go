@@ -2,17 +2,47 @@ package steps
 
 import (
 	"archive/zip"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"math/rand"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/powershell"
+)
+
+// Steps that run in parallel share machine-wide state that only tolerates
+// one writer at a time.
+var (
+	// Windows Installer and Windows feature servicing each refuse to start
+	// while another installation is in progress.
+	systemInstallMu sync.Mutex
+	// The machine PATH is updated by reading it and writing it back.
+	machinePathMu sync.Mutex
 )
 
+func runSystemInstall(script string) powershell.Result {
+	systemInstallMu.Lock()
+	defer systemInstallMu.Unlock()
+	return powershell.Run(script)
+}
+
+func updateMachinePath(script string) powershell.Result {
+	machinePathMu.Lock()
+	defer machinePathMu.Unlock()
+	return powershell.Run(script)
+}
+
 func dirExists(path string) bool {
 	info, err := os.Stat(path)
 	return err == nil && info.IsDir()
@@ -161,31 +191,6 @@ func copyFile(src, dst string) error {
 	return os.WriteFile(dst, data, info.Mode())
 }
 
-func replaceFirst(s, old, new string) (string, bool) {
-	idx := strings.Index(s, old)
-	if idx == -1 {
-		return s, false
-	}
-	return s[:idx] + new + s[idx+len(old):], true
-}
-
-func setIniValue(contents, key, value string) string {
-	lines := strings.Split(contents, "\n")
-	keyPrefix := key + " ="
-	replaced := false
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, keyPrefix) || strings.HasPrefix(trimmed, key+"=") {
-			lines[i] = key + " = " + value
-			replaced = true
-		}
-	}
-	if !replaced {
-		lines = append(lines, key+" = "+value)
-	}
-	return strings.Join(lines, "\n")
-}
-
 func randomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	rand.Seed(time.Now().UnixNano())
@@ -252,3 +257,111 @@ func escapeSQLString(val string) string {
 	val = strings.ReplaceAll(val, "'", "\\'")
 	return val
 }
+
+// updateEnvFile sets keys in an existing .env file in place, keeping the
+// order, comments and unrelated lines. Keys that are not present yet are
+// appended in sorted order, so repeated runs write the same file.
+func updateEnvFile(path string, values map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	seen := make(map[string]bool)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, _, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if val, found := values[key]; found {
+			lines[i] = key + "=" + formatEnvValue(val)
+			seen[key] = true
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if !seen[key] {
+			lines = append(lines, key+"="+formatEnvValue(values[key]))
+		}
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+}
+
+// readEnvFile parses a .env file into a map, removing surrounding quotes.
+func readEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		switch {
+		case len(val) > 1 && val[0] == '"' && val[len(val)-1] == '"':
+			val = strings.ReplaceAll(val[1:len(val)-1], `\"`, `"`)
+		case len(val) > 1 && val[0] == '\'' && val[len(val)-1] == '\'':
+			val = val[1 : len(val)-1]
+		}
+		values[strings.TrimSpace(key)] = val
+	}
+	return values, nil
+}
+
+// logWriter forwards complete output lines to the installer log.
+type logWriter struct {
+	ctx    *installer.Context
+	prefix string
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		if line := strings.TrimRight(string(w.buf[:idx]), "\r"); strings.TrimSpace(line) != "" {
+			w.ctx.Logf("%s%s", w.prefix, line)
+		}
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (w *logWriter) Flush() {
+	if strings.TrimSpace(string(w.buf)) != "" {
+		w.ctx.Logf("%s%s", w.prefix, strings.TrimRight(string(w.buf), "\r"))
+	}
+	w.buf = nil
+}
+
+// runLogged runs a command in dir and streams its output into the installer
+// log as it is produced. A nil env inherits the installer's environment.
+func runLogged(ctx *installer.Context, dir string, env []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	out := &logWriter{ctx: ctx, prefix: "  "}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err := cmd.Run()
+	out.Flush()
+	return err
+}
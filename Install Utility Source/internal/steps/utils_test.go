@@ -0,0 +1,29 @@
+package steps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	values := map[string]string{"QUEUE_CONNECTION": "database", "MAIL_HOST": "smtp.example.com", "CACHE_DRIVER": "file", "LOG_LEVEL": "error"}
+	want := "# CRM settings\nAPP_NAME=CRM\nQUEUE_CONNECTION=database\nCACHE_DRIVER=file\nLOG_LEVEL=error\nMAIL_HOST=smtp.example.com\n"
+	// Map order varies between runs; the appended keys must not.
+	for range 10 {
+		if err := os.WriteFile(path, []byte("# CRM settings\r\nAPP_NAME=CRM\r\nQUEUE_CONNECTION=sync\r\n\r\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := updateEnvFile(path, values); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); got != want {
+			t.Fatalf(".env is\n%q\nwant\n%q", got, want)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package steps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"yachtcrm-installer/internal/ini"
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/powershell"
+	"yachtcrm-installer/internal/templates"
+)
+
+// VerifyStatus is the outcome of checking one item.
+type VerifyStatus string
+
+const (
+	VerifyOK    VerifyStatus = "OK"
+	VerifyDrift VerifyStatus = "Drift"
+	VerifyError VerifyStatus = "Error"
+	VerifyFixed VerifyStatus = "Fixed"
+)
+
+// VerifyItem is one thing the installer set up, compared with the machine.
+type VerifyItem struct {
+	Area     string
+	Item     string
+	Expected string
+	Actual   string
+	Status   VerifyStatus
+
+	// fix repairs the item; nil when it has to be repaired by hand. Items
+	// repaired together, such as the keys of one file, share a fix.
+	fix *verifyFix
+}
+
+// Fixable reports whether FixDrift can repair the item.
+func (i VerifyItem) Fixable() bool {
+	return i.Status == VerifyDrift && i.fix != nil
+}
+
+type verifyFix struct {
+	name string
+	run  func() error
+}
+
+type verifier struct {
+	ctx   *installer.Context
+	items []VerifyItem
+}
+
+func (v *verifier) check(area, item, expected, actual string, ok bool, fix *verifyFix) {
+	status := VerifyDrift
+	if ok {
+		status = VerifyOK
+	}
+	v.items = append(v.items, VerifyItem{Area: area, Item: item, Expected: expected, Actual: actual, Status: status, fix: fix})
+}
+
+func (v *verifier) failed(area, item string, err error) {
+	v.items = append(v.items, VerifyItem{Area: area, Item: item, Actual: err.Error(), Status: VerifyError})
+}
+
+// Verify compares what the installer sets up with the machine: the php.ini
+// extensions and values, the local MariaDB server's my.ini, the IIS site,
+// application pool and PHP handler, the web.config files, the .env keys
+// and the storage directory permissions.
+func Verify(ctx *installer.Context) []VerifyItem {
+	v := &verifier{ctx: ctx}
+	v.phpIni()
+	v.mariaDB()
+	v.iis()
+	v.webConfigs()
+	v.env()
+	v.acls()
+	return v.items
+}
+
+// FixDrift repairs the drifted items that can be repaired, running each fix
+// once, and verifies again. Items that were drifted and now match are
+// reported as Fixed.
+func FixDrift(ctx *installer.Context, items []VerifyItem) ([]VerifyItem, error) {
+	done := map[*verifyFix]bool{}
+	drifted := map[string]bool{}
+	var errs []error
+	for _, item := range items {
+		if item.Status == VerifyDrift {
+			drifted[item.Area+"\x00"+item.Item] = true
+		}
+		if !item.Fixable() || done[item.fix] {
+			continue
+		}
+		done[item.fix] = true
+		ctx.Logf("Fixing %s", item.fix.name)
+		if err := item.fix.run(); err != nil {
+			errs = append(errs, fmt.Errorf("fix %s: %w", item.fix.name, err))
+		}
+	}
+
+	after := Verify(ctx)
+	for i, item := range after {
+		if item.Status == VerifyOK && drifted[item.Area+"\x00"+item.Item] {
+			after[i].Status = VerifyFixed
+		}
+	}
+	return after, errors.Join(errs...)
+}
+
+// WithoutFix leaves the items of area to be repaired by hand, for when the
+// settings they were compared with are only assumed.
+func WithoutFix(items []VerifyItem, area string) []VerifyItem {
+	for i := range items {
+		if items[i].Area == area {
+			items[i].fix = nil
+		}
+	}
+	return items
+}
+
+func (v *verifier) phpIni() {
+	path := phpIniPath(v.ctx)
+	cfg, err := v.ctx.PHP.Resolve()
+	if err != nil {
+		v.failed("php.ini", path, err)
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		v.failed("php.ini", path, err)
+		return
+	}
+	f := ini.Parse(data)
+	fix := &verifyFix{name: path, run: func() error {
+		err := rewriteFile(path, func(contents string) (string, error) { return applyPhpIni(v.ctx, contents, cfg) })
+		if err != nil {
+			return err
+		}
+		// php-cgi reads php.ini when it starts.
+		pool := appPoolName(v.ctx)
+		if result := powershell.Run("Import-Module WebAdministration; Restart-WebAppPool -Name " + powershell.Quote(pool)); result.Err != nil {
+			v.ctx.Warnf("could not recycle application pool %s: %v", pool, result.Err)
+		}
+		return nil
+	}}
+	checkExtensions := func(directive string, exts []string) {
+		for _, ext := range exts {
+			enabled := phpExtensionEnabled(f, directive, ext)
+			actual := "disabled"
+			if enabled {
+				actual = "enabled"
+			}
+			v.check("php.ini", directive+" "+ext, "enabled", actual, enabled, fix)
+		}
+	}
+	checkExtensions("extension", cfg.Extensions)
+	checkExtensions("zend_extension", cfg.ZendExtensions)
+	for _, kv := range cfg.Values {
+		actual, _ := phpIniValue(f, kv[0])
+		v.check("php.ini", kv[0], kv[1], actual, strings.EqualFold(actual, kv[1]), fix)
+	}
+}
+
+func (v *verifier) mariaDB() {
+	if v.ctx.ExternalDatabase {
+		return
+	}
+	binDir := v.ctx.MariaDBBinDir
+	if binDir == "" {
+		var err error
+		if binDir, err = findMariaDBBinDir(); err != nil {
+			v.failed("my.ini", "MariaDB", err)
+			return
+		}
+	}
+	path, err := findMariaDBConfig(binDir)
+	if err != nil {
+		v.failed("my.ini", binDir, err)
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		v.failed("my.ini", path, err)
+		return
+	}
+	fix := &verifyFix{name: path, run: func() error {
+		if err := rewriteFile(path, applyMariaDBIni); err != nil {
+			return err
+		}
+		if result := restartMariaDB(); result.Err != nil {
+			return fmt.Errorf("restart MariaDB: %w (stderr: %s)", result.Err, result.Stderr)
+		}
+		return nil
+	}}
+	myIni := parseMyIni(string(data))
+	for _, kv := range mariaDBSettings {
+		actual, _ := myIni.Get("mysqld", kv[0])
+		v.check("my.ini", kv[0], kv[1], actual, strings.EqualFold(actual, kv[1]), fix)
+	}
+}
+
+func (v *verifier) iis() {
+	backendPath, frontendPath := sitePaths(v.ctx)
+	site, pool, phpCgi := iisSiteName(v.ctx), appPoolName(v.ctx), phpCgiPath(v.ctx)
+	result := powershell.Run(fmt.Sprintf(`Import-Module WebAdministration
+$site = Get-Website -Name '%s'
+if ($site) {
+    'site=exists'
+    'physicalPath=' + $site.physicalPath
+    'applicationPool=' + $site.applicationPool
+    $vdir = Get-WebVirtualDirectory -Site $site.Name -Name 'frontend' -ErrorAction SilentlyContinue
+    if ($vdir) { 'frontend=' + $vdir.physicalPath }
+}
+$pool = Get-Item 'IIS:\AppPools\%s' -ErrorAction SilentlyContinue
+if ($pool) {
+    'pool=exists'
+    'managedRuntimeVersion=' + $pool.managedRuntimeVersion
+    'managedPipelineMode=' + $pool.managedPipelineMode
+    'enable32BitAppOnWin64=' + $pool.enable32BitAppOnWin64
+}
+$handler = Get-WebConfiguration -PSPath 'MACHINE/WEBROOT/APPHOST' -Filter "system.webServer/handlers/add[@name='PHP_via_FastCGI']"
+if ($handler) { 'handler=' + $handler.scriptProcessor }
+$fastCgi = Get-WebConfiguration -PSPath 'MACHINE/WEBROOT/APPHOST' -Filter "system.webServer/fastCgi/application[@fullPath='%s']"
+if ($fastCgi) { 'fastCgi=registered' }`, escapeSingleQuotes(site), escapeSingleQuotes(pool), phpCgi))
+	if result.Err != nil {
+		v.failed("IIS", site, fmt.Errorf("%w (stderr: %s)", result.Err, result.Stderr))
+		return
+	}
+	live := map[string]string{}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			live[key] = value
+		}
+	}
+
+	fix := &verifyFix{name: "IIS site " + site, run: func() error {
+		if result := powershell.Run(iisScript(v.ctx)); result.Err != nil {
+			return fmt.Errorf("%w (stderr: %s)", result.Err, result.Stderr)
+		}
+		return nil
+	}}
+	same := func(key, want string) bool { return strings.EqualFold(live[key], want) }
+	v.check("IIS", "site "+site, "exists", orMissing(live["site"]), live["site"] != "", fix)
+	if live["site"] != "" {
+		v.check("IIS", "site physical path", backendPath, live["physicalPath"], same("physicalPath", backendPath), fix)
+		v.check("IIS", "site application pool", pool, live["applicationPool"], same("applicationPool", pool), fix)
+		v.check("IIS", "frontend virtual directory", frontendPath, orMissing(live["frontend"]), same("frontend", frontendPath), fix)
+	}
+	v.check("IIS", "application pool "+pool, "exists", orMissing(live["pool"]), live["pool"] != "", fix)
+	if live["pool"] != "" {
+		v.check("IIS", "pool .NET CLR version", "No Managed Code", orValue(live["managedRuntimeVersion"], "No Managed Code"), live["managedRuntimeVersion"] == "", fix)
+		v.check("IIS", "pool pipeline mode", "Integrated", live["managedPipelineMode"], same("managedPipelineMode", "Integrated"), fix)
+		v.check("IIS", "pool 32-bit applications", "False", live["enable32BitAppOnWin64"], same("enable32BitAppOnWin64", "False"), fix)
+	}
+	v.check("IIS", "PHP_via_FastCGI handler", phpCgi, orMissing(live["handler"]), same("handler", phpCgi), fix)
+	v.check("IIS", "FastCGI application "+phpCgi, "registered", orMissing(live["fastCgi"]), live["fastCgi"] != "", fix)
+}
+
+func (v *verifier) webConfigs() {
+	backendPath, frontendPath := sitePaths(v.ctx)
+	for _, wc := range []struct{ dir, template string }{
+		{backendPath, templates.BackendWebConfig},
+		{frontendPath, templates.FrontendWebConfig},
+	} {
+		path := filepath.Join(wc.dir, "web.config")
+		data, err := os.ReadFile(path)
+		actual := "matches"
+		switch {
+		case os.IsNotExist(err):
+			actual = "missing"
+		case err != nil:
+			v.failed("web.config", path, err)
+			continue
+		case !bytes.Equal(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte(wc.template)):
+			actual = "changed"
+		}
+		fix := &verifyFix{name: path, run: func() error {
+			return os.WriteFile(path, []byte(wc.template), 0o644)
+		}}
+		v.check("web.config", path, "installer template", actual, actual == "matches", fix)
+	}
+}
+
+func (v *verifier) env() {
+	path := filepath.Join(v.ctx.RuntimeDir, "backend", ".env")
+	env, err := readEnvFile(path)
+	if err != nil {
+		v.failed(".env", path, err)
+		return
+	}
+
+	// The registered instance says what the keys should be; without one
+	// they only have to be set.
+	want := map[string]string{}
+	if v.ctx.AppURL != "" {
+		want["APP_URL"] = v.ctx.AppURL
+	}
+	if v.ctx.DatabaseName != "" {
+		want["DB_HOST"] = v.ctx.DatabaseHost
+		want["DB_PORT"] = strconv.Itoa(v.ctx.DatabasePort)
+		want["DB_DATABASE"] = v.ctx.DatabaseName
+		want["DB_USERNAME"] = v.ctx.DatabaseUser
+	}
+	fix := &verifyFix{name: path, run: func() error {
+		if err := updateEnvFile(path, want); err != nil {
+			return err
+		}
+		return RefreshLaravelCache{}.Run(v.ctx)
+	}}
+	for _, key := range []string{"APP_KEY", "APP_URL", "DB_HOST", "DB_PORT", "DB_DATABASE", "DB_USERNAME", "DB_PASSWORD"} {
+		if expected, ok := want[key]; ok {
+			v.check(".env", key, expected, env[key], env[key] == expected, fix)
+			continue
+		}
+		// Values that are not compared may be secrets; only say whether
+		// they are set.
+		actual := "not set"
+		if env[key] != "" {
+			actual = "set"
+		}
+		v.check(".env", key, "set", actual, env[key] != "", nil)
+	}
+}
+
+func (v *verifier) acls() {
+	for _, dir := range writableDirs(v.ctx) {
+		if !dirExists(dir) {
+			continue
+		}
+		result := powershell.Run(fmt.Sprintf(`$full = [System.Security.AccessControl.FileSystemRights]::FullControl
+$inherit = [System.Security.AccessControl.InheritanceFlags]'ContainerInherit, ObjectInherit'
+[bool]((Get-Acl -LiteralPath '%s').Access | Where-Object { $_.IdentityReference -like '*\IIS_IUSRS' -and $_.AccessControlType -eq 'Allow' -and ($_.FileSystemRights -band $full) -eq $full -and ($_.InheritanceFlags -band $inherit) -eq $inherit })`, escapeSingleQuotes(dir)))
+		if result.Err != nil {
+			v.failed("ACL", dir, fmt.Errorf("%w (stderr: %s)", result.Err, result.Stderr))
+			continue
+		}
+		granted := strings.EqualFold(result.Stdout, "True")
+		actual := "not granted"
+		if granted {
+			actual = "granted"
+		}
+		fix := &verifyFix{name: "permissions on " + dir, run: func() error {
+			if result := grantIISAccess(dir); result.Err != nil {
+				return fmt.Errorf("%w (stderr: %s)", result.Err, result.Stderr)
+			}
+			return nil
+		}}
+		v.check("ACL", dir, "IIS_IUSRS full control", actual, granted, fix)
+	}
+}
+
+// rewriteFile applies edit to the contents of path.
+func rewriteFile(path string, edit func(string) (string, error)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	contents, err := edit(string(data))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(contents), 0o644)
+}
+
+func orMissing(value string) string {
+	return orValue(value, "missing")
+}
+
+func orValue(value, blank string) string {
+	if value == "" {
+		return blank
+	}
+	return value
+}
@@ -3,6 +3,7 @@ package tasks
 import (
 	"strings"
 	"sync"
+	"time"
 )
 
 type StepStatus string
@@ -12,12 +13,21 @@ const (
 	StepStatusRunning   StepStatus = "Running"
 	StepStatusCompleted StepStatus = "Completed"
 	StepStatusFailed    StepStatus = "Failed"
+	StepStatusSkipped   StepStatus = "Skipped"
 )
 
+// Started reports whether a step with this status has run or is running,
+// so that it has an elapsed time.
+func (s StepStatus) Started() bool {
+	return s == StepStatusRunning || s == StepStatusCompleted || s == StepStatusFailed
+}
+
 type State struct {
 	mu          sync.RWMutex
 	stepStatus  map[string]StepStatus
 	stepLogs    map[string][]string
+	started     map[string]time.Time
+	finished    map[string]time.Time
 	SharedStore map[string]any
 }
 
@@ -30,6 +40,8 @@ func NewState(stepIDs []string) *State {
 	return &State{
 		stepStatus:  status,
 		stepLogs:    make(map[string][]string),
+		started:     make(map[string]time.Time),
+		finished:    make(map[string]time.Time),
 		SharedStore: make(map[string]any),
 	}
 }
@@ -44,6 +56,28 @@ func (s *State) SetStatus(stepID string, status StepStatus) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.stepStatus[stepID] = status
+	switch status {
+	case StepStatusRunning:
+		s.started[stepID] = time.Now()
+		delete(s.finished, stepID)
+	case StepStatusCompleted, StepStatusFailed:
+		s.finished[stepID] = time.Now()
+	}
+}
+
+// Elapsed reports how long a step has been running, or how long it took
+// once it has finished. Steps that never started report zero.
+func (s *State) Elapsed(stepID string) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	start, ok := s.started[stepID]
+	if !ok {
+		return 0
+	}
+	if end, ok := s.finished[stepID]; ok {
+		return end.Sub(start)
+	}
+	return time.Since(start)
 }
 
 func (s *State) AppendLog(stepID, line string) {
@@ -57,3 +91,10 @@ func (s *State) Logs(stepID string) string {
 	defer s.mu.RUnlock()
 	return strings.Join(s.stepLogs[stepID], "\n")
 }
+
+// LogLines returns a copy of the log lines recorded for a step.
+func (s *State) LogLines(stepID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.stepLogs[stepID]...)
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package tui
+
+// enableVirtualTerminal is a no-op outside Windows; terminals there handle
+// escape sequences already.
+func enableVirtualTerminal() (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// Named keys. Printable input is reported as the character itself.
+const (
+	keyUp        = "<up>"
+	keyDown      = "<down>"
+	keyPgUp      = "<pgup>"
+	keyPgDn      = "<pgdn>"
+	keyHome      = "<home>"
+	keyEnd       = "<end>"
+	keyEnter     = "<enter>"
+	keyBackspace = "<backspace>"
+	keyCtrlC     = "<ctrl-c>"
+	keyCtrlU     = "<ctrl-u>"
+	keyEsc       = "<esc>"
+)
+
+var sequences = map[string]string{
+	"[A": keyUp, "OA": keyUp,
+	"[B": keyDown, "OB": keyDown,
+	"[5~": keyPgUp, "[6~": keyPgDn,
+	"[H": keyHome, "OH": keyHome, "[1~": keyHome,
+	"[F": keyEnd, "OF": keyEnd, "[4~": keyEnd,
+}
+
+// readKeys forwards raw stdin reads until stdin fails.
+func readKeys(r io.Reader, keys chan<- []byte) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			keys <- append([]byte(nil), buf[:n]...)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseKeys splits one read from a raw-mode terminal into keys. Escape
+// sequences that are not in sequences are dropped.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			n, key := parseEscape(b)
+			if key != "" {
+				keys = append(keys, key)
+			}
+			b = b[n:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, keyEnter)
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, keyBackspace)
+		case c == 0x03:
+			keys = append(keys, keyCtrlC)
+		case c == 0x15:
+			keys = append(keys, keyCtrlU)
+		case c < ' ':
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, string(r))
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// parseEscape reads a CSI or SS3 sequence starting at b[0] and returns its
+// length and key.
+func parseEscape(b []byte) (int, string) {
+	if len(b) < 2 || (b[1] != '[' && b[1] != 'O') {
+		return 1, keyEsc
+	}
+	end := 2
+	for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+		end++
+	}
+	if end == len(b) {
+		return len(b), ""
+	}
+	end++
+	return end, sequences[string(b[1:end])]
+}
@@ -0,0 +1,282 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"yachtcrm-installer/internal/tasks"
+)
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l\x1b[2J"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+
+	reset   = "\x1b[0m"
+	bold    = "\x1b[1m"
+	reverse = "\x1b[7m"
+	red     = "\x1b[31m"
+	green   = "\x1b[32m"
+	yellow  = "\x1b[33m"
+	dim     = "\x1b[2m"
+	fgReset = "\x1b[39m"
+)
+
+var spinner = []string{"|", "/", "-", "\\"}
+
+// escapes matches colour and cursor sequences that tools such as composer
+// and npm write into their output.
+var escapes = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+func (u *ui) size() (int, int) {
+	w, h, err := term.GetSize(u.outFd)
+	if err != nil || w < 40 || h < 10 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// logHeight is the number of log lines the right pane shows.
+func (u *ui) logHeight() int {
+	_, h := u.size()
+	return h - 4
+}
+
+func (u *ui) draw(w io.Writer) {
+	width, height := u.size()
+	bodyHeight := height - 3
+
+	if u.follow {
+		for i, name := range u.names {
+			if u.state.Status(name) == tasks.StepStatusRunning {
+				u.selected = i
+			}
+		}
+	}
+
+	leftWidth := 20
+	for _, name := range u.names {
+		leftWidth = max(leftWidth, len(name)+13)
+	}
+	leftWidth = min(leftWidth, width*2/5)
+	rightWidth := width - leftWidth - 3
+
+	left := u.stepList(leftWidth, bodyHeight)
+	var right []string
+	if u.finished && u.showSummary {
+		right = u.summary(rightWidth, bodyHeight)
+	} else {
+		right = u.logPane(rightWidth, bodyHeight)
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	b.WriteString(reverse + fit(u.title(width), width) + reset + "\x1b[K\r\n")
+	for i := 0; i < bodyHeight; i++ {
+		b.WriteString(left[i] + dim + " | " + reset + right[i] + reset + "\x1b[K\r\n")
+	}
+	if u.notice != "" {
+		b.WriteString(red + fit(u.notice, width) + reset)
+	}
+	b.WriteString("\x1b[K\r\n")
+
+	if u.prompt != nil {
+		answer := string(u.input)
+		if u.prompt.secret {
+			answer = strings.Repeat("*", len(u.input))
+		}
+		line := []rune(u.prompt.text + answer)
+		if len(line) > width-1 {
+			line = line[len(line)-(width-1):]
+		}
+		b.WriteString(bold + string(line) + reset + "\x1b[K")
+		fmt.Fprintf(&b, "\x1b[%d;%dH\x1b[?25h", height, len(line)+1)
+	} else {
+		help := "Up/Down select step  PgUp/PgDn scroll  f follow running step  Ctrl+C abort"
+		if u.finished {
+			help = "Up/Down view step logs  PgUp/PgDn scroll  s summary  q quit"
+		}
+		b.WriteString(dim + fit(help, width-1) + reset + "\x1b[K\x1b[?25l")
+	}
+	io.WriteString(w, b.String())
+}
+
+func (u *ui) title(width int) string {
+	done := 0
+	for _, name := range u.names {
+		if status := u.state.Status(name); status == tasks.StepStatusCompleted || status == tasks.StepStatusSkipped {
+			done++
+		}
+	}
+	elapsed := time.Since(u.started)
+	if u.finished {
+		elapsed = u.elapsed
+	}
+	status := fmt.Sprintf("%d/%d steps  Elapsed %s ", done, len(u.names), formatElapsed(elapsed))
+	name := " YachtCRM-DMS Installer"
+	gap := max(width-len(name)-len(status), 1)
+	return name + strings.Repeat(" ", gap) + status
+}
+
+func (u *ui) stepList(width, height int) []string {
+	top := 0
+	if u.selected >= height {
+		top = u.selected - height + 1
+	}
+	nameWidth := width - 13
+
+	rows := make([]string, height)
+	for i := range rows {
+		idx := top + i
+		if idx >= len(u.names) {
+			rows[i] = strings.Repeat(" ", width)
+			continue
+		}
+		name := u.names[idx]
+		status := u.state.Status(name)
+		elapsed := ""
+		if status.Started() {
+			elapsed = formatElapsed(u.state.Elapsed(name))
+		}
+		row := u.indicator(status) + " " + fit(name, nameWidth) + " " + fmt.Sprintf("%8s", elapsed)
+		if idx == u.selected {
+			row = reverse + row + reset
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// indicator renders a step's status as three columns.
+func (u *ui) indicator(status tasks.StepStatus) string {
+	switch status {
+	case tasks.StepStatusRunning:
+		return yellow + "[" + spinner[u.frame%len(spinner)] + "]" + fgReset
+	case tasks.StepStatusCompleted:
+		return green + "[+]" + fgReset
+	case tasks.StepStatusFailed:
+		return red + "[!]" + fgReset
+	case tasks.StepStatusSkipped:
+		return "[-]"
+	default:
+		return "[ ]"
+	}
+}
+
+func (u *ui) logPane(width, height int) []string {
+	rows := make([]string, height)
+	if len(u.names) == 0 {
+		for i := range rows {
+			rows[i] = fit("", width)
+		}
+		return rows
+	}
+
+	name := u.names[u.selected]
+	status := u.state.Status(name)
+	header := fmt.Sprintf("%s - %s", name, status)
+	if status.Started() {
+		header += " " + formatElapsed(u.state.Elapsed(name))
+	}
+	rows[0] = bold + fit(header, width) + reset
+
+	var lines []string
+	for _, line := range u.state.LogLines(name) {
+		lines = append(lines, wrap(line, width)...)
+	}
+	visible := height - 1
+	u.scroll = max(min(u.scroll, len(lines)-visible), 0)
+	end := len(lines) - u.scroll
+	start := max(end-visible, 0)
+	for i := 1; i < height; i++ {
+		line := ""
+		if idx := start + i - 1; idx < end {
+			line = lines[idx]
+		}
+		switch {
+		case strings.HasPrefix(line, "Error: "):
+			rows[i] = red + fit(line, width) + reset
+		case strings.HasPrefix(line, "Warning: "):
+			rows[i] = yellow + fit(line, width) + reset
+		default:
+			rows[i] = fit(line, width)
+		}
+	}
+	return rows
+}
+
+func (u *ui) summary(width, height int) []string {
+	counts := map[tasks.StepStatus]int{}
+	for _, name := range u.names {
+		counts[u.state.Status(name)]++
+	}
+
+	var lines []string
+	result := green + bold + fit("Installation completed", width) + reset
+	if u.err != nil {
+		result = red + bold + fit("Installation failed", width) + reset
+	}
+	lines = append(lines,
+		result,
+		fit("", width),
+		fit("Total time  "+formatElapsed(u.elapsed), width),
+		fit(fmt.Sprintf("Completed   %d", counts[tasks.StepStatusCompleted]), width),
+		fit(fmt.Sprintf("Failed      %d", counts[tasks.StepStatusFailed]), width),
+		fit(fmt.Sprintf("Skipped     %d", counts[tasks.StepStatusSkipped]), width),
+		fit(fmt.Sprintf("Not run     %d", counts[tasks.StepStatusPending]), width),
+	)
+	if u.err != nil {
+		lines = append(lines, fit("", width))
+		for _, line := range wrap(u.err.Error(), width) {
+			lines = append(lines, red+fit(line, width)+reset)
+		}
+	}
+	lines = append(lines, fit("", width), fit("Press q or Enter to exit.", width))
+
+	rows := make([]string, height)
+	for i := range rows {
+		if i < len(lines) {
+			rows[i] = lines[i]
+		} else {
+			rows[i] = fit("", width)
+		}
+	}
+	return rows
+}
+
+// fit truncates or pads s to exactly width columns.
+func fit(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+// wrap splits a log line into rows of at most width columns, dropping
+// control characters that would upset the layout.
+func wrap(line string, width int) []string {
+	line = escapes.ReplaceAllString(line, "")
+	line = strings.ReplaceAll(line, "\t", "    ")
+	line = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, line)
+
+	r := []rune(line)
+	if len(r) == 0 {
+		return []string{""}
+	}
+	var rows []string
+	for len(r) > width {
+		rows = append(rows, string(r[:width]))
+		r = r[width:]
+	}
+	return append(rows, string(r))
+}
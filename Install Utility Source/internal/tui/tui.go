@@ -0,0 +1,276 @@
+// Package tui runs the installer full screen: a step list with status
+// indicators, the live log of the selected step, elapsed time and a closing
+// summary. Prompts, including secret ones, are answered inside the screen.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/prompts"
+	"yachtcrm-installer/internal/tasks"
+)
+
+// ErrInterrupted is returned when the operator presses Ctrl+C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Run executes runner against ctx. When stdin or stdout is not a terminal
+// the steps run with plain output instead.
+func Run(ctx *installer.Context, runner *installer.Runner) error {
+	inFd, outFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+		return runner.Run(ctx)
+	}
+
+	restoreConsole, err := enableVirtualTerminal()
+	if err != nil {
+		ctx.Warnf("terminal UI unavailable (%v); using plain output", err)
+		return runner.Run(ctx)
+	}
+	defer restoreConsole()
+	saved, err := term.MakeRaw(inFd)
+	if err != nil {
+		ctx.Warnf("terminal UI unavailable (%v); using plain output", err)
+		return runner.Run(ctx)
+	}
+
+	u := newUI(runner.StepNames(), outFd)
+	unsubscribe := ctx.Subscribe(installer.RecordState(u.state))
+	ctx.Output = io.Discard
+	prompts.SetPrompter(u)
+
+	done := make(chan error, 1)
+	go func() { done <- runner.Run(ctx) }()
+	go readKeys(os.Stdin, u.keys)
+
+	io.WriteString(os.Stdout, enterScreen)
+	runErr := u.loop(done)
+	io.WriteString(os.Stdout, leaveScreen)
+	term.Restore(inFd, saved)
+	prompts.SetPrompter(nil)
+	unsubscribe()
+	ctx.Output = nil
+
+	u.printSummary(os.Stdout, runErr)
+	return runErr
+}
+
+type promptRequest struct {
+	text   string
+	secret bool
+	reply  chan string
+}
+
+type ui struct {
+	state   *tasks.State
+	names   []string
+	outFd   int
+	started time.Time
+
+	keys    chan []byte
+	prompts chan *promptRequest
+	notices chan string
+
+	selected int
+	follow   bool // keep the selection on the running step
+	scroll   int  // log lines scrolled up from the bottom
+	prompt   *promptRequest
+	input    []rune
+	notice   string
+
+	finished    bool
+	showSummary bool
+	elapsed     time.Duration
+	err         error
+	frame       int
+}
+
+func newUI(names []string, outFd int) *ui {
+	return &ui{
+		state:   tasks.NewState(names),
+		names:   names,
+		outFd:   outFd,
+		started: time.Now(),
+		keys:    make(chan []byte, 16),
+		prompts: make(chan *promptRequest),
+		notices: make(chan string),
+		follow:  true,
+	}
+}
+
+// Ask implements prompts.Prompter. It blocks the runner until the operator
+// answers in the prompt line.
+func (u *ui) Ask(prompt string, secret bool) (string, error) {
+	req := &promptRequest{text: prompt, secret: secret, reply: make(chan string, 1)}
+	u.prompts <- req
+	return <-req.reply, nil
+}
+
+// Notify implements prompts.Prompter.
+func (u *ui) Notify(message string) {
+	u.notices <- message
+}
+
+// loop redraws the screen until the steps have finished and the operator
+// leaves the summary, and returns the runner's error.
+func (u *ui) loop(done <-chan error) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		u.draw(os.Stdout)
+		select {
+		case err := <-done:
+			done = nil
+			u.finish(err)
+		case req := <-u.prompts:
+			u.prompt, u.input = req, nil
+			u.follow = true
+		case msg := <-u.notices:
+			u.notice = msg
+		case b, ok := <-u.keys:
+			if !ok {
+				// stdin closed; nothing more can be answered or dismissed.
+				if u.finished {
+					return u.err
+				}
+				u.keys = nil
+				continue
+			}
+			for _, k := range parseKeys(b) {
+				switch u.handle(k) {
+				case actionQuit:
+					return u.err
+				case actionInterrupt:
+					return ErrInterrupted
+				}
+			}
+		case <-ticker.C:
+			u.frame++
+		}
+	}
+}
+
+func (u *ui) finish(err error) {
+	u.finished = true
+	u.showSummary = true
+	u.follow = false
+	u.elapsed = time.Since(u.started)
+	u.err = err
+	for i, name := range u.names {
+		if u.state.Status(name) == tasks.StepStatusFailed {
+			u.selected = i
+		}
+	}
+}
+
+type action int
+
+const (
+	actionNone action = iota
+	actionQuit
+	actionInterrupt
+)
+
+func (u *ui) handle(k string) action {
+	if k == keyCtrlC {
+		return actionInterrupt
+	}
+
+	if u.prompt != nil {
+		switch k {
+		case keyEnter:
+			u.prompt.reply <- string(u.input)
+			u.prompt, u.input, u.notice = nil, nil, ""
+		case keyBackspace:
+			if len(u.input) > 0 {
+				u.input = u.input[:len(u.input)-1]
+			}
+		case keyCtrlU:
+			u.input = nil
+		default:
+			if r := []rune(k); len(r) == 1 && r[0] >= ' ' {
+				u.input = append(u.input, r[0])
+			}
+		}
+		return actionNone
+	}
+
+	page := u.logHeight() - 1
+	switch k {
+	case keyUp, "k":
+		if u.selected > 0 {
+			u.selected--
+		}
+		u.follow, u.scroll, u.showSummary = false, 0, false
+	case keyDown, "j":
+		if u.selected < len(u.names)-1 {
+			u.selected++
+		}
+		u.follow, u.scroll, u.showSummary = false, 0, false
+	case keyPgUp:
+		u.scroll += page
+	case keyPgDn:
+		u.scroll = max(u.scroll-page, 0)
+	case keyHome:
+		u.scroll = 1 << 30
+	case keyEnd:
+		u.scroll = 0
+	case "f":
+		if !u.finished {
+			u.follow, u.scroll = true, 0
+		}
+	case "s":
+		if u.finished {
+			u.showSummary = !u.showSummary
+		}
+	case "q", keyEnter:
+		if u.finished {
+			return actionQuit
+		}
+	}
+	return actionNone
+}
+
+// printSummary leaves a plain record on the normal screen once the UI has
+// closed, including the failed step's log.
+func (u *ui) printSummary(w io.Writer, err error) {
+	switch {
+	case err == nil:
+		fmt.Fprintf(w, "Installation completed in %s\n", formatElapsed(u.elapsed))
+	case errors.Is(err, ErrInterrupted):
+		fmt.Fprintf(w, "Installation interrupted after %s\n", formatElapsed(time.Since(u.started)))
+	default:
+		fmt.Fprintf(w, "Installation failed after %s\n", formatElapsed(u.elapsed))
+	}
+	for _, name := range u.names {
+		status := u.state.Status(name)
+		elapsed := ""
+		if status.Started() {
+			elapsed = formatElapsed(u.state.Elapsed(name))
+		}
+		fmt.Fprintf(w, "  %-9s %8s  %s\n", status, elapsed, name)
+	}
+	for _, name := range u.names {
+		if u.state.Status(name) == tasks.StepStatusFailed {
+			fmt.Fprintf(w, "\nLog for %s:\n", name)
+			for _, line := range u.state.LogLines(name) {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+	}
+}
+
+func formatElapsed(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
@@ -0,0 +1,110 @@
+package wizard
+
+import (
+	"strings"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/modules"
+	"yachtcrm-installer/internal/steps"
+)
+
+const modulePrefix = "module:"
+
+// page is an InputPage as the browser renders it. The modules question is
+// replaced by a yes/no input per module.
+type page struct {
+	steps.InputPage
+	Modules bool `json:"modules,omitempty"`
+}
+
+func formPages(instance string) ([]page, error) {
+	inputPages, err := steps.InputPages(instance)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]page, 0, len(inputPages))
+	for _, p := range inputPages {
+		if len(p.Inputs) != 1 || p.Inputs[0].Key != "modules" {
+			pages = append(pages, page{InputPage: p})
+			continue
+		}
+		mp := page{InputPage: steps.InputPage{
+			Title:  p.Title,
+			Toggle: "Choose the enabled modules now? (otherwise the imported database's settings are kept)",
+		}, Modules: true}
+		for _, m := range modules.Defaults {
+			def := "no"
+			if m.Enabled {
+				def = "yes"
+			}
+			mp.Inputs = append(mp.Inputs, steps.Input{Key: modulePrefix + m.Key, Question: m.Name + " - " + m.Description, Default: def, YesNo: true})
+		}
+		pages = append(pages, mp)
+	}
+	return pages, nil
+}
+
+// submission is the wizard form as the browser posts it.
+type submission struct {
+	// Page limits the check to one page; install checks every page.
+	Page   string            `json:"page"`
+	Values map[string]string `json:"values"`
+	// Enabled holds the optional pages, those with a Toggle, that are
+	// switched on.
+	Enabled map[string]bool `json:"enabled"`
+}
+
+// check treats the submission like typed answers and converts it into an
+// answer file for the runner. Errors are keyed by input.
+func (sub submission) check() (*installer.Answers, map[string]string) {
+	errs := map[string]string{}
+	pages, err := formPages(sub.Values["instance_name"])
+	if err != nil {
+		errs["instance_name"] = err.Error()
+		return nil, errs
+	}
+
+	answers := &installer.Answers{Inputs: map[string]string{}, Env: map[string]string{}}
+	checked := map[string]string{}
+	for _, p := range pages {
+		if sub.Page != "" && p.Title != sub.Page {
+			continue
+		}
+		if p.Toggle != "" && !sub.Enabled[p.Title] {
+			if p.Modules {
+				answers.Inputs["modules"] = ""
+			}
+			continue
+		}
+
+		var enabled []string
+		for _, in := range p.Inputs {
+			if !in.Applies(checked) {
+				continue
+			}
+			value, err := in.Check(sub.Values[in.Key])
+			if err != nil {
+				errs[in.Key] = err.Error()
+				continue
+			}
+			checked[in.Key] = value
+			switch {
+			case p.Env:
+				answers.Env[in.Key] = value
+			case p.Modules:
+				if value == "yes" {
+					enabled = append(enabled, strings.TrimPrefix(in.Key, modulePrefix))
+				}
+			default:
+				answers.Inputs[in.Key] = value
+			}
+		}
+		if p.Modules {
+			answers.Inputs["modules"] = "none"
+			if len(enabled) > 0 {
+				answers.Inputs["modules"] = strings.Join(enabled, ",")
+			}
+		}
+	}
+	return answers, errs
+}
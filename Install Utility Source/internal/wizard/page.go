@@ -0,0 +1,258 @@
+package wizard
+
+// indexHTML is the whole wizard: the settings pages, a review page and the
+// progress view. It talks to the /api endpoints and renders everything with
+// textContent so values from the server are never parsed as markup.
+const indexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>YachtCRM-DMS Installer</title>
+<style>
+  body { font-family: "Segoe UI", Arial, sans-serif; margin: 0; background: #f3f5f8; color: #1d2733; }
+  header { background: #12344d; color: #fff; padding: 14px 24px; font-size: 20px; }
+  main { max-width: 1100px; margin: 24px auto; padding: 0 24px; }
+  .card { background: #fff; border-radius: 6px; box-shadow: 0 1px 3px rgba(0,0,0,.12); padding: 24px; }
+  .crumbs { color: #5b6b7a; font-size: 13px; margin-bottom: 12px; }
+  h2 { margin-top: 0; }
+  label { display: block; margin: 14px 0 4px; font-weight: 600; }
+  input[type=text], input[type=password], select { width: 100%; box-sizing: border-box; padding: 8px; border: 1px solid #b8c2cc; border-radius: 4px; font-size: 14px; }
+  .toggle { display: flex; gap: 8px; align-items: center; font-weight: 600; margin-bottom: 8px; }
+  .error { color: #b3261e; font-size: 13px; margin-top: 4px; }
+  .hint { color: #5b6b7a; font-size: 12px; }
+  .buttons { margin-top: 24px; display: flex; justify-content: space-between; }
+  button, a.button { background: #1f6fb2; color: #fff; border: 0; border-radius: 4px; padding: 9px 18px; font-size: 14px; cursor: pointer; text-decoration: none; }
+  button.secondary { background: #e3e8ee; color: #1d2733; }
+  button:disabled { opacity: .5; cursor: default; }
+  table.review { border-collapse: collapse; width: 100%; font-size: 14px; }
+  table.review td { border-bottom: 1px solid #e3e8ee; padding: 6px; vertical-align: top; }
+  table.review td.section { font-weight: 600; padding-top: 16px; }
+  .progress { display: grid; grid-template-columns: 320px 1fr; gap: 16px; }
+  .steps div { padding: 6px 8px; border-radius: 4px; cursor: pointer; display: flex; justify-content: space-between; font-size: 14px; }
+  .steps div.selected { background: #dbe8f5; }
+  .Pending { color: #7a8794; } .Running { color: #a66300; font-weight: 600; } .Completed { color: #1e7b34; } .Failed { color: #b3261e; font-weight: 600; } .Skipped { color: #7a8794; }
+  pre { background: #0f1a24; color: #d7e0e8; padding: 12px; height: 460px; overflow: auto; margin: 0; font-size: 12px; white-space: pre-wrap; word-break: break-all; border-radius: 4px; }
+  .question { background: #fff6e0; border: 1px solid #e6c56a; border-radius: 6px; padding: 12px 16px; margin-bottom: 16px; }
+  .banner { padding: 12px 16px; border-radius: 6px; margin-bottom: 16px; font-weight: 600; }
+  .banner.ok { background: #e2f4e6; color: #1e7b34; } .banner.failed { background: #fbe4e2; color: #b3261e; }
+</style>
+</head>
+<body>
+<header>YachtCRM-DMS Installer</header>
+<main><div class="card" id="view">Loading...</div></main>
+<script>
+"use strict";
+let pages = [], values = {}, enabled = {}, errors = {}, index = 0;
+const view = document.getElementById("view");
+
+function el(tag, attrs, ...children) {
+  const e = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (k === "onclick" || k === "onchange") e[k] = v; else e.setAttribute(k, v);
+  }
+  for (const c of children) e.append(c);
+  return e;
+}
+
+async function api(method, path, body) {
+  const opts = { method, headers: {} };
+  if (body !== undefined) { opts.headers["Content-Type"] = "application/json"; opts.body = JSON.stringify(body); }
+  const res = await fetch(path, opts);
+  const data = await res.json().catch(() => ({}));
+  return { status: res.status, data };
+}
+
+async function loadPages() {
+  const r = await api("GET", "/api/pages?instance=" + encodeURIComponent(values.instance_name || ""));
+  if (r.status !== 200) { alert(r.data.error); return false; }
+  pages = r.data.pages;
+  if (r.data.started) { showProgress(); return false; }
+  return true;
+}
+
+function applies(input) {
+  for (const [k, v] of Object.entries(input.when || {})) {
+    const current = values[k] !== undefined ? values[k] : (findInput(k) || {}).default;
+    if (current !== v) return false;
+  }
+  return true;
+}
+
+function findInput(key) {
+  for (const p of pages) for (const i of p.inputs) if (i.key === key) return i;
+}
+
+function valueOf(input) {
+  return values[input.key] !== undefined ? values[input.key] : (input.secret ? "" : (input.default || ""));
+}
+
+function field(input) {
+  const wrap = el("div");
+  wrap.append(el("label", { for: "f-" + input.key }, input.question));
+  let control;
+  if (input.yes_no) {
+    control = el("select", { id: "f-" + input.key }, el("option", { value: "yes" }, "Yes"), el("option", { value: "no" }, "No"));
+    control.value = valueOf(input) || "no";
+    control.onchange = () => { values[input.key] = control.value; renderPage(); };
+  } else {
+    control = el("input", { id: "f-" + input.key, type: input.secret ? "password" : "text", autocomplete: "off" });
+    control.value = valueOf(input);
+    control.onchange = () => { values[input.key] = control.value; };
+    if (!input.required && !input.default) control.placeholder = "optional";
+  }
+  wrap.append(control);
+  if (errors[input.key]) wrap.append(el("div", { class: "error" }, errors[input.key]));
+  return wrap;
+}
+
+function collect() {
+  for (const input of pages[index].inputs) {
+    const c = document.getElementById("f-" + input.key);
+    if (c) values[input.key] = c.value;
+  }
+}
+
+function renderPage() {
+  const page = pages[index];
+  view.replaceChildren();
+  view.append(el("div", { class: "crumbs" }, "Step " + (index + 1) + " of " + (pages.length + 1)));
+  view.append(el("h2", {}, page.title));
+  let show = true;
+  if (page.toggle) {
+    const box = el("input", { type: "checkbox", id: "toggle" });
+    box.checked = !!enabled[page.title];
+    box.onchange = () => { collect(); enabled[page.title] = box.checked; renderPage(); };
+    view.append(el("div", { class: "toggle" }, box, el("label", { for: "toggle" }, page.toggle)));
+    show = box.checked;
+  }
+  if (show) for (const input of page.inputs) if (applies(input)) view.append(field(input));
+  const back = el("button", { class: "secondary", onclick: () => { collect(); index--; errors = {}; renderPage(); } }, "Back");
+  if (index === 0) back.disabled = true;
+  view.append(el("div", { class: "buttons" }, back, el("button", { onclick: next }, "Next")));
+}
+
+async function next() {
+  collect();
+  const page = pages[index];
+  const r = await api("POST", "/api/validate", { page: page.title, values, enabled });
+  errors = r.data.errors || {};
+  if (Object.keys(errors).length > 0) { renderPage(); return; }
+  if (index === 0 && !(await loadPages())) return;
+  index++;
+  if (index < pages.length) renderPage(); else renderReview();
+}
+
+function renderReview() {
+  view.replaceChildren();
+  view.append(el("div", { class: "crumbs" }, "Step " + (pages.length + 1) + " of " + (pages.length + 1)));
+  view.append(el("h2", {}, "Review and install"));
+  const table = el("table", { class: "review" });
+  pages.forEach((page, i) => {
+    const edit = el("a", { href: "#" }, "edit");
+    edit.onclick = (e) => { e.preventDefault(); index = i; renderPage(); };
+    table.append(el("tr", {}, el("td", { class: "section" }, page.title), el("td", { class: "section" }, edit)));
+    if (page.toggle && !enabled[page.title]) { table.append(el("tr", {}, el("td", {}, "Not configured"), el("td"))); return; }
+    for (const input of page.inputs) {
+      if (!applies(input)) continue;
+      let v = valueOf(input);
+      if (input.secret && v) v = "********";
+      table.append(el("tr", {}, el("td", {}, input.question), el("td", {}, v)));
+    }
+  });
+  view.append(table);
+  const install = el("button", { onclick: startInstall }, "Install");
+  view.append(el("div", { class: "buttons" }, el("button", { class: "secondary", onclick: () => { index = pages.length - 1; renderPage(); } }, "Back"), install));
+}
+
+async function startInstall() {
+  const r = await api("POST", "/api/install", { values, enabled });
+  if (r.status === 422) {
+    errors = r.data.errors;
+    index = pages.findIndex(p => p.inputs.some(i => errors[i.key]));
+    if (index < 0) index = 0;
+    renderPage();
+    return;
+  }
+  if (r.status !== 202 && r.status !== 409) { alert(r.data.error || "Could not start the installation"); return; }
+  showProgress();
+}
+
+function duration(ms) {
+  const s = Math.round(ms / 1000);
+  const pad = n => String(n).padStart(2, "0");
+  return s >= 3600 ? Math.floor(s / 3600) + ":" + pad(Math.floor(s / 60) % 60) + ":" + pad(s % 60) : pad(Math.floor(s / 60)) + ":" + pad(s % 60);
+}
+
+function showProgress() {
+  const logs = {};
+  let selected = null, follow = true, last = null, answered = 0;
+  const banner = el("div"), questionBox = el("div"), stepList = el("div", { class: "steps" }), logPane = el("pre"), elapsed = el("div", { class: "hint" });
+  view.replaceChildren(el("h2", {}, "Installing"), elapsed, banner, questionBox, el("div", { class: "progress" }, stepList, logPane));
+
+  function renderLog() {
+    const atBottom = logPane.scrollTop + logPane.clientHeight >= logPane.scrollHeight - 4;
+    logPane.textContent = (logs[selected] || []).join("\n");
+    if (atBottom) logPane.scrollTop = logPane.scrollHeight;
+  }
+
+  function renderQuestion(p) {
+    if (!p.question) { questionBox.replaceChildren(); return; }
+    if (p.question.id === answered && !p.notice) return;
+    if (questionBox.dataset.id === String(p.question.id) && questionBox.dataset.notice === (p.notice || "")) return;
+    questionBox.dataset.id = p.question.id;
+    questionBox.dataset.notice = p.notice || "";
+    const input = el("input", { type: p.question.secret ? "password" : "text", autocomplete: "off" });
+    const send = async () => {
+      answered = p.question.id;
+      questionBox.replaceChildren(el("div", { class: "hint" }, "Answer sent..."));
+      await api("POST", "/api/answer", { id: p.question.id, value: input.value });
+    };
+    input.onkeydown = e => { if (e.key === "Enter") send(); };
+    const box = el("div", { class: "question" }, el("label", {}, p.question.text), input);
+    if (p.notice) box.append(el("div", { class: "error" }, p.notice));
+    box.append(el("div", { class: "buttons" }, el("span"), el("button", { onclick: send }, "Answer")));
+    questionBox.replaceChildren(box);
+    input.focus();
+  }
+
+  const events = new EventSource("/api/events");
+  events.onmessage = (msg) => {
+    const p = JSON.parse(msg.data);
+    if (last === null) for (const k in logs) delete logs[k];
+    last = p;
+    for (const [step, lines] of Object.entries(p.logs || {})) (logs[step] = logs[step] || []).push(...lines);
+    if (follow) {
+      const running = p.steps.find(s => s.status === "Running");
+      if (running) selected = running.name;
+    }
+    if (selected === null && p.steps.length) selected = p.steps[0].name;
+    stepList.replaceChildren(...p.steps.map(s => {
+      const row = el("div", { class: s.name === selected ? "selected" : "" },
+        el("span", { class: s.status }, s.name),
+        el("span", { class: "hint" }, s.status === "Pending" || s.status === "Skipped" ? s.status : s.status + " " + duration(s.elapsed_ms)));
+      row.onclick = () => { selected = s.name; follow = false; renderLog(); stepList.querySelectorAll("div").forEach(d => d.classList.toggle("selected", d === row)); };
+      return row;
+    }));
+    elapsed.textContent = "Elapsed " + duration(p.elapsed_ms);
+    renderLog();
+    renderQuestion(p);
+    if (p.done) {
+      events.close();
+      const failed = p.steps.find(s => s.status === "Failed");
+      if (failed) { selected = failed.name; renderLog(); }
+      banner.className = "banner " + (p.error ? "failed" : "ok");
+      banner.replaceChildren(p.error ? "Installation failed: " + p.error : "Installation completed in " + duration(p.elapsed_ms) + ".", " ",
+        el("a", { class: "button", href: "/api/report" }, "Download report"));
+      view.querySelector("h2").textContent = p.error ? "Installation failed" : "Installation complete";
+    }
+  };
+  events.onerror = () => { last = null; };
+}
+
+(async () => {
+  if (await loadPages()) renderPage();
+})();
+</script>
+</body>
+</html>
+`
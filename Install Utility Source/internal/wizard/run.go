@@ -0,0 +1,193 @@
+package wizard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"yachtcrm-installer/internal/installer"
+	"yachtcrm-installer/internal/prompts"
+	"yachtcrm-installer/internal/tasks"
+)
+
+// run is an install started from the wizard. Questions the answers do not
+// cover, such as a missing Prerequisites directory, are relayed to the page.
+type run struct {
+	ctx     *installer.Context
+	state   *tasks.State
+	names   []string
+	started time.Time
+
+	mu       sync.Mutex
+	done     bool
+	err      error
+	finished time.Time
+	question *question
+	notice   string
+	asked    int
+}
+
+type question struct {
+	ID     int    `json:"id"`
+	Text   string `json:"text"`
+	Secret bool   `json:"secret"`
+	reply  chan string
+}
+
+func startRun(runner *installer.Runner, answers *installer.Answers) *run {
+	r := &run{names: runner.StepNames(), started: time.Now()}
+	r.state = tasks.NewState(r.names)
+	r.ctx = &installer.Context{Answers: answers}
+	r.ctx.Subscribe(installer.RecordState(r.state))
+	r.ctx.Subscribe(r.finish)
+	prompts.SetPrompter(r)
+
+	go func() {
+		if err := runner.Run(r.ctx); err != nil {
+			r.ctx.Logf("Installation failed: %v", err)
+		} else {
+			r.ctx.Logf("Installation completed")
+		}
+	}()
+	return r
+}
+
+// finish records the outcome when the runner publishes the end of the run.
+func (r *run) finish(e installer.Event) {
+	if e.Kind != installer.EventRunFinished {
+		return
+	}
+	r.mu.Lock()
+	r.done, r.err, r.finished = true, e.Err, e.Time
+	r.mu.Unlock()
+}
+
+// Ask implements prompts.Prompter. It blocks the runner until the page
+// posts an answer.
+func (r *run) Ask(prompt string, secret bool) (string, error) {
+	r.mu.Lock()
+	r.asked++
+	q := &question{ID: r.asked, Text: prompt, Secret: secret, reply: make(chan string, 1)}
+	r.question = q
+	r.mu.Unlock()
+	return <-q.reply, nil
+}
+
+// Notify implements prompts.Prompter.
+func (r *run) Notify(message string) {
+	r.mu.Lock()
+	r.notice = message
+	r.mu.Unlock()
+}
+
+func (r *run) answer(id int, value string) error {
+	r.mu.Lock()
+	q := r.question
+	if q == nil || q.ID != id {
+		r.mu.Unlock()
+		return errors.New("that question is no longer waiting for an answer")
+	}
+	r.question, r.notice = nil, ""
+	r.mu.Unlock()
+	q.reply <- value
+	return nil
+}
+
+type stepProgress struct {
+	Name      string           `json:"name"`
+	Status    tasks.StepStatus `json:"status"`
+	ElapsedMS int64            `json:"elapsed_ms"`
+}
+
+type progress struct {
+	Steps []stepProgress `json:"steps"`
+	// Logs holds the lines added since the previous event, by step.
+	Logs      map[string][]string `json:"logs,omitempty"`
+	Question  *question           `json:"question"`
+	Notice    string              `json:"notice,omitempty"`
+	ElapsedMS int64               `json:"elapsed_ms"`
+	Done      bool                `json:"done"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// progress snapshots the run. offsets tracks how many log lines of each
+// step the caller has already been sent.
+func (r *run) progress(offsets map[string]int) progress {
+	r.mu.Lock()
+	p := progress{Question: r.question, Notice: r.notice, Done: r.done}
+	end := time.Now()
+	if r.done {
+		end = r.finished
+		if r.err != nil {
+			p.Error = r.err.Error()
+		}
+	}
+	r.mu.Unlock()
+	p.ElapsedMS = end.Sub(r.started).Milliseconds()
+
+	for _, name := range r.names {
+		p.Steps = append(p.Steps, stepProgress{Name: name, Status: r.state.Status(name), ElapsedMS: r.state.Elapsed(name).Milliseconds()})
+		lines := r.state.LogLines(name)
+		if len(lines) > offsets[name] {
+			if p.Logs == nil {
+				p.Logs = map[string][]string{}
+			}
+			p.Logs[name] = lines[offsets[name]:]
+			offsets[name] = len(lines)
+		}
+	}
+	return p
+}
+
+// report writes the downloadable install report: the outcome, each step's
+// status and time, and every step's log.
+func (r *run) report(w io.Writer) {
+	r.mu.Lock()
+	done, err, finished := r.done, r.err, r.finished
+	r.mu.Unlock()
+
+	host, _ := os.Hostname()
+	fmt.Fprintf(w, "YachtCRM-DMS installation report\n\n")
+	fmt.Fprintf(w, "Machine:   %s\n", host)
+	fmt.Fprintf(w, "Started:   %s\n", r.started.Format(time.RFC1123))
+	switch {
+	case !done:
+		fmt.Fprintf(w, "Result:    still running (%s so far)\n", time.Since(r.started).Round(time.Second))
+	case err != nil:
+		fmt.Fprintf(w, "Result:    failed after %s\n", finished.Sub(r.started).Round(time.Second))
+		fmt.Fprintf(w, "Error:     %v\n", err)
+	default:
+		fmt.Fprintf(w, "Result:    completed in %s\n", finished.Sub(r.started).Round(time.Second))
+	}
+	if done {
+		// The steps have finished, so the context is no longer written to.
+		fmt.Fprintf(w, "Instance:  %s\n", r.ctx.InstanceName)
+		fmt.Fprintf(w, "Site:      %s\n", r.ctx.AppURL)
+		fmt.Fprintf(w, "Runtime:   %s\n", r.ctx.RuntimeDir)
+		fmt.Fprintf(w, "Database:  %s on %s:%d\n", r.ctx.DatabaseName, r.ctx.DatabaseHost, r.ctx.DatabasePort)
+		fmt.Fprintf(w, "Admin:     %s\n", r.ctx.AdminEmail)
+	}
+
+	fmt.Fprintf(w, "\nSteps:\n")
+	for _, name := range r.names {
+		status := r.state.Status(name)
+		elapsed := ""
+		if status.Started() {
+			elapsed = r.state.Elapsed(name).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "  %-9s %8s  %s\n", status, elapsed, name)
+	}
+	for _, name := range r.names {
+		lines := r.state.LogLines(name)
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n== %s ==\n", name)
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Package wizard serves the browser-based installer: a step-by-step form
+// for the install settings, live progress of the runner streamed with
+// Server-Sent Events, and a downloadable report. It only listens on
+// loopback addresses and admits the browser that opens the one-time link.
+package wizard
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"yachtcrm-installer/internal/installer"
+)
+
+const sessionCookie = "ycrm_wizard"
+
+// Server is the wizard's HTTP handler and the install it starts.
+type Server struct {
+	runner *installer.Runner
+
+	mu      sync.Mutex
+	token   string // from the printed link; cleared once it has been used
+	session string
+	run     *run
+}
+
+// New prepares a wizard that installs with runner. The wizard starts at most
+// one run.
+func New(runner *installer.Runner) (*Server, error) {
+	token, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	session, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{runner: runner, token: token, session: session}, nil
+}
+
+// Listen opens addr, which must be a loopback address such as
+// 127.0.0.1:0.
+func Listen(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !isLoopback(host) {
+		return nil, fmt.Errorf("%s is not a loopback address; the wizard only listens on localhost", host)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// URL is the one-time link to open the wizard on ln.
+func (s *Server) URL(ln net.Listener) string {
+	return fmt.Sprintf("http://%s/?token=%s", ln.Addr(), s.token)
+}
+
+// Serve handles requests on ln until ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /api/pages", s.authed(s.pages))
+	mux.HandleFunc("POST /api/validate", s.authed(s.validate))
+	mux.HandleFunc("POST /api/install", s.authed(s.install))
+	mux.HandleFunc("POST /api/answer", s.authed(s.answer))
+	mux.HandleFunc("GET /api/events", s.authed(s.events))
+	mux.HandleFunc("GET /api/report", s.authed(s.report))
+
+	srv := &http.Server{Handler: guard(mux), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// guard rejects requests addressed to anything but a loopback host, which
+// stops DNS rebinding, and sets headers that keep the page out of frames
+// and caches.
+func guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !isLoopback(strings.Trim(host, "[]")) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		h := w.Header()
+		h.Set("Cache-Control", "no-store")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'; frame-ancestors 'none'")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// index exchanges the one-time token for a session cookie and serves the
+// wizard page to the browser holding that cookie.
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		s.mu.Lock()
+		ok := s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+		if ok {
+			s.token = ""
+		}
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "This link is invalid or has already been used. Restart installer serve for a new one.", http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: s.session, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, "Open the link printed by installer serve.", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(indexHTML))
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	c, err := r.Cookie(sessionCookie)
+	return err == nil && subtle.ConstantTimeCompare([]byte(c.Value), []byte(s.session)) == 1
+}
+
+// authed requires the session cookie, and JSON bodies on POST so that other
+// sites cannot submit forms to the wizard.
+func (s *Server) authed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "not signed in"})
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "expected application/json"})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) pages(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	started := s.run != nil
+	s.mu.Unlock()
+	pages, err := formPages(r.URL.Query().Get("instance"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pages": pages, "started": started})
+}
+
+func (s *Server) validate(w http.ResponseWriter, r *http.Request) {
+	var sub submission
+	if !readJSON(w, r, &sub) {
+		return
+	}
+	_, errs := sub.check()
+	writeJSON(w, http.StatusOK, map[string]any{"errors": errs})
+}
+
+func (s *Server) install(w http.ResponseWriter, r *http.Request) {
+	var sub submission
+	if !readJSON(w, r, &sub) {
+		return
+	}
+	sub.Page = ""
+	answers, errs := sub.check()
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"errors": errs})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.run != nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "the installation has already been started"})
+		return
+	}
+	s.run = startRun(s.runner, answers)
+	writeJSON(w, http.StatusAccepted, map[string]bool{"started": true})
+}
+
+func (s *Server) current(w http.ResponseWriter) *run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.run == nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "the installation has not been started"})
+	}
+	return s.run
+}
+
+func (s *Server) answer(w http.ResponseWriter, r *http.Request) {
+	run := s.current(w)
+	if run == nil {
+		return
+	}
+	var req struct {
+		ID    int    `json:"id"`
+		Value string `json:"value"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if err := run.answer(req.ID, req.Value); err != nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// events streams the install's progress. Each event carries the step
+// statuses, the log lines added since the previous event and any question
+// waiting for an answer; the stream ends after the final event.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	run := s.current(w)
+	if run == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	offsets := map[string]int{}
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		p := run.progress(offsets)
+		data, err := json.Marshal(p)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+		if p.Done {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) report(w http.ResponseWriter, r *http.Request) {
+	run := s.current(w)
+	if run == nil {
+		return
+	}
+	name := fmt.Sprintf("yachtcrm-install-%s.txt", run.started.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	run.report(w)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err := dec.Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
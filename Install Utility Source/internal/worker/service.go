@@ -0,0 +1,150 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"yachtcrm-installer/internal/powershell"
+	"yachtcrm-installer/internal/scheduler"
+)
+
+// DefaultTaskName is the Windows task that keeps the supervisor running.
+const DefaultTaskName = "YachtCRM Queue Worker"
+
+// Service describes how the supervisor is registered with Windows. The
+// supervisor runs from a task started at boot with restart-on-failure
+// settings, which keeps it alive without a separate service wrapper.
+type Service struct {
+	TaskName string
+	ExePath  string
+	Args     []string
+	// RunAsUser defaults to SYSTEM.
+	RunAsUser     string
+	RunAsPassword string
+}
+
+// Install registers the service, replacing any existing registration.
+func Install(svc Service) error {
+	if svc.TaskName == "" || svc.ExePath == "" {
+		return errors.New("task name and executable are required")
+	}
+	user := svc.RunAsUser
+	if user == "" {
+		user = "SYSTEM"
+	}
+
+	quotedArgs := make([]string, 0, len(svc.Args))
+	for _, arg := range svc.Args {
+		if strings.ContainsAny(arg, " \t") {
+			arg = `"` + arg + `"`
+		}
+		quotedArgs = append(quotedArgs, arg)
+	}
+
+	script := strings.Builder{}
+	script.WriteString(fmt.Sprintf("$action = New-ScheduledTaskAction -Execute %s -Argument %s\n",
+		powershell.Quote(svc.ExePath), powershell.Quote(strings.Join(quotedArgs, " "))))
+	script.WriteString("$trigger = New-ScheduledTaskTrigger -AtStartup\n")
+	script.WriteString("$settings = New-ScheduledTaskSettingsSet -AllowStartIfOnBatteries -DontStopIfGoingOnBatteries -StartWhenAvailable -MultipleInstances IgnoreNew -ExecutionTimeLimit ([TimeSpan]::Zero) -RestartCount 999 -RestartInterval (New-TimeSpan -Minutes 1)\n")
+	if scheduler.IsServiceAccount(user) {
+		script.WriteString(fmt.Sprintf("$principal = New-ScheduledTaskPrincipal -UserId %s -LogonType ServiceAccount -RunLevel Highest\n", powershell.Quote(user)))
+		script.WriteString(fmt.Sprintf("Register-ScheduledTask -TaskName %s -Action $action -Trigger $trigger -Settings $settings -Principal $principal -Force | Out-Null\n",
+			powershell.Quote(svc.TaskName)))
+	} else {
+		if svc.RunAsPassword == "" {
+			return fmt.Errorf("a password is required to run the worker as %s", user)
+		}
+		script.WriteString(fmt.Sprintf("Register-ScheduledTask -TaskName %s -Action $action -Trigger $trigger -Settings $settings -User %s -Password %s -RunLevel Highest -Force | Out-Null\n",
+			powershell.Quote(svc.TaskName), powershell.Quote(user), powershell.Quote(svc.RunAsPassword)))
+	}
+
+	result := powershell.Run(script.String())
+	if result.Err != nil {
+		return fmt.Errorf("register worker service: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	return nil
+}
+
+// Installed reports whether the service is registered.
+func Installed(taskName string) bool {
+	result := powershell.Run(fmt.Sprintf(`if (Get-ScheduledTask -TaskName %s -ErrorAction SilentlyContinue) { 'yes' }`, powershell.Quote(taskName)))
+	return result.Err == nil && result.Stdout == "yes"
+}
+
+// Remove stops and unregisters the service.
+func Remove(taskName string) error {
+	script := fmt.Sprintf(`if (Get-ScheduledTask -TaskName %[1]s -ErrorAction SilentlyContinue) { Stop-ScheduledTask -TaskName %[1]s; Unregister-ScheduledTask -TaskName %[1]s -Confirm:$false }`, powershell.Quote(taskName))
+	result := powershell.Run(script)
+	if result.Err != nil {
+		return fmt.Errorf("remove worker service: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	return nil
+}
+
+// Start clears any pending stop request and starts the service.
+func Start(cfg Config) error {
+	cfg = cfg.withDefaults()
+	if err := os.Remove(cfg.stopPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("clear stop request: %w", err)
+	}
+	result := powershell.Run(fmt.Sprintf(`Start-ScheduledTask -TaskName %s`, powershell.Quote(cfg.Name)))
+	if result.Err != nil {
+		return fmt.Errorf("start worker service: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	return nil
+}
+
+// GracefulRestart asks running workers to finish their current job and
+// exit. The supervisor then starts a fresh worker with the deployed code.
+func GracefulRestart(phpExePath, backendDir string) error {
+	cmd := exec.Command(phpExePath, "artisan", "queue:restart")
+	cmd.Dir = backendDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("artisan queue:restart: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Stop asks the supervisor to exit once the current job is done and waits
+// up to timeout for it to do so. If it is still running afterwards the
+// task is stopped forcibly.
+func Stop(cfg Config, timeout time.Duration) error {
+	cfg = cfg.withDefaults()
+	if err := os.MkdirAll(cfg.StateDir, 0o755); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	if err := os.WriteFile(cfg.stopPath(), []byte(time.Now().Format(time.RFC3339)), 0o644); err != nil {
+		return fmt.Errorf("write stop request: %w", err)
+	}
+
+	if fileExists(cfg.pidPath()) {
+		restartErr := GracefulRestart(cfg.PhpExePath, cfg.BackendDir)
+		deadline := time.Now().Add(timeout)
+		for restartErr == nil && fileExists(cfg.pidPath()) && time.Now().Before(deadline) {
+			time.Sleep(time.Second)
+		}
+	}
+
+	result := powershell.Run(fmt.Sprintf(`Stop-ScheduledTask -TaskName %s -ErrorAction SilentlyContinue`, powershell.Quote(cfg.Name)))
+	if result.Err != nil {
+		return fmt.Errorf("stop worker service: %w (stderr: %s)", result.Err, result.Stderr)
+	}
+	return nil
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func safeName(name string) string {
+	return unsafeNameChars.ReplaceAllString(name, "_")
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,250 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exitMemoryExceeded is the status `queue:work --memory` exits with when
+// the worker outgrows its limit. It is a recycle, not a crash.
+const exitMemoryExceeded = 12
+
+// Config controls how the supervisor runs `php artisan queue:work`.
+type Config struct {
+	Name       string
+	PhpExePath string
+	BackendDir string
+	LogPath    string
+	StateDir   string
+	Queue      string
+	// MaxJobs and MaxMemoryMB are passed through to queue:work, which exits
+	// cleanly once either is reached so the supervisor can start a fresh
+	// process.
+	MaxJobs     int
+	MaxMemoryMB int
+	Sleep       int
+	Tries       int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	// StableAfter resets the crash backoff once a worker has run this long.
+	StableAfter time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Name == "" {
+		c.Name = DefaultTaskName
+	}
+	if c.LogPath == "" {
+		c.LogPath = filepath.Join(c.BackendDir, "storage", "logs", "queue-worker.log")
+	}
+	if c.StateDir == "" {
+		c.StateDir = DefaultStateDir()
+	}
+	if c.Queue == "" {
+		c.Queue = "default"
+	}
+	if c.MaxJobs == 0 {
+		c.MaxJobs = 500
+	}
+	if c.MaxMemoryMB == 0 {
+		c.MaxMemoryMB = 256
+	}
+	if c.Sleep == 0 {
+		c.Sleep = 3
+	}
+	if c.Tries == 0 {
+		c.Tries = 3
+	}
+	if c.MinBackoff == 0 {
+		c.MinBackoff = 2 * time.Second
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = 5 * time.Minute
+	}
+	if c.StableAfter == 0 {
+		c.StableAfter = time.Minute
+	}
+	return c
+}
+
+// DefaultStateDir holds pid and stop-request files shared between the
+// supervisor and the installer.
+func DefaultStateDir() string {
+	base := os.Getenv("ProgramData")
+	if base == "" {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "YachtCRM-DMS", "worker")
+}
+
+func (c Config) pidPath() string  { return filepath.Join(c.StateDir, safeName(c.Name)+".pid") }
+func (c Config) stopPath() string { return filepath.Join(c.StateDir, safeName(c.Name)+".stop") }
+
+// Supervise runs queue:work until ctx is cancelled or a stop is requested.
+// Clean exits (max jobs, memory limit, queue:restart) restart immediately;
+// crashes restart with exponential backoff.
+func Supervise(ctx context.Context, cfg Config) error {
+	cfg = cfg.withDefaults()
+	if cfg.PhpExePath == "" || cfg.BackendDir == "" {
+		return errors.New("php executable and backend directory are required")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.LogPath), 0o755); err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+	logFile, err := os.OpenFile(cfg.LogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open worker log: %w", err)
+	}
+	defer logFile.Close()
+	log := &lineLogger{w: logFile}
+
+	if err := os.MkdirAll(cfg.StateDir, 0o755); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	if err := os.WriteFile(cfg.pidPath(), []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		return fmt.Errorf("write pid file: %w", err)
+	}
+	defer os.Remove(cfg.pidPath())
+
+	log.Printf("supervisor: starting %s (queue %s, max jobs %d, memory %dMB)", cfg.Name, cfg.Queue, cfg.MaxJobs, cfg.MaxMemoryMB)
+	// A stop request is meant for the supervisor running when it was made.
+	// One left behind, e.g. by an install that failed after stopping the
+	// worker, must not keep this one from running.
+	if err := os.Remove(cfg.stopPath()); err == nil {
+		log.Printf("supervisor: cleared a stale stop request")
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("clear stop request: %w", err)
+	}
+	backoff := cfg.MinBackoff
+	for {
+		if stopRequested(cfg) {
+			log.Printf("supervisor: stop requested, exiting")
+			return nil
+		}
+
+		started := time.Now()
+		code, err := runWorker(ctx, cfg, log)
+		if ctx.Err() != nil {
+			log.Printf("supervisor: shutting down")
+			return nil
+		}
+		elapsed := time.Since(started)
+		ran := elapsed.Round(time.Second)
+
+		switch {
+		case err != nil:
+			log.Printf("supervisor: worker failed to start: %v", err)
+		// A worker that exits cleanly straight away gets the crash backoff
+		// so a misconfiguration cannot spin the CPU.
+		case (code == 0 || code == exitMemoryExceeded) && elapsed >= cfg.MinBackoff:
+			log.Printf("supervisor: worker recycled after %s (exit %d)", ran, code)
+			backoff = cfg.MinBackoff
+			continue
+		default:
+			log.Printf("supervisor: worker exited after %s (exit %d)", ran, code)
+		}
+
+		if ran >= cfg.StableAfter {
+			backoff = cfg.MinBackoff
+		}
+		log.Printf("supervisor: restarting in %s", backoff)
+		select {
+		case <-ctx.Done():
+			log.Printf("supervisor: shutting down")
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+}
+
+func runWorker(ctx context.Context, cfg Config, log *lineLogger) (int, error) {
+	cmd := exec.CommandContext(ctx, cfg.PhpExePath, "artisan", "queue:work",
+		"--queue="+cfg.Queue,
+		"--max-jobs="+strconv.Itoa(cfg.MaxJobs),
+		"--memory="+strconv.Itoa(cfg.MaxMemoryMB),
+		"--sleep="+strconv.Itoa(cfg.Sleep),
+		"--tries="+strconv.Itoa(cfg.Tries),
+	)
+	stdout := &prefixWriter{log: log, prefix: "worker: "}
+	stderr := &prefixWriter{log: log, prefix: "worker: "}
+	cmd.Dir = cfg.BackendDir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+func stopRequested(cfg Config) bool {
+	_, err := os.Stat(cfg.stopPath())
+	return err == nil
+}
+
+// lineLogger timestamps each line written to the worker log. It is shared
+// by the supervisor and the child's stdout/stderr, so writes are serialized.
+type lineLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lineLogger) Printf(format string, args ...any) {
+	l.writeLine(fmt.Sprintf(format, args...))
+}
+
+func (l *lineLogger) writeLine(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), line)
+}
+
+// prefixWriter turns the worker's output into timestamped log lines.
+type prefixWriter struct {
+	log    *lineLogger
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		w.log.writeLine(w.prefix + strings.TrimRight(string(w.buf[:idx]), "\r"))
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any trailing output that did not end in a newline.
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.log.writeLine(w.prefix + string(w.buf))
+		w.buf = nil
+	}
+}